@@ -0,0 +1,547 @@
+// groot-gen generates Go types from the StreamerInfos stored in a ROOT file.
+//
+// The generated types implement the groot.ROOTStreamer interface and
+// register themselves into groot.Factory, so that objects of these classes
+// can be read (and written) with groot:
+//
+//	$ groot-gen -f event.root -p event -o event_gen.go
+//	$ groot-gen -f event.root -p event -c Event,Track
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/sbinet/go-root/pkg/groot"
+)
+
+var fname = flag.String("f", "", "ROOT file to inspect")
+var pkgname = flag.String("p", "main", "package name of the generated code")
+var oname = flag.String("o", "", "output file (default: stdout)")
+var classes = flag.String("c", "", "comma-separated list of classes to generate (default: all classes w/o a groot factory)")
+
+// basic describes how a builtin type is mapped to Go
+type basic struct {
+	gotype string // Go type
+	suffix string // suffix of the groot.Buffer Read/Write methods
+}
+
+var basics = map[int]basic{
+	groot.KChar:     {"int8", "I8"},
+	groot.KShort:    {"int16", "I16"},
+	groot.KInt:      {"int32", "I32"},
+	groot.KLong:     {"int64", "I64"},
+	groot.KFloat:    {"float32", "F32"},
+	groot.KCounter:  {"int32", "I32"},
+	groot.KDouble:   {"float64", "F64"},
+	groot.KDouble32: {"float64", "Double32"},
+	groot.KUChar:    {"uint8", "U8"},
+	groot.KUShort:   {"uint16", "U16"},
+	groot.KUInt:     {"uint32", "U32"},
+	groot.KULong:    {"uint64", "U64"},
+	groot.KBits:     {"uint32", "U32"},
+	groot.KLong64:   {"int64", "I64"},
+	groot.KULong64:  {"uint64", "U64"},
+	groot.KBool:     {"bool", "Bool"},
+	groot.KFloat16:  {"float32", "Float16"},
+}
+
+// field is a field of a generated Go type
+type field struct {
+	name   string
+	gotype string
+	doc    string
+}
+
+// member holds the Go code for a streamer element
+type member struct {
+	fields []field
+	decode []string // statements decoding the member
+	encode []string // statements encoding the member
+}
+
+type generator struct {
+	pkg    string
+	types  map[string]string              // Go type names of the generated classes, by class name
+	sinfos map[string]*groot.StreamerInfo // StreamerInfos of the generated classes, by class name
+	buf    bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// type_name returns a valid (exported) Go identifier for class
+func type_name(class string) string {
+	name := strings.Replace(class, "::", "_", -1)
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// field_name returns the name of the Go field for the data member name
+func field_name(name string) string {
+	name = type_name(name)
+	switch name {
+	case "Class", "Name", "Title", "ROOTDecode", "ROOTEncode":
+		name += "_"
+	}
+	return name
+}
+
+// read_expr returns the expression reading a value of builtin type etype
+func read_expr(etype int, title string) string {
+	bt := basics[etype]
+	switch etype {
+	case groot.KDouble32, groot.KFloat16:
+		return fmt.Sprintf("b.Read%s(%q)", bt.suffix, title)
+	}
+	return fmt.Sprintf("b.Read%s()", bt.suffix)
+}
+
+// write_stmt returns the statement writing the value v of builtin type etype
+func write_stmt(etype int, v, title string) string {
+	bt := basics[etype]
+	switch etype {
+	case groot.KDouble32, groot.KFloat16:
+		return fmt.Sprintf("b.Write%s(%s, %q)", bt.suffix, v, title)
+	}
+	return fmt.Sprintf("b.Write%s(%s)", bt.suffix, v)
+}
+
+func is_stl_string(elmt groot.StreamerElement) bool {
+	switch se := elmt.(type) {
+	case *groot.StreamerSTLstring:
+		return true
+	case *groot.StreamerSTL:
+		return se.STLType() == groot.KSTLstring
+	}
+	switch strings.TrimSpace(elmt.TypeName()) {
+	case "string", "std::string":
+		return true
+	}
+	return false
+}
+
+// is_stl_container returns whether elmt describes an STL container member
+func is_stl_container(elmt groot.StreamerElement) bool {
+	_, ok := elmt.(*groot.StreamerSTL)
+	return ok
+}
+
+// field_path returns the expression accessing the Go field name of an object
+// of the class described by si, or "" if there is no such field.
+// the fields of the generated base classes are looked up as well.
+func (g *generator) field_path(si *groot.StreamerInfo, name string) string {
+	for _, elmt := range si.Elements() {
+		if elmt == nil {
+			continue
+		}
+		se, ok := elmt.(*groot.StreamerBase)
+		switch {
+		case !ok:
+			if field_name(elmt.Name()) == name {
+				return "o." + name
+			}
+		case se.Name() == "TObject":
+			if name == "FUniqueID" || name == "FBits" {
+				return "o." + name
+			}
+		case se.Name() == "TNamed":
+			if name == "FName" || name == "FTitle" {
+				return "o." + name
+			}
+		default:
+			bsi, ok := g.sinfos[se.Name()]
+			if _, gen := g.types[se.Name()]; !ok || !gen {
+				continue
+			}
+			if path := g.field_path(bsi, name); path != "" {
+				return "o.Base" + type_name(se.Name()) + strings.TrimPrefix(path, "o")
+			}
+		}
+	}
+	return ""
+}
+
+// gen_member generates the Go code for the streamer element elmt of the
+// class described by si
+func (g *generator) gen_member(si *groot.StreamerInfo, elmt groot.StreamerElement) (m member, err error) {
+	if elmt == nil {
+		return m, fmt.Errorf("unknown streamer element")
+	}
+	name := field_name(elmt.Name())
+	etype := elmt.Type()
+	title := elmt.Title()
+	vname := "o." + name
+
+	if se, ok := elmt.(*groot.StreamerBase); ok {
+		switch se.Name() {
+		case "TObject":
+			m.fields = []field{{"FUniqueID", "uint32", ""}, {"FBits", "uint32", ""}}
+			m.decode = []string{"o.FUniqueID, o.FBits = b.ReadTObject()"}
+			m.encode = []string{"b.WriteTObject(o.FUniqueID, o.FBits)"}
+		case "TNamed":
+			m.fields = []field{{"FName", "string", ""}, {"FTitle", "string", ""}}
+			m.decode = []string{"o.FName, o.FTitle = b.ReadTNamed()"}
+			m.encode = []string{"b.WriteTNamed(o.FName, o.FTitle)"}
+		default:
+			name = "Base" + type_name(se.Name())
+			g.gen_value(&m, name, se.Name(), "base class "+se.Name())
+		}
+		return m, err
+	}
+
+	switch {
+	case etype > groot.KBase && etype < groot.KOffsetL:
+		bt, ok := basics[etype]
+		if !ok {
+			return m, fmt.Errorf("builtin type %d is not supported", etype)
+		}
+		if elmt.ArrLen() > 0 {
+			return g.gen_array(elmt, etype, name, title)
+		}
+		m.fields = []field{{name, bt.gotype, title}}
+		m.decode = []string{fmt.Sprintf("%s = %s", vname, read_expr(etype, title))}
+		m.encode = []string{write_stmt(etype, vname, title)}
+
+	case etype > groot.KOffsetL && etype < groot.KOffsetP:
+		if _, ok := basics[etype-groot.KOffsetL]; !ok {
+			return m, fmt.Errorf("builtin type %d is not supported", etype-groot.KOffsetL)
+		}
+		return g.gen_array(elmt, etype-groot.KOffsetL, name, title)
+
+	case etype > groot.KOffsetP && etype < groot.KObject:
+		se, ok := elmt.(*groot.StreamerBasicPointer)
+		if !ok {
+			return m, fmt.Errorf("pointer member without a counter")
+		}
+		bt, ok := basics[etype-groot.KOffsetP]
+		if !ok {
+			return m, fmt.Errorf("builtin type %d is not supported", etype-groot.KOffsetP)
+		}
+		// the counter may be a member of a base class
+		count := g.field_path(si, field_name(se.CountName()))
+		if count == "" {
+			return m, fmt.Errorf("counter [%s] of class [%s] is not a generated member", se.CountName(), se.CountClass())
+		}
+		m.fields = []field{{name, "[]" + bt.gotype, title}}
+		m.decode = []string{
+			"if b.ReadU8() != 0 {",
+			fmt.Sprintf("%s = make([]%s, int(%s))", vname, bt.gotype, count),
+			fmt.Sprintf("for i := range %s {", vname),
+			fmt.Sprintf("%s[i] = %s", vname, read_expr(etype-groot.KOffsetP, title)),
+			"}",
+			"} else {",
+			fmt.Sprintf("%s = nil", vname),
+			"}",
+		}
+		m.encode = []string{
+			fmt.Sprintf("if %s == nil {", vname),
+			"b.WriteU8(0)",
+			"} else {",
+			"b.WriteU8(1)",
+			fmt.Sprintf("for _, v := range %s {", vname),
+			write_stmt(etype-groot.KOffsetP, "v", title),
+			"}",
+			"}",
+		}
+
+	case etype == groot.KTString:
+		m.fields = []field{{name, "string", title}}
+		m.decode = []string{fmt.Sprintf("%s = b.ReadTString()", vname)}
+		m.encode = []string{fmt.Sprintf("b.WriteTString(%s)", vname)}
+
+	case etype == groot.KSTLstring, is_stl_string(elmt):
+		m.fields = []field{{name, "string", title}}
+		m.decode = []string{fmt.Sprintf("%s = b.ReadStdString()", vname)}
+		m.encode = []string{fmt.Sprintf("b.WriteStdString(%s)", vname)}
+
+	case etype == groot.KObject, etype == groot.KAny, etype == groot.KObjectp, etype == groot.KAnyp,
+		etype == groot.KTObject, etype == groot.KTNamed:
+		class := strings.TrimSpace(strings.TrimRight(elmt.TypeName(), "*"))
+		if elmt.ArrLen() > 0 {
+			return m, fmt.Errorf("arrays of objects are not supported")
+		}
+		g.gen_value(&m, name, class, title)
+
+	case etype == groot.KObjectP, etype == groot.KAnyP:
+		class := strings.TrimSpace(strings.TrimRight(elmt.TypeName(), "*"))
+		if elmt.ArrLen() > 0 {
+			return m, fmt.Errorf("arrays of pointers are not supported")
+		}
+		if tname, ok := g.types[class]; ok {
+			m.fields = []field{{name, "*" + tname, title}}
+			m.decode = []string{fmt.Sprintf("%s, _ = b.ReadObject().(*%s)", vname, tname)}
+		} else {
+			m.fields = []field{{name, "groot.Object", title}}
+			m.decode = []string{fmt.Sprintf("%s = b.ReadObject()", vname)}
+		}
+		m.encode = []string{
+			fmt.Sprintf("if err = b.WriteObject(%s); err != nil {", vname),
+			"return err",
+			"}",
+		}
+
+	case is_stl_container(elmt):
+		class := strings.TrimSpace(elmt.TypeName())
+		if elmt.ArrLen() > 0 {
+			return m, fmt.Errorf("arrays of STL containers are not supported")
+		}
+		rt, err := groot.STLType(class)
+		if err != nil {
+			return m, err
+		}
+		m.fields = []field{{name, rt.String(), title}}
+		m.decode = []string{
+			fmt.Sprintf("if err = b.ReadSTL(%q, &%s); err != nil {", class, vname),
+			"return err",
+			"}",
+		}
+		m.encode = []string{
+			fmt.Sprintf("if err = b.WriteSTL(%q, %s); err != nil {", class, vname),
+			"return err",
+			"}",
+		}
+
+	default:
+		return m, fmt.Errorf("member type %d (%s) is not supported", etype, elmt.TypeName())
+	}
+	return m, err
+}
+
+// gen_array generates the code for a fixed-size array of builtin types
+func (g *generator) gen_array(elmt groot.StreamerElement, etype int, name, title string) (m member, err error) {
+	vname := "o." + name
+	m.fields = []field{{name, fmt.Sprintf("[%d]%s", elmt.ArrLen(), basics[etype].gotype), title}}
+	m.decode = []string{
+		fmt.Sprintf("for i := range %s {", vname),
+		fmt.Sprintf("%s[i] = %s", vname, read_expr(etype, title)),
+		"}",
+	}
+	m.encode = []string{
+		fmt.Sprintf("for _, v := range %s {", vname),
+		write_stmt(etype, "v", title),
+		"}",
+	}
+	return m, err
+}
+
+// gen_value generates the code for an object of class class, streamed by value
+func (g *generator) gen_value(m *member, name, class, doc string) {
+	vname := "o." + name
+	if tname, ok := g.types[class]; ok {
+		m.fields = []field{{name, tname, doc}}
+		m.decode = []string{
+			fmt.Sprintf("if err = %s.ROOTDecode(b); err != nil {", vname),
+			"return err",
+			"}",
+		}
+		m.encode = []string{
+			fmt.Sprintf("if err = %s.ROOTEncode(b); err != nil {", vname),
+			"return err",
+			"}",
+		}
+		return
+	}
+	m.fields = []field{{name, "groot.Object", doc}}
+	m.decode = []string{
+		fmt.Sprintf("if %s, err = b.ReadObjectAny(%q); err != nil {", vname, class),
+		"return err",
+		"}",
+	}
+	m.encode = []string{
+		fmt.Sprintf("if err = b.WriteObjectAny(%s); err != nil {", vname),
+		"return err",
+		"}",
+	}
+}
+
+// gen_class generates the Go type for the class described by si
+func (g *generator) gen_class(si *groot.StreamerInfo) (err error) {
+	class := si.Name()
+	tname := g.types[class]
+	vers := si.ClassVersion()
+
+	members := make([]member, 0, len(si.Elements()))
+	for _, elmt := range si.Elements() {
+		m, err := g.gen_member(si, elmt)
+		if err != nil {
+			name := "<unknown>"
+			if elmt != nil {
+				name = elmt.Name()
+			}
+			return fmt.Errorf("class [%s], member [%s]: %v", class, name, err)
+		}
+		members = append(members, m)
+	}
+
+	g.printf("// %s was generated from the StreamerInfo of class %s (version=%d)\n", tname, class, vers)
+	g.printf("type %s struct {\n", tname)
+	for _, m := range members {
+		for _, f := range m.fields {
+			if f.doc != "" {
+				g.printf("%s %s // %s\n", f.name, f.gotype, f.doc)
+			} else {
+				g.printf("%s %s\n", f.name, f.gotype)
+			}
+		}
+	}
+	g.printf("}\n\n")
+
+	g.printf("func (o *%s) Class() string {\nreturn %q\n}\n\n", tname, class)
+	if path := g.field_path(si, "FName"); path != "" {
+		g.printf("func (o *%s) Name() string {\nreturn %s\n}\n\n", tname, path)
+	} else {
+		g.printf("func (o *%s) Name() string {\nreturn %q\n}\n\n", tname, class)
+	}
+	if path := g.field_path(si, "FTitle"); path != "" {
+		g.printf("func (o *%s) Title() string {\nreturn %s\n}\n\n", tname, path)
+	} else {
+		g.printf("func (o *%s) Title() string {\nreturn \"\"\n}\n\n", tname)
+	}
+
+	g.printf("func (o *%s) ROOTDecode(b *groot.Buffer) (err error) {\n", tname)
+	g.printf("spos := b.Pos()\n")
+	g.printf("vers, pos, bcnt := b.ReadVersion()\n")
+	g.printf("if vers != %d {\n", vers)
+	g.printf("return fmt.Errorf(\"%s: unsupported class version %%d (want %d)\", vers)\n", class, vers)
+	g.printf("}\n")
+	for _, m := range members {
+		for _, stmt := range m.decode {
+			g.printf("%s\n", stmt)
+		}
+	}
+	g.printf("b.CheckByteCount(pos, bcnt, spos, %q)\n", class)
+	g.printf("return err\n}\n\n")
+
+	g.printf("func (o *%s) ROOTEncode(b *groot.Buffer) (err error) {\n", tname)
+	g.printf("pos := b.WriteVersion(%d)\n", vers)
+	for _, m := range members {
+		for _, stmt := range m.encode {
+			g.printf("%s\n", stmt)
+		}
+	}
+	g.printf("b.SetByteCount(pos)\n")
+	g.printf("return err\n}\n\n")
+
+	g.printf("func init() {\n")
+	g.printf("f := func() reflect.Value {\no := &%s{}\nreturn reflect.ValueOf(o)\n}\n", tname)
+	g.printf("groot.Factory.Add(%q, f)\n", class)
+	g.printf("groot.Factory.Add(%q, f)\n", "*"+g.pkg+"."+tname)
+	g.printf("}\n\n")
+
+	g.printf("// check interfaces\n")
+	g.printf("var _ groot.Object = (*%s)(nil)\n", tname)
+	g.printf("var _ groot.ROOTStreamer = (*%s)(nil)\n\n", tname)
+	return err
+}
+
+// generate generates the Go code for the classes described by sinfos.
+// classes which can not be generated are reported and skipped.
+func (g *generator) generate(sinfos []*groot.StreamerInfo) ([]byte, error) {
+	for _, si := range sinfos {
+		g.types[si.Name()] = type_name(si.Name())
+		g.sinfos[si.Name()] = si
+	}
+
+	// drop the classes with unsupported members, until all the remaining
+	// classes (which may refer to each other) can be generated.
+	for {
+		g.buf.Reset()
+		g.printf("// automatically generated by groot-gen. DO NOT EDIT.\n\n")
+		g.printf("package %s\n\n", g.pkg)
+		g.printf("import (\n\"fmt\"\n\"reflect\"\n\n\"github.com/sbinet/go-root/pkg/groot\"\n)\n\n")
+
+		dropped := false
+		for _, si := range sinfos {
+			if _, ok := g.types[si.Name()]; !ok {
+				continue
+			}
+			err := g.gen_class(si)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "**warn** skipping %v\n", err)
+				delete(g.types, si.Name())
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			break
+		}
+	}
+	g.printf("// EOF\n")
+	return format.Source(g.buf.Bytes())
+}
+
+func main() {
+	flag.Parse()
+
+	if *fname == "" {
+		fmt.Fprintf(os.Stderr, "**error** you have to give a (valid) path to a ROOT file\n")
+		os.Exit(1)
+	}
+
+	f, err := groot.Open(*fname)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** %v\n", err)
+		os.Exit(1)
+	}
+
+	sinfos := make([]*groot.StreamerInfo, 0)
+	if *classes != "" {
+		for _, class := range strings.Split(*classes, ",") {
+			si, err := f.StreamerInfo(strings.TrimSpace(class), -1)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "**error** %v\n", err)
+				os.Exit(1)
+			}
+			sinfos = append(sinfos, si)
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, si := range f.StreamerInfos() {
+			name := si.Name()
+			if seen[name] || groot.Factory.HasKey(name) || strings.Contains(name, "<") {
+				continue
+			}
+			seen[name] = true
+			// use the most recent version of the class
+			si, _ = f.StreamerInfo(name, -1)
+			sinfos = append(sinfos, si)
+		}
+	}
+
+	g := &generator{
+		pkg:    *pkgname,
+		types:  make(map[string]string),
+		sinfos: make(map[string]*groot.StreamerInfo),
+	}
+	src, err := g.generate(sinfos)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** could not format generated code: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *oname == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	err = ioutil.WriteFile(*oname, src, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "**error** %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// EOF
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sbinet/go-root/pkg/groot"
+)
+
+// create_file creates a ROOT file holding a TH1F and a TObjString, with the
+// StreamerInfos of their classes.
+func create_file(t *testing.T) string {
+	fname := filepath.Join(t.TempDir(), "gen.root")
+	f, err := groot.Create(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := groot.NewH1F("h1", "my h1", 3, 0, 3)
+	h.Fill(1.5, 2)
+	err = f.Dir().Put("h1", h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Dir().Put("str", groot.NewObjString("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fname
+}
+
+// generate generates the code of the given classes of the file fname
+func generate(t *testing.T, fname string, classes ...string) string {
+	f, err := groot.Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var sinfos []*groot.StreamerInfo
+	for _, class := range classes {
+		si, err := f.StreamerInfo(class, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sinfos = append(sinfos, si)
+	}
+	g := &generator{
+		pkg:    "main",
+		types:  make(map[string]string),
+		sinfos: make(map[string]*groot.StreamerInfo),
+	}
+	src, err := g.generate(sinfos)
+	if err != nil {
+		t.Fatalf("invalid generated code: %v", err)
+	}
+	return string(src)
+}
+
+func TestGenerate(t *testing.T) {
+	fname := create_file(t)
+	src := generate(t, fname, "TObject", "TNamed", "TAttLine", "TAttFill", "TAttMarker",
+		"TArray", "TArrayF", "TH1F", "TObjString")
+
+	for _, want := range []string{
+		"type TAttLine struct {\n\tFLineColor int16 // Line color\n",
+		"\to.FLineColor = b.ReadI16()\n",
+		"\tb.WriteI16(o.FLineColor)\n",
+		"type TObjString struct {\n\tFUniqueID uint32\n\tFBits     uint32\n\tFString   string // wrapped TString\n}",
+		// counter of a base class
+		"o.FArray = make([]float32, int(o.BaseTArray.FN))",
+		// name of a base class
+		"func (o *TNamed) Name() string {\n\treturn o.FName\n}",
+		`groot.Factory.Add("TAttLine", f)`,
+		`groot.Factory.Add("*main.TAttLine", f)`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("missing %q in generated code", want)
+		}
+	}
+	// TH1 was not generated
+	if want := "\tBaseTH1     groot.Object // base class TH1\n"; !strings.Contains(src, want) {
+		t.Errorf("missing %q in generated code", want)
+	}
+
+	// without TArray, the counter of TArrayF is unknown: TArrayF is skipped.
+	src = generate(t, fname, "TArrayF", "TAttLine")
+	if strings.Contains(src, "type TArrayF") || !strings.Contains(src, "type TAttLine") {
+		t.Errorf("TArrayF should have been skipped:\n%s", src)
+	}
+}
+
+// gen_main uses the generated types to read the file given as argument, and
+// to round-trip values through a buffer.
+const gen_main = `package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/sbinet/go-root/pkg/groot"
+)
+
+func roundtrip(in, out groot.ROOTStreamer) {
+	w, err := groot.NewWBuffer(binary.BigEndian, 0)
+	if err == nil {
+		err = in.ROOTEncode(w)
+	}
+	if err != nil {
+		panic(err)
+	}
+	b, err := groot.NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err == nil {
+		err = out.ROOTDecode(b)
+	}
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%T %+v\n", out, out)
+}
+
+func main() {
+	f, err := groot.Open(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	obj, err := f.Get("str")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%T %s\n", obj, obj.(*TObjString).FString)
+
+	roundtrip(&TAttLine{FLineColor: 2, FLineStyle: 3, FLineWidth: 4}, &TAttLine{})
+	roundtrip(&TArrayF{BaseTArray: TArray{FN: 2}, FArray: []float32{1.5, 2.5}}, &TArrayF{})
+	roundtrip(&TNamed{FName: "n", FTitle: "t"}, &TNamed{})
+}
+`
+
+func TestGeneratedCode(t *testing.T) {
+	gocmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go command")
+	}
+	fname := create_file(t)
+	src := generate(t, fname, "TObject", "TNamed", "TAttLine", "TArray", "TArrayF", "TObjString")
+
+	dir := t.TempDir()
+	for name, src := range map[string]string{"gen.go": src, "main.go": gen_main} {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(gocmd, "run", "gen.go", "main.go", fname)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("could not run the generated code: %v\n%s", err, out)
+	}
+	want := strings.Join([]string{
+		"*main.TObjString hello",
+		"*main.TAttLine &{FLineColor:2 FLineStyle:3 FLineWidth:4}",
+		"*main.TArrayF &{BaseTArray:{FN:2} FArray:[1.5 2.5]}",
+		"*main.TNamed &{FUniqueID:0 FBits:50331648 FName:n FTitle:t}",
+	}, "\n") + "\n"
+	if string(out) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+// EOF
@@ -14,6 +14,7 @@ import (
 
 var fname = flag.String("f", "", "ROOT file to inspect")
 var detailed = flag.Bool("detailed", false, "enable detailed dump (of trees)")
+var sinfos = flag.Bool("sinfos", false, "dump the streamer infos of the file")
 
 //var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
 
@@ -28,6 +29,12 @@ func normpath(path []string) string {
 	return name
 }
 
+// graph is the interface shared by TGraph, TGraphErrors and TGraphAsymmErrors
+type graph interface {
+	Len() int
+	XY(i int) (x, y float64)
+}
+
 func inspect(dir *groot.Directory, path []string, indent string) {
 	name := normpath(path)
 	if dir == nil {
@@ -42,7 +49,13 @@ func inspect(dir *groot.Directory, path []string, indent string) {
 		if i+1 >= nkeys {
 			str = s_bot
 		}
-		switch v := k.Value().(type) {
+		v, err := k.Value()
+		if err != nil {
+			fmt.Printf("%s%s %s title='%s' type=%s\n%s    **error** %v\n",
+				indent, str, k.Name(), k.Title(), k.Class(), indent, err)
+			continue
+		}
+		switch v := v.(type) {
 		default:
 			fmt.Printf("%s%s %s title='%s' type=%s\n",
 				indent, str, k.Name(), k.Title(), k.Class())
@@ -69,6 +82,60 @@ func inspect(dir *groot.Directory, path []string, indent string) {
 						indent, "   ", strbr, branch.Name(), branch.Class())
 				}
 			}
+
+		case graph:
+			npts := v.Len()
+			fmt.Printf("%s%s %s title='%s' npoints=%v type=%s\n",
+				indent, str,
+				k.Name(), k.Title(), npts, k.Class())
+			if *detailed {
+				strpt := s_tee
+				for i := 0; i < npts; i++ {
+					if i+1 >= npts {
+						strpt = s_bot
+					}
+					x, y := v.XY(i)
+					fmt.Printf(" %s%s%s (%v, %v)\n",
+						indent, "   ", strpt, x, y)
+				}
+			}
+
+		case groot.Collection:
+			nobjs := v.Len()
+			fmt.Printf("%s%s %s title='%s' nobjs=%v type=%s\n",
+				indent, str,
+				k.Name(), k.Title(), nobjs, k.Class())
+			if *detailed {
+				strobj := s_tee
+				for i := 0; i < nobjs; i++ {
+					if i+1 >= nobjs {
+						strobj = s_bot
+					}
+					obj := v.At(i)
+					if obj == nil {
+						fmt.Printf(" %s%s%s <nil>\n", indent, "   ", strobj)
+						continue
+					}
+					fmt.Printf(" %s%s%s %s type=%s\n",
+						indent, "   ", strobj, obj.Name(), obj.Class())
+				}
+			}
+		}
+	}
+}
+
+func dump_sinfos(f *groot.File) {
+	fmt.Printf("streamer infos:\n")
+	for _, si := range f.StreamerInfos() {
+		fmt.Printf("%s (version=%d, checksum=0x%x)\n",
+			si.Name(), si.ClassVersion(), si.CheckSum())
+		for _, elmt := range si.Elements() {
+			if elmt == nil {
+				fmt.Printf("    <unknown element>\n")
+				continue
+			}
+			fmt.Printf("    %-20s %-20s type=%-3d size=%-3d // %s\n",
+				elmt.TypeName(), elmt.Name(), elmt.Type(), elmt.Size(), elmt.Title())
 		}
 	}
 }
@@ -91,7 +158,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	f, err := groot.NewFileReader(*fname)
+	f, err := groot.Open(*fname)
 	if err != nil {
 		fmt.Printf("**error** %v\n", err)
 		os.Exit(1)
@@ -104,6 +171,10 @@ func main() {
 
 	fmt.Printf("file: '%s' (version=%v)\n", f.Name(), f.Version())
 
+	if *sinfos {
+		dump_sinfos(f)
+	}
+
 	dir := f.Dir()
 	inspect(dir, []string{"/"}, "")
 
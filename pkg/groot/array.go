@@ -0,0 +1,173 @@
+package groot
+
+import (
+	"reflect"
+)
+
+// ArrayD is an array of float64 (TArrayD)
+type ArrayD struct {
+	data []float64
+}
+
+func (arr *ArrayD) Class() string {
+	return "TArrayD"
+}
+
+func (arr *ArrayD) Name() string {
+	return "TArrayD"
+}
+
+func (arr *ArrayD) Title() string {
+	return "Array of doubles"
+}
+
+// Len returns the number of elements of the array
+func (arr *ArrayD) Len() int {
+	return len(arr.data)
+}
+
+// At returns the i-th element of the array
+func (arr *ArrayD) At(i int) float64 {
+	return arr.data[i]
+}
+
+// Data returns the elements of the array
+func (arr *ArrayD) Data() []float64 {
+	return arr.data
+}
+
+// TArrays are streamed without a version header.
+// see TArrayD::Streamer
+
+func (arr *ArrayD) ROOTDecode(b *Buffer) (err error) {
+	arr.data = b.read_array_D()
+	return
+}
+
+func (arr *ArrayD) ROOTEncode(b *Buffer) (err error) {
+	b.write_array_D(arr.data)
+	return
+}
+
+// ArrayF is an array of float32 (TArrayF)
+type ArrayF struct {
+	data []float32
+}
+
+func (arr *ArrayF) Class() string {
+	return "TArrayF"
+}
+
+func (arr *ArrayF) Name() string {
+	return "TArrayF"
+}
+
+func (arr *ArrayF) Title() string {
+	return "Array of floats"
+}
+
+// Len returns the number of elements of the array
+func (arr *ArrayF) Len() int {
+	return len(arr.data)
+}
+
+// At returns the i-th element of the array
+func (arr *ArrayF) At(i int) float32 {
+	return arr.data[i]
+}
+
+// Data returns the elements of the array
+func (arr *ArrayF) Data() []float32 {
+	return arr.data
+}
+
+func (arr *ArrayF) ROOTDecode(b *Buffer) (err error) {
+	arr.data = b.read_array_F()
+	return
+}
+
+func (arr *ArrayF) ROOTEncode(b *Buffer) (err error) {
+	b.write_array_F(arr.data)
+	return
+}
+
+// ArrayI is an array of int32 (TArrayI)
+type ArrayI struct {
+	data []int32
+}
+
+func (arr *ArrayI) Class() string {
+	return "TArrayI"
+}
+
+func (arr *ArrayI) Name() string {
+	return "TArrayI"
+}
+
+func (arr *ArrayI) Title() string {
+	return "Array of ints"
+}
+
+// Len returns the number of elements of the array
+func (arr *ArrayI) Len() int {
+	return len(arr.data)
+}
+
+// At returns the i-th element of the array
+func (arr *ArrayI) At(i int) int32 {
+	return arr.data[i]
+}
+
+// Data returns the elements of the array
+func (arr *ArrayI) Data() []int32 {
+	return arr.data
+}
+
+func (arr *ArrayI) ROOTDecode(b *Buffer) (err error) {
+	arr.data = b.read_array_I()
+	return
+}
+
+func (arr *ArrayI) ROOTEncode(b *Buffer) (err error) {
+	b.write_array_I(arr.data)
+	return
+}
+
+func init() {
+	{
+		f := func() reflect.Value {
+			o := &ArrayD{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TArrayD"] = f
+		Factory.db["*groot.ArrayD"] = f
+	}
+
+	{
+		f := func() reflect.Value {
+			o := &ArrayF{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TArrayF"] = f
+		Factory.db["*groot.ArrayF"] = f
+	}
+
+	{
+		f := func() reflect.Value {
+			o := &ArrayI{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TArrayI"] = f
+		Factory.db["*groot.ArrayI"] = f
+	}
+}
+
+// check interfaces
+var _ Object = (*ArrayD)(nil)
+var _ ROOTStreamer = (*ArrayD)(nil)
+var _ Object = (*ArrayF)(nil)
+var _ ROOTStreamer = (*ArrayF)(nil)
+var _ Object = (*ArrayI)(nil)
+var _ ROOTStreamer = (*ArrayI)(nil)
+
+// EOF
@@ -0,0 +1,170 @@
+package groot
+
+import (
+	"reflect"
+)
+
+// AttLine holds the line attributes of an object (TAttLine)
+type AttLine struct {
+	color uint16 // line color
+	style uint16 // line style
+	width uint16 // line width
+}
+
+func (att *AttLine) Class() string {
+	return "TAttLine"
+}
+
+func (att *AttLine) Name() string {
+	return "TAttLine"
+}
+
+func (att *AttLine) Title() string {
+	return "Line attributes"
+}
+
+// Color returns the line color
+func (att *AttLine) Color() int {
+	return int(att.color)
+}
+
+// Style returns the line style
+func (att *AttLine) Style() int {
+	return int(att.style)
+}
+
+// Width returns the line width
+func (att *AttLine) Width() int {
+	return int(att.width)
+}
+
+func (att *AttLine) ROOTDecode(b *Buffer) (err error) {
+	att.color, att.style, att.width = b.read_attline()
+	return
+}
+
+func (att *AttLine) ROOTEncode(b *Buffer) (err error) {
+	b.write_attline(att.color, att.style, att.width)
+	return
+}
+
+// AttFill holds the fill area attributes of an object (TAttFill)
+type AttFill struct {
+	color uint16 // fill area color
+	style uint16 // fill area style
+}
+
+func (att *AttFill) Class() string {
+	return "TAttFill"
+}
+
+func (att *AttFill) Name() string {
+	return "TAttFill"
+}
+
+func (att *AttFill) Title() string {
+	return "Fill area attributes"
+}
+
+// Color returns the fill area color
+func (att *AttFill) Color() int {
+	return int(att.color)
+}
+
+// Style returns the fill area style
+func (att *AttFill) Style() int {
+	return int(att.style)
+}
+
+func (att *AttFill) ROOTDecode(b *Buffer) (err error) {
+	att.color, att.style = b.read_attfill()
+	return
+}
+
+func (att *AttFill) ROOTEncode(b *Buffer) (err error) {
+	b.write_attfill(att.color, att.style)
+	return
+}
+
+// AttMarker holds the marker attributes of an object (TAttMarker)
+type AttMarker struct {
+	color uint16  // marker color
+	style uint16  // marker style
+	size  float32 // marker size
+}
+
+func (att *AttMarker) Class() string {
+	return "TAttMarker"
+}
+
+func (att *AttMarker) Name() string {
+	return "TAttMarker"
+}
+
+func (att *AttMarker) Title() string {
+	return "Marker attributes"
+}
+
+// Color returns the marker color
+func (att *AttMarker) Color() int {
+	return int(att.color)
+}
+
+// Style returns the marker style
+func (att *AttMarker) Style() int {
+	return int(att.style)
+}
+
+// Size returns the marker size
+func (att *AttMarker) Size() float64 {
+	return float64(att.size)
+}
+
+func (att *AttMarker) ROOTDecode(b *Buffer) (err error) {
+	att.color, att.style, att.size = b.read_attmarker()
+	return
+}
+
+func (att *AttMarker) ROOTEncode(b *Buffer) (err error) {
+	b.write_attmarker(att.color, att.style, att.size)
+	return
+}
+
+func init() {
+	{
+		f := func() reflect.Value {
+			o := &AttLine{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TAttLine"] = f
+		Factory.db["*groot.AttLine"] = f
+	}
+
+	{
+		f := func() reflect.Value {
+			o := &AttFill{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TAttFill"] = f
+		Factory.db["*groot.AttFill"] = f
+	}
+
+	{
+		f := func() reflect.Value {
+			o := &AttMarker{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TAttMarker"] = f
+		Factory.db["*groot.AttMarker"] = f
+	}
+}
+
+// check interfaces
+var _ Object = (*AttLine)(nil)
+var _ ROOTStreamer = (*AttLine)(nil)
+var _ Object = (*AttFill)(nil)
+var _ ROOTStreamer = (*AttFill)(nil)
+var _ Object = (*AttMarker)(nil)
+var _ ROOTStreamer = (*AttMarker)(nil)
+
+// EOF
@@ -0,0 +1,267 @@
+package groot
+
+import (
+	"reflect"
+)
+
+// attaxis holds the attributes of an axis (TAttAxis)
+type attaxis struct {
+	ndivs       int32   // number of divisions
+	axis_color  uint16  // color of the line axis
+	label_color uint16  // color of the labels
+	label_font  uint16  // font of the labels
+	label_off   float32 // offset of the labels
+	label_size  float32 // size of the labels
+	tick_length float32 // length of the tick marks
+	title_off   float32 // offset of the axis title
+	title_size  float32 // size of the axis title
+	title_color uint16  // color of the axis title
+	title_font  uint16  // font of the axis title
+}
+
+func new_attaxis() attaxis {
+	return attaxis{
+		ndivs:       510,
+		axis_color:  1,
+		label_color: 1,
+		label_font:  42,
+		label_off:   0.005,
+		label_size:  0.035,
+		tick_length: 0.03,
+		title_off:   1,
+		title_size:  0.035,
+		title_color: 1,
+		title_font:  42,
+	}
+}
+
+func (att *attaxis) read(b *Buffer) {
+	spos := b.Pos()
+	/*vers*/ _, pos, bcnt := b.read_version()
+	att.ndivs = b.ntoi4()
+	att.axis_color = b.ntou2()
+	att.label_color = b.ntou2()
+	att.label_font = b.ntou2()
+	att.label_off = b.ntof()
+	att.label_size = b.ntof()
+	att.tick_length = b.ntof()
+	att.title_off = b.ntof()
+	att.title_size = b.ntof()
+	att.title_color = b.ntou2()
+	att.title_font = b.ntou2()
+	b.check_byte_count(pos, bcnt, spos, "TAttAxis")
+}
+
+func (att *attaxis) write(b *Buffer) {
+	pos := b.write_version(4)
+	b.i4ton(att.ndivs)
+	b.u2ton(att.axis_color)
+	b.u2ton(att.label_color)
+	b.u2ton(att.label_font)
+	b.fton(att.label_off)
+	b.fton(att.label_size)
+	b.fton(att.tick_length)
+	b.fton(att.title_off)
+	b.fton(att.title_size)
+	b.u2ton(att.title_color)
+	b.u2ton(att.title_font)
+	b.set_byte_count(pos)
+}
+
+// Axis is the axis of a histogram (TAxis)
+type Axis struct {
+	name  string
+	title string
+	att   attaxis
+
+	nbins   int       // number of bins
+	xmin    float64   // low edge of the first bin
+	xmax    float64   // up edge of the last bin
+	xbins   []float64 // bin edges (variable bin sizes only)
+	first   int32     // first bin to display
+	last    int32     // last bin to display
+	bits2   uint16    // second bit status word
+	timedsp bool      // whether to display time values
+	timefmt string    // date&time format
+	labels  Object    // list of labels
+	modlabs Object    // list of modified labels
+}
+
+// new_axis returns a new axis with nbins bins of equal width
+func new_axis(name string, nbins int, xmin, xmax float64) *Axis {
+	return &Axis{
+		name:  name,
+		att:   new_attaxis(),
+		nbins: nbins,
+		xmin:  xmin,
+		xmax:  xmax,
+	}
+}
+
+func (axis *Axis) Class() string {
+	return "TAxis"
+}
+
+func (axis *Axis) Name() string {
+	return axis.name
+}
+
+func (axis *Axis) Title() string {
+	return axis.title
+}
+
+// NBins returns the number of bins of the axis
+func (axis *Axis) NBins() int {
+	return axis.nbins
+}
+
+// XMin returns the low edge of the first bin
+func (axis *Axis) XMin() float64 {
+	return axis.xmin
+}
+
+// XMax returns the up edge of the last bin
+func (axis *Axis) XMax() float64 {
+	return axis.xmax
+}
+
+// BinLowEdge returns the low edge of bin i (1 is the first bin)
+func (axis *Axis) BinLowEdge(i int) float64 {
+	if len(axis.xbins) > 0 && i >= 1 && i <= axis.nbins {
+		return axis.xbins[i-1]
+	}
+	return axis.xmin + float64(i-1)*axis.width()
+}
+
+// BinUpEdge returns the up edge of bin i (1 is the first bin)
+func (axis *Axis) BinUpEdge(i int) float64 {
+	if len(axis.xbins) > 0 && i >= 0 && i < axis.nbins {
+		return axis.xbins[i]
+	}
+	return axis.xmin + float64(i)*axis.width()
+}
+
+// BinCenter returns the center of bin i (1 is the first bin)
+func (axis *Axis) BinCenter(i int) float64 {
+	return 0.5 * (axis.BinLowEdge(i) + axis.BinUpEdge(i))
+}
+
+// BinWidth returns the width of bin i (1 is the first bin)
+func (axis *Axis) BinWidth(i int) float64 {
+	return axis.BinUpEdge(i) - axis.BinLowEdge(i)
+}
+
+// Edges returns the nbins+1 edges of the bins
+func (axis *Axis) Edges() []float64 {
+	edges := make([]float64, axis.nbins+1)
+	for i := range edges {
+		edges[i] = axis.BinLowEdge(i + 1)
+	}
+	return edges
+}
+
+// FindBin returns the bin holding x: 0 for the underflow, nbins+1 for the
+// overflow.
+// NaN values go to the overflow, as in ROOT.
+func (axis *Axis) FindBin(x float64) int {
+	switch {
+	case x < axis.xmin:
+		return 0
+	case !(x < axis.xmax):
+		return axis.nbins + 1
+	}
+	if len(axis.xbins) == 0 {
+		bin := 1 + int(float64(axis.nbins)*(x-axis.xmin)/(axis.xmax-axis.xmin))
+		if bin > axis.nbins {
+			// x just below xmax may be rounded up to xmax
+			bin = axis.nbins
+		}
+		return bin
+	}
+	lo, hi := 0, axis.nbins
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if x < axis.xbins[mid] {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return lo + 1
+}
+
+// width returns the width of the bins of a fixed bin size axis
+func (axis *Axis) width() float64 {
+	if axis.nbins <= 0 {
+		return 0
+	}
+	return (axis.xmax - axis.xmin) / float64(axis.nbins)
+}
+
+func (axis *Axis) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[axis] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	axis.name, axis.title = b.read_tnamed()
+	axis.att.read(b)
+
+	axis.nbins = int(b.ntoi4())
+	axis.xmin = b.ntod()
+	axis.xmax = b.ntod()
+	axis.xbins = b.read_array_D()
+	axis.first = b.ntoi4()
+	axis.last = b.ntoi4()
+	if vers >= 9 {
+		axis.bits2 = b.ntou2()
+		axis.timedsp = b.read_bool()
+		axis.timefmt = b.read_tstring()
+		axis.labels = b.read_object()
+	}
+	if vers >= 10 {
+		axis.modlabs = b.read_object()
+	}
+
+	b.check_byte_count(pos, bcnt, spos, "TAxis")
+	return
+}
+
+func (axis *Axis) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(10)
+	b.write_tnamed(axis.name, axis.title)
+	axis.att.write(b)
+
+	b.i4ton(int32(axis.nbins))
+	b.dton(axis.xmin)
+	b.dton(axis.xmax)
+	b.write_array_D(axis.xbins)
+	b.i4ton(axis.first)
+	b.i4ton(axis.last)
+	b.u2ton(axis.bits2)
+	b.write_bool(axis.timedsp)
+	b.write_tstring(axis.timefmt)
+	err = b.write_object(axis.labels)
+	if err != nil {
+		return err
+	}
+	err = b.write_object(axis.modlabs)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
+	return
+}
+
+func init() {
+	f := func() reflect.Value {
+		o := &Axis{att: new_attaxis()}
+		return reflect.ValueOf(o)
+	}
+	Factory.db["TAxis"] = f
+	Factory.db["*groot.Axis"] = f
+}
+
+// check interfaces
+var _ Object = (*Axis)(nil)
+var _ ROOTStreamer = (*Axis)(nil)
+
+// EOF
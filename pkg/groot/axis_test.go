@@ -0,0 +1,106 @@
+package groot
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestAxisFindBin(t *testing.T) {
+	fixed := new_axis("x", 3, -2.2, 3.3)
+	vars := new_axis("x", 3, 0, 10)
+	vars.xbins = []float64{0, 1, 5, 10}
+
+	for _, tc := range []struct {
+		name string
+		axis *Axis
+		x    float64
+		want int
+	}{
+		{"underflow", fixed, -2.3, 0},
+		{"xmin", fixed, -2.2, 1},
+		{"first bin", fixed, -0.4, 1},
+		{"second bin", fixed, -0.3, 2},
+		{"last bin", fixed, 3, 3},
+		{"just below xmax", fixed, math.Nextafter(3.3, -2.2), 3},
+		{"xmax", fixed, 3.3, 4},
+		{"overflow", fixed, 10, 4},
+		{"nan", fixed, math.NaN(), 4},
+		{"+inf", fixed, math.Inf(+1), 4},
+		{"-inf", fixed, math.Inf(-1), 0},
+
+		{"var underflow", vars, -1, 0},
+		{"var xmin", vars, 0, 1},
+		{"var first edge", vars, 1, 2},
+		{"var last bin", vars, 9.99, 3},
+		{"var xmax", vars, 10, 4},
+		{"var nan", vars, math.NaN(), 4},
+	} {
+		if got := tc.axis.FindBin(tc.x); got != tc.want {
+			t.Errorf("%s: FindBin(%v): got %d, want %d", tc.name, tc.x, got, tc.want)
+		}
+	}
+
+	// the bin found for x just below xmax stays in range for any nbins
+	for _, nbins := range []int{1, 3, 7, 10, 100, 1000} {
+		axis := new_axis("x", nbins, -2.2, 3.3)
+		x := math.Nextafter(axis.xmax, axis.xmin)
+		if got := axis.FindBin(x); got != nbins {
+			t.Errorf("nbins=%d: FindBin(%v): got %d, want %d", nbins, x, got, nbins)
+		}
+	}
+}
+
+func TestAxisEdges(t *testing.T) {
+	axis := new_axis("x", 4, 0, 2)
+	if got, want := axis.Edges(), []float64{0, 0.5, 1, 1.5, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fixed: got edges %v, want %v", got, want)
+	}
+	if got, want := axis.BinCenter(2), 0.75; got != want {
+		t.Errorf("fixed: got center %v, want %v", got, want)
+	}
+
+	axis = new_axis("x", 3, 0, 10)
+	axis.xbins = []float64{0, 1, 5, 10}
+	if got, want := axis.Edges(), axis.xbins; !reflect.DeepEqual(got, want) {
+		t.Errorf("variable: got edges %v, want %v", got, want)
+	}
+	if got, want := axis.BinWidth(2), 4.0; got != want {
+		t.Errorf("variable: got width %v, want %v", got, want)
+	}
+}
+
+func TestAxisReadWrite(t *testing.T) {
+	want := new_axis("xaxis", 3, 0, 10)
+	want.title = "x [cm]"
+	want.xbins = []float64{0, 1, 5, 10}
+
+	w, err := NewWBuffer(binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = want.ROOTEncode(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Axis
+	err = got.ROOTDecode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("%d bytes left", b.Len())
+	}
+	if got.Name() != want.Name() || got.Title() != want.Title() || got.NBins() != want.NBins() ||
+		got.XMin() != want.XMin() || got.XMax() != want.XMax() || !reflect.DeepEqual(got.Edges(), want.Edges()) {
+		t.Errorf("got %+v, want %+v", got, *want)
+	}
+}
+
+// EOF
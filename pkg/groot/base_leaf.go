@@ -1,16 +1,39 @@
 package groot
 
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
 type ibaseLeaf interface {
 	toBaseLeaf() *baseLeaf
 }
 
+// ileaf is the interface implemented by all the concrete leaves.
+// the values read from a leaf are held by the read_state of each reader: the
+// leaves of a tree are shared by all its readers and are not modified when
+// reading.
+type ileaf interface {
+	Object
+	ibaseLeaf
+
+	// read_basket reads the n value(s) of the leaf for the current entry.
+	// data holds the values of the previous entry (or nil): its storage is
+	// reused when possible.
+	read_basket(b *Buffer, n int, data interface{}) (interface{}, error)
+}
+
 type baseLeaf struct {
-	name   string
-	title  string
-	ndata  uint32 // number of elements in fAddress
-	length uint32 // number of fixed length elements
+	name     string
+	title    string
+	ndata    uint32 // number of elements in fAddress
+	length   uint32 // number of fixed length elements
+	etype    int32  // number of bytes for this data type
+	unsigned bool   // whether the data type is unsigned
 
-	leaf_count *baseLeaf // pointer to Leaf-count if variable length
+	leaf_count ileaf   // pointer to Leaf-count if variable length
+	branch     *Branch // branch holding this leaf
 }
 
 func (base *baseLeaf) Class() Class {
@@ -34,15 +57,17 @@ func (base *baseLeaf) ROOTDecode(b *Buffer) (err error) {
 	printf("baseleaf-name='%v' title='%v'\n", base.name, base.title)
 	base.length = b.ntou4()
 	printf("baseleaf-length=%v\n", base.length)
-	b.ntoi4()   // fLengthType
+	base.etype = b.ntoi4()
 	b.ntoi4()   // fOffset
 	b.ntobyte() // fIsRange
-	b.ntobyte() // fIsUnsigned
+	base.unsigned = b.read_bool()
 
 	obj := b.read_object()
 	printf("baseleaf-nobjs: %v\n", obj)
 	if obj != nil {
-		base.leaf_count = obj.(ibaseLeaf).toBaseLeaf()
+		if leaf, ok := obj.(ileaf); ok {
+			base.leaf_count = leaf
+		}
 	}
 
 	if base.length == 0 {
@@ -54,6 +79,98 @@ func (base *baseLeaf) ROOTDecode(b *Buffer) (err error) {
 	return
 }
 
+func (base *baseLeaf) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(2)
+	b.write_tnamed(base.name, base.title)
+	b.u4ton(base.length)
+	b.i4ton(base.etype)
+	b.i4ton(0)          // fOffset
+	b.write_bool(false) // fIsRange
+	b.write_bool(base.unsigned)
+	err = b.write_object(base.leaf_count)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
+	return
+}
+
+// count_name returns the name of the leaf-count of this leaf, extracted
+// from the leaf title (e.g. "px[n]"), or "" if there is none.
+// see TLeaf::GetLeafCounter
+func (base *baseLeaf) count_name() string {
+	beg := strings.Index(base.title, "[")
+	if beg < 0 {
+		return ""
+	}
+	end := strings.Index(base.title[beg:], "]")
+	if end < 0 {
+		return ""
+	}
+	name := base.title[beg+1 : beg+end]
+	if _, err := strconv.Atoi(name); err == nil {
+		// fixed size array
+		return ""
+	}
+	return name
+}
+
+// count returns the number of values held by this leaf for the current entry
+// of the reader state s
+func (base *baseLeaf) count(s *read_state) int {
+	if base.leaf_count == nil {
+		return int(base.length)
+	}
+	n := 0
+	switch v := base.leaf_count.toBaseLeaf().value(s.leaves[base.leaf_count]).(type) {
+	case int8:
+		n = int(v)
+	case uint8:
+		n = int(v)
+	case int16:
+		n = int(v)
+	case uint16:
+		n = int(v)
+	case int32:
+		n = int(v)
+	case uint32:
+		n = int(v)
+	case int64:
+		n = int(v)
+	case uint64:
+		n = int(v)
+	}
+	return n * int(base.length)
+}
+
+// value returns the value(s) of this leaf, from the data returned by its
+// read_basket method: a single value for a leaf holding a single value,
+// the slice of values otherwise.
+func (base *baseLeaf) value(data interface{}) interface{} {
+	if base.leaf_count != nil || base.length != 1 {
+		return data
+	}
+	rv := reflect.ValueOf(data)
+	if rv.Kind() != reflect.Slice || rv.Len() != 1 {
+		return data
+	}
+	return rv.Index(0).Interface()
+}
+
+// load_count makes sure the leaf-count of this leaf holds the value for the
+// given entry in the reader state s
+func (base *baseLeaf) load_count(entry int64, s *read_state) error {
+	if base.leaf_count == nil {
+		return nil
+	}
+	br := base.leaf_count.toBaseLeaf().branch
+	if br == nil || br == base.branch {
+		// leaf-count is read alongside this leaf
+		return nil
+	}
+	return br.load_entry(entry, s)
+}
+
 // func init() {
 // 	f := func() reflect.Value {
 // 		o := &BaseLeaf{}
@@ -1,6 +1,7 @@
 package groot
 
 import (
+	"encoding/binary"
 	"fmt"
 	"reflect"
 )
@@ -8,17 +9,18 @@ import (
 type Basket struct {
 	key Key
 
-	nev_bufsz    uint32 // Length in Int_t of entry_offset
-	nev          uint32 // Number of entries in basket
-	last         uint32 // Pointer to last used byte in basket
-	entry_offset int    // [m_nev] Offset of entries in Key.buffer
-	displacement int    //![m_nev] Displacement of entries in Key.buffer
+	bufsz        uint32  // length of the basket buffer
+	nev_bufsz    uint32  // Length in Int_t of entry_offset (or fixed length of each entry if no entry_offset)
+	nev          uint32  // Number of entries in basket
+	last         uint32  // Pointer to last used byte in basket
+	entry_offset []int32 // [m_nev] Offset of entries in buffer
+	displacement []int32 //![m_nev] Displacement of entries in buffer
 
+	buffer []byte // uncompressed content of the basket (key header included)
 }
 
-func (basket *Basket) Class() Class {
-	//FIXME
-	panic("not implemented")
+func (basket *Basket) Class() string {
+	return "TBasket"
 }
 
 func (basket *Basket) Name() string {
@@ -30,7 +32,7 @@ func (basket *Basket) Title() string {
 }
 
 func (basket *Basket) ROOTDecode(b *Buffer) (err error) {
-	startpos := b.Len()
+	startpos := b.Pos()
 	k, err := NewKey(nil, 0, 0)
 	if err != nil {
 		return err
@@ -41,24 +43,33 @@ func (basket *Basket) ROOTDecode(b *Buffer) (err error) {
 		return err
 	}
 
-	vers, pos, bcnt := b.read_version()
-	printf("basket-vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
-	bufsz := b.ntou4()
-	basket.nev_bufsz = b.ntou4()
+	// TBasket is streamed w/o any byte count
+	vers := b.ntou2()
+	basket.bufsz = b.ntou4()
+	nev_bufsz := b.ntoi4()
+	if nev_bufsz < 0 {
+		nev_bufsz = -nev_bufsz
+		b.ntobyte() // fIOBits
+	}
+	basket.nev_bufsz = uint32(nev_bufsz)
 	basket.nev = b.ntou4()
 	basket.last = b.ntou4()
 	flag := b.ntobyte()
-	if basket.last > bufsz {
-		bufsz = basket.last
+	printf("basket-vers=%v bufsz=%v nev_bufsz=%v nev=%v last=%v flag=%v\n",
+		vers, basket.bufsz, basket.nev_bufsz, basket.nev, basket.last, flag)
+	if basket.last > basket.bufsz {
+		basket.bufsz = basket.last
+	}
+
+	if b.err != nil {
+		return b.err
 	}
 
-	basket_key_len := b.Len() - startpos
+	basket_key_len := b.Pos() - startpos
 	if basket_key_len != int(basket.key.keysz) {
 		basket.key.keysz = uint16(basket_key_len)
 	}
-	if basket.key.objsz != 0 {
-		basket.key.objsz = uint32(basket.key.nbytes) - uint32(basket.key.keysz)
-	}
+
 	if flag == 0 {
 		// fHeaderOnly
 		return
@@ -70,19 +81,127 @@ func (basket *Basket) ROOTDecode(b *Buffer) (err error) {
 		flag != 51 && flag != 52 {
 		err = fmt.Errorf("groot.basket.ROOTDecode: bad flag (=%v)",
 			int(flag))
+		return
 	}
-	//FIXME: incomplete!! see basket basket::stream
-	return
+
+	if flag%10 != 2 {
+		if basket.nev > 0 {
+			basket.entry_offset = b.read_array_I()
+		}
+		if 20 < flag && flag < 40 {
+			for i := range basket.entry_offset {
+				basket.entry_offset[i] &= int32(^uint32(kDisplacementMask))
+			}
+		}
+		if flag > 40 {
+			basket.displacement = b.read_array_I()
+		}
+	}
+
+	if flag == 1 || flag > 10 {
+		// the basket buffer was streamed along with its header
+		// (this happens for the last basket of a branch, if it was not
+		// flushed to file before the tree was written out)
+		if vers > 1 {
+			basket.buffer = b.read_fast_array_C(int(basket.last))
+		} else {
+			basket.buffer = b.read_array_C()
+		}
+	}
+	return b.err
 }
 
+// ROOTEncode writes the key and the header of the basket.
+// the content of the basket is written separately, right after.
 func (basket *Basket) ROOTEncode(b *Buffer) (err error) {
-	//bb := b.Clone()
-	//err = basket.key.ROOTEncode(b)
+	basket.key.fill_buffer(b)
+
+	// TBasket is streamed w/o any byte count
+	b.u2ton(3)
+	b.u4ton(basket.bufsz)
+	b.u4ton(basket.nev_bufsz)
+	b.u4ton(basket.nev)
+	b.u4ton(basket.last)
+	b.byteton(0) // fHeaderOnly
+	return
+}
+
+// basket_header_size is the number of bytes of the TBasket header
+// following the key structure.
+const basket_header_size = 2 + 4 + 4 + 4 + 4 + 1
+
+// read_basket reads the basket of nbytes located at pos in file f, from the
+// cache tc if it holds it.
+// the content of the basket is decompressed if needed.
+func read_basket(f *File, tc *tree_cache, pos int64, nbytes int) (basket *Basket, err error) {
+	err = f.check_range(pos, int64(nbytes))
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]byte, nbytes)
+	if tc == nil || !tc.get(raw, pos) {
+		err = f.read_at(raw, pos)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := NewBuffer(raw, f.order, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	basket = &Basket{}
+	err = basket.ROOTDecode(b)
+	if err != nil {
+		return nil, err
+	}
+	basket.key.file = f
+
+	keysz := int(basket.key.keysz)
+	objsz := int(int32(basket.key.objsz))
+	if keysz > nbytes || objsz < 0 {
+		return nil, fmt.Errorf("groot: invalid basket header (keysz=%d, objsz=%d, nbytes=%d)", keysz, objsz, nbytes)
+	}
+	if objsz <= nbytes-keysz {
+		basket.buffer = raw
+		return basket, err
+	}
+
+	if err := check_unzip_size(raw[keysz:], objsz); err != nil {
+		return nil, fmt.Errorf("groot: invalid compressed payload for basket [%s] of tree [%s] (offset=%d, nbytes=%d, objsz=%d): %v",
+			basket.Name(), basket.Title(), pos, nbytes, objsz, err)
+	}
+	basket.buffer = make([]byte, keysz+objsz)
+	copy(basket.buffer, raw[:keysz])
+	err = unzip_root_buffer(basket.buffer[keysz:], raw[keysz:], f.unzipers)
+	if err != nil {
+		return nil, fmt.Errorf("groot: could not decompress basket [%s] of tree [%s] (offset=%d, nbytes=%d, objsz=%d): %v",
+			basket.Name(), basket.Title(), pos, nbytes, objsz, err)
+	}
+	return basket, err
+}
+
+// read_entry_offsets reads the table of entry offsets (and displacements)
+// stored at the end of the basket buffer.
+// see TBasket::ReadBasketBuffers
+func (basket *Basket) read_entry_offsets(order binary.ByteOrder) (err error) {
+	last := int(basket.last)
+	if last <= 0 || last >= len(basket.buffer) {
+		return fmt.Errorf("groot: no entry offsets table in basket [%s] (last=%d len=%d)",
+			basket.Name(), last, len(basket.buffer))
+	}
+	b, err := NewBuffer(basket.buffer, order, 0)
 	if err != nil {
 		return err
 	}
-	panic("fixme")
-	return
+	b.skip_nbytes(last)
+	basket.entry_offset = b.read_array_I()
+	if b.Len() > 0 {
+		// remaining data is the displacement array
+		basket.displacement = b.read_array_I()
+	}
+	return b.err
 }
 
 func init() {
@@ -94,4 +213,8 @@ func init() {
 	Factory.db["*groot.Basket"] = f
 }
 
+// check interfaces
+var _ Object = (*Basket)(nil)
+var _ ROOTStreamer = (*Basket)(nil)
+
 // EOF
@@ -1,7 +1,9 @@
 package groot
 
 import (
+	"fmt"
 	"reflect"
+	"sort"
 )
 
 type ibranch interface {
@@ -15,17 +17,24 @@ type Branch struct {
 	file *File
 
 	autodelete     bool
+	compress       int32 // compression level and algorithm
+	basketSize     int32 // initial size of baskets
 	branches       []Branch
-	leaves         []baseLeaf
-	baskets        []Basket
-	entryOffsetLen uint32 // initial length of fEntryOffset table in the basket buffers
-	writeBasket    uint32 // last basket number written
-	entryNumber    uint32 // current entry number (last one filled in this branch)
-	readBasket     uint32 // current basket number when reading
+	leaves         []ileaf
+	baskets        []*Basket // baskets streamed along with the branch (if any)
+	entryOffsetLen uint32    // initial length of fEntryOffset table in the basket buffers
+	writeBasket    uint32    // last basket number written
+	entryNumber    uint32    // current entry number (last one filled in this branch)
+	readBasket     uint32    // current basket number when reading
+	entries        int64     // number of entries
+	totBytes       int64     // total number of bytes in all baskets (uncompressed)
+	zipBytes       int64     // total number of bytes in all baskets (compressed)
 
 	basketBytes []int32 // length of baskets on file
-	basketEntry []int32 // table of first entry of each basket
+	basketEntry []int64 // table of first entry of each basket
 	basketSeek  []int64 // addresses of baskets on file
+
+	element *BranchElement // description of the object held by a TBranchElement (nil for a TBranch)
 }
 
 func (branch *Branch) toBranch() *Branch {
@@ -33,6 +42,9 @@ func (branch *Branch) toBranch() *Branch {
 }
 
 func (branch *Branch) Class() string {
+	if branch.element != nil {
+		return branch.element.Class()
+	}
 	return "TBranch"
 }
 
@@ -54,79 +66,82 @@ func (branch *Branch) ROOTDecode(b *Buffer) (err error) {
 	maxbaskets := uint32(0)
 	splitlvl := int32(0)
 	if vers <= 5 {
-		b.ntoi4() // fCompress
-		b.ntoi4() // fBasketSize
+		branch.compress = b.ntoi4()
+		branch.basketSize = b.ntoi4()
 		branch.entryOffsetLen = b.ntou4()
 		maxbaskets = b.ntou4() // fMaxBaskets
 		branch.writeBasket = b.ntou4()
 		branch.entryNumber = b.ntou4()
-		b.ntod()  // entries
+		branch.entries = int64(b.ntod())
 		b.ntod()  // tot_bytes
 		b.ntod()  // zip_bytes
 		b.ntoi4() // fOffset
 	} else if vers <= 6 {
-		b.ntoi4() // fCompress
-		b.ntoi4() // fBasketSize
+		branch.compress = b.ntoi4()
+		branch.basketSize = b.ntoi4()
 		branch.entryOffsetLen = b.ntou4()
 		branch.writeBasket = b.ntou4()
 		branch.entryNumber = b.ntou4()
 		b.ntoi4()              // fOffset
 		maxbaskets = b.ntou4() // fMaxBaskets
-		b.ntod()               // entries
-		b.ntod()               // tot_bytes
-		b.ntod()               // zip_bytes
+		branch.entries = int64(b.ntod())
+		b.ntod() // tot_bytes
+		b.ntod() // zip_bytes
 	} else if vers <= 7 {
-		b.ntoi4() // fCompress
-		b.ntoi4() // fBasketSize
+		branch.compress = b.ntoi4()
+		branch.basketSize = b.ntoi4()
 		branch.entryOffsetLen = b.ntou4()
 		branch.writeBasket = b.ntou4()
 		branch.entryNumber = b.ntou4()
 		b.ntoi4()              // fOffset
 		maxbaskets = b.ntou4() // fMaxBaskets
 		splitlvl = b.ntoi4()   // fSplitLevel
-		b.ntod()               // entries
-		b.ntod()               // tot_bytes
-		b.ntod()               // zip_bytes
+		branch.entries = int64(b.ntod())
+		b.ntod() // tot_bytes
+		b.ntod() // zip_bytes
 	} else if vers <= 9 {
 		b.read_attfill()
-		b.ntoi4() // fCompress
-		b.ntoi4() // fBasketSize
+		branch.compress = b.ntoi4()
+		branch.basketSize = b.ntoi4()
 		branch.entryOffsetLen = b.ntou4()
 		branch.writeBasket = b.ntou4()
 		branch.entryNumber = b.ntou4()
 		b.ntoi4()              // fOffset
 		maxbaskets = b.ntou4() // fMaxBaskets
 		splitlvl = b.ntoi4()   // fSplitLevel
-		b.ntod()               // entries
-		b.ntod()               // tot_bytes
-		b.ntod()               // zip_bytes
+		branch.entries = int64(b.ntod())
+		b.ntod() // tot_bytes
+		b.ntod() // zip_bytes
 	} else if vers <= 10 {
 		b.read_attfill()
-		b.ntoi4() // fCompress
-		b.ntoi4() // fBasketSize
+		branch.compress = b.ntoi4()
+		branch.basketSize = b.ntoi4()
 		branch.entryOffsetLen = b.ntou4()
 		branch.writeBasket = b.ntou4()
 		branch.entryNumber = uint32(b.ntou8()) //fixme ?
 		b.ntoi4()                              // fOffset
 		maxbaskets = b.ntou4()                 // fMaxBaskets
 		splitlvl = b.ntoi4()                   // fSplitLevel
-		b.ntou8()                              // entries
-		b.ntou8()                              // tot_bytes
-		b.ntou8()                              // zip_bytes
+		branch.entries = b.ntoi8()
+		branch.totBytes = b.ntoi8()
+		branch.zipBytes = b.ntoi8()
 	} else { //vers>=11
 		b.read_attfill()
-		b.ntoi4() // fCompress
-		b.ntoi4() // fBasketSize
+		branch.compress = b.ntoi4()
+		branch.basketSize = b.ntoi4()
 		branch.entryOffsetLen = b.ntou4()
 		branch.writeBasket = b.ntou4()
 		branch.entryNumber = uint32(b.ntou8()) //fixme ?
-		b.ntoi4()                              // fOffset
-		maxbaskets = b.ntou4()                 // fMaxBaskets
-		splitlvl = b.ntoi4()                   // fSplitLevel
-		b.ntou8()                              // entries
-		b.ntou8()                              // fFirstEntry
-		b.ntou8()                              // tot_bytes
-		b.ntou8()                              // zip_bytes
+		if vers >= 13 {
+			b.read_iofeatures() // fIOFeatures
+		}
+		b.ntoi4()              // fOffset
+		maxbaskets = b.ntou4() // fMaxBaskets
+		splitlvl = b.ntoi4()   // fSplitLevel
+		branch.entries = b.ntoi8()
+		b.ntou8() // fFirstEntry
+		branch.totBytes = b.ntoi8()
+		branch.zipBytes = b.ntoi8()
 	}
 	printf("::branch::stream : [%s] split-lvl= %v\n", branch.name, splitlvl)
 
@@ -134,35 +149,62 @@ func (branch *Branch) ROOTDecode(b *Buffer) (err error) {
 	branches := b.read_obj_array()
 	printf("::branch::stream : branches : end\n")
 	printf("sub-branches: %v\n", len(branches))
+	branch.branches = make([]Branch, 0, len(branches))
+	for _, v := range branches {
+		if v, ok := v.(ibranch); ok {
+			branch.branches = append(branch.branches, *v.toBranch())
+		}
+	}
 
 	printf("::branch::stream : leaves : begin\n")
 	leaves := b.read_obj_array()
 	printf("::branch::stream : leaves : end\n")
 	printf("sub-leaves: %v\n", len(leaves))
+	branch.leaves = make([]ileaf, 0, len(leaves))
+	for _, v := range leaves {
+		if v, ok := v.(ileaf); ok {
+			branch.leaves = append(branch.leaves, v)
+		}
+	}
 
 	printf("::branch::stream : streamed_baskets : begin\n")
 	baskets := b.read_obj_array()
 	printf("::branch::stream : streamed_baskets : end\n")
 	printf("baskets: %v\n", len(baskets))
+	branch.baskets = make([]*Basket, len(baskets))
+	for i, v := range baskets {
+		if v, ok := v.(*Basket); ok {
+			branch.baskets[i] = v
+		}
+	}
 
-	branch.basketEntry = make([]int32, 0, int(maxbaskets))
-	branch.basketBytes = make([]int32, 0, int(maxbaskets))
+	if !b.check_len(int(maxbaskets), 1) {
+		return b.err
+	}
+	branch.basketEntry = make([]int64, int(maxbaskets))
+	branch.basketBytes = make([]int32, int(maxbaskets))
 	branch.basketSeek = make([]int64, int(maxbaskets))
 
 	if vers < 6 {
-		copy(branch.basketEntry, b.read_array_I())
-		if vers <= 4 {
-			branch.basketBytes = make([]int32, int(maxbaskets))
-		} else {
+		entries := b.read_array_I()
+		if len(entries) > len(branch.basketEntry) {
+			return fmt.Errorf("groot: branch [%s] has %d basket entries (max=%d)", branch.name, len(entries), maxbaskets)
+		}
+		for i, v := range entries {
+			branch.basketEntry[i] = int64(v)
+		}
+		if vers > 4 {
 			copy(branch.basketBytes, b.read_array_I())
 		}
 		if vers < 2 {
-			panic("branch.ROOTDecode of vers<2 *NOT* handled")
-		} else {
-			nseeks := int(b.ntoi4())
-			for i := 0; i < nseeks; i++ {
-				branch.basketSeek[i] = int64(b.ntoi4())
-			}
+			return fmt.Errorf("groot: version %d of TBranch is not supported", vers)
+		}
+		nseeks := int(b.ntoi4())
+		if nseeks > len(branch.basketSeek) {
+			return fmt.Errorf("groot: branch [%s] has %d basket seeks (max=%d)", branch.name, nseeks, maxbaskets)
+		}
+		for i := 0; i < nseeks; i++ {
+			branch.basketSeek[i] = int64(b.ntoi4())
 		}
 	} else if vers <= 9 {
 		// see TStreamerInfo::ReadBuffer::ReadBasicPointer
@@ -173,7 +215,9 @@ func (branch *Branch) ROOTDecode(b *Buffer) (err error) {
 		}
 		isarray = b.ntobyte()
 		if isarray != 0 {
-			copy(branch.basketEntry, b.read_fast_array_I(int(maxbaskets)))
+			for i, v := range b.read_fast_array_I(int(maxbaskets)) {
+				branch.basketEntry[i] = int64(v)
+			}
 		}
 		isbigfile := b.ntobyte()
 		if isbigfile == 2 {
@@ -195,18 +239,12 @@ func (branch *Branch) ROOTDecode(b *Buffer) (err error) {
 
 		isarray = b.ntobyte()
 		if isarray != 0 {
-			bentries := b.read_fast_array_UL(int(maxbaskets))
-			for _, v := range bentries {
-				branch.basketEntry = append(branch.basketEntry, int32(v))
-			}
+			copy(branch.basketEntry, b.read_fast_array_L(int(maxbaskets)))
 		}
 
 		isarray = b.ntobyte()
 		if isarray != 0 {
-			bentries := b.read_fast_array_UL(int(maxbaskets))
-			for i, v := range bentries {
-				branch.basketSeek[i] = int64(v)
-			}
+			copy(branch.basketSeek, b.read_fast_array_L(int(maxbaskets)))
 		}
 	}
 
@@ -219,10 +257,274 @@ func (branch *Branch) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (branch *Branch) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(13)
+	b.write_tnamed(branch.name, branch.title)
+	b.write_attfill(0, 1001)
+	b.i4ton(branch.compress)
+	b.i4ton(branch.basketSize)
+	b.u4ton(branch.entryOffsetLen)
+	b.u4ton(branch.writeBasket)
+	b.i8ton(int64(branch.entryNumber))
+	b.write_iofeatures()
+	b.i4ton(0) // fOffset
+	maxbaskets := len(branch.basketBytes)
+	b.i4ton(int32(maxbaskets))
+	b.i4ton(0) // fSplitLevel
+	b.i8ton(branch.entries)
+	b.i8ton(0) // fFirstEntry
+	b.i8ton(branch.totBytes)
+	b.i8ton(branch.zipBytes)
+
+	branches := make([]Object, len(branch.branches))
+	for i := range branch.branches {
+		branches[i] = &branch.branches[i]
+	}
+	err = b.write_obj_array("", branches)
+	if err != nil {
+		return err
+	}
+
+	leaves := make([]Object, len(branch.leaves))
+	for i, leaf := range branch.leaves {
+		leaves[i] = leaf
+	}
+	err = b.write_obj_array("", leaves)
+	if err != nil {
+		return err
+	}
+
+	// all baskets have been written to file
+	err = b.write_obj_array("", nil)
+	if err != nil {
+		return err
+	}
+
+	b.byteton(1)
+	b.write_fast_array_I(branch.basketBytes)
+	b.byteton(1)
+	b.write_fast_array_L(branch.basketEntry)
+	b.byteton(1)
+	b.write_fast_array_L(branch.basketSeek)
+
+	b.write_tstring("") // fFileName
+	b.set_byte_count(pos)
 	return
 }
 
+// Entries returns the number of entries in this branch
+func (branch *Branch) Entries() int64 {
+	return branch.entries
+}
+
+// Branches returns the sub-branches of this branch
+func (branch *Branch) Branches() []Branch {
+	return branch.branches
+}
+
+// is_readable returns whether all the leaves of this branch can be read
+func (branch *Branch) is_readable() bool {
+	if branch.element != nil {
+		// the leaves of a TBranchElement are read through its StreamerInfo
+		return true
+	}
+	if len(branch.leaves) == 0 {
+		return false
+	}
+	for _, leaf := range branch.leaves {
+		if _, ok := leaf.(*LeafElement); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// set_file attaches this branch (and its sub-branches) to file f
+// and connects the leaves to their branch.
+func (branch *Branch) set_file(f *File) {
+	branch.file = f
+	for _, leaf := range branch.leaves {
+		leaf.toBaseLeaf().branch = branch
+	}
+	for i := range branch.branches {
+		branch.branches[i].set_file(f)
+	}
+}
+
+// find_basket returns the index of the basket holding the given entry
+func (branch *Branch) find_basket(entry int64) int {
+	return sort.Search(branch.nbaskets(), func(i int) bool {
+		return branch.basketEntry[i] > entry
+	}) - 1
+}
+
+// load_basket makes the j-th basket of this branch the current one in the
+// reader state s, reading it either from file or from the baskets which have
+// been streamed along with the branch.
+func (branch *Branch) load_basket(j int, s *read_state) (basket *Basket, err error) {
+	bs := s.branch(branch)
+	if bs.basket != nil && bs.ibasket == j {
+		return bs.basket, nil
+	}
+
+	if j < len(branch.basketSeek) && branch.basketSeek[j] != 0 {
+		branch.prefetch_baskets(j, s)
+	}
+	basket, err = branch.decode_basket(j, s.tc)
+	if err != nil {
+		return nil, err
+	}
+	bs.set_basket(j, basket)
+	return basket, err
+}
+
+// decode_basket reads and decompresses the j-th basket of this branch, from
+// the cache tc if it holds it.
+// decode_basket does not modify the branch: the baskets of a branch may be
+// decoded concurrently.
+func (branch *Branch) decode_basket(j int, tc *tree_cache) (basket *Basket, err error) {
+	switch {
+	case j < len(branch.basketSeek) && branch.basketSeek[j] != 0:
+		basket, err = read_basket(
+			branch.file,
+			tc,
+			branch.basketSeek[j],
+			int(branch.basketBytes[j]),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("groot: could not read basket #%d of branch [%s] (offset=%d): %w",
+				j, branch.name, branch.basketSeek[j], err)
+		}
+	case j < len(branch.baskets) && branch.baskets[j] != nil && branch.baskets[j].buffer != nil:
+		// the streamed baskets are shared by all the readers of the branch
+		bk := *branch.baskets[j]
+		basket = &bk
+	default:
+		return nil, fmt.Errorf("groot: no basket #%d for branch [%s]", j, branch.name)
+	}
+
+	if branch.entryOffsetLen > 0 && basket.entry_offset == nil {
+		err = basket.read_entry_offsets(branch.file.order)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return basket, err
+}
+
+// nbaskets returns the number of baskets of this branch
+func (branch *Branch) nbaskets() int {
+	n := int(branch.writeBasket) + 1
+	if n > len(branch.basketEntry) {
+		n = len(branch.basketEntry)
+	}
+	return n
+}
+
+// prefetch_baskets asks the file to fetch, in one go, the baskets of this
+// branch starting at the j-th one.
+// errors are ignored: the baskets are read again when they are loaded.
+func (branch *Branch) prefetch_baskets(j int, s *read_state) {
+	if s.tc != nil {
+		// the baskets are read ahead by the cache
+		return
+	}
+	bs := s.branch(branch)
+	if bs.prefetched[0] <= j && j < bs.prefetched[1] {
+		return
+	}
+	n := len(branch.basketSeek)
+	if n > len(branch.basketBytes) {
+		n = len(branch.basketBytes)
+	}
+	spans := make([]span, 0, kPrefetchBaskets)
+	end := j
+	for ; end < n && end < j+kPrefetchBaskets; end++ {
+		if branch.basketSeek[end] == 0 {
+			break
+		}
+		spans = append(spans, span{branch.basketSeek[end], int64(branch.basketBytes[end])})
+	}
+	bs.prefetched = [2]int{j, end}
+	branch.file.prefetch(spans)
+}
+
+// load_entry reads the values of the leaves of this branch for the given
+// entry into the reader state s
+func (branch *Branch) load_entry(entry int64, s *read_state) (err error) {
+	bs := s.branch(branch)
+	if entry == bs.entry {
+		return
+	}
+	b, err := branch.entry_buffer(entry, s)
+	if err != nil {
+		return err
+	}
+
+	for _, leaf := range branch.leaves {
+		base := leaf.toBaseLeaf()
+		err = base.load_count(entry, s)
+		if err != nil {
+			return err
+		}
+		data, err := leaf.read_basket(b, base.count(s), s.leaves[leaf])
+		if err == nil {
+			err = b.err
+		}
+		if err != nil {
+			return fmt.Errorf("groot: branch [%s], entry %d, leaf [%s]: %w", branch.name, entry, leaf.Name(), err)
+		}
+		s.leaves[leaf] = data
+	}
+	bs.entry = entry
+	return
+}
+
+// entry_buffer returns a buffer positioned at the start of the data of the
+// given entry, in the basket holding that entry.
+// the basket becomes the current one of the branch in the reader state s.
+func (branch *Branch) entry_buffer(entry int64, s *read_state) (b *Buffer, err error) {
+	if entry < 0 || entry >= branch.entries {
+		return nil, fmt.Errorf("groot: entry %d out of range for branch [%s] (entries=%d)",
+			entry, branch.name, branch.entries)
+	}
+
+	j := branch.find_basket(entry)
+	if j < 0 {
+		return nil, fmt.Errorf("groot: no basket for entry %d in branch [%s]",
+			entry, branch.name)
+	}
+	basket, err := branch.load_basket(j, s)
+	if err != nil {
+		return nil, err
+	}
+
+	ientry := int(entry - branch.basketEntry[j])
+	pos := 0
+	if basket.entry_offset != nil {
+		if ientry >= len(basket.entry_offset) {
+			return nil, fmt.Errorf("groot: no offset for entry %d in branch [%s]",
+				entry, branch.name)
+		}
+		pos = int(basket.entry_offset[ientry])
+	} else {
+		pos = int(basket.key.keysz) + ientry*int(basket.nev_bufsz)
+	}
+	if pos < 0 || pos > len(basket.buffer) {
+		return nil, fmt.Errorf("groot: invalid offset for entry %d in branch [%s]",
+			entry, branch.name)
+	}
+
+	// the basket buffer holds the key header: references to objects are
+	// relative to its start.
+	b, err = NewBuffer(basket.buffer, branch.file.order, 0)
+	if err != nil {
+		return nil, err
+	}
+	b.file = branch.file
+	b.skip_nbytes(pos)
+	return b, err
+}
+
 func init() {
 	f := func() reflect.Value {
 		o := &Branch{}
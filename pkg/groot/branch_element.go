@@ -1,17 +1,41 @@
 package groot
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
+)
+
+// types of TBranchElements
+// see TBranchElement::fType
+const (
+	kBranchElemLeaf       = 0  // leaf-node (or top-level) branch
+	kBranchElemBase       = 1  // base class
+	kBranchElemObject     = 2  // split object member
+	kBranchElemClones     = 3  // TClonesArray
+	kBranchElemSTL        = 4  // STL collection
+	kBranchElemClonesNode = 31 // member of the objects of a TClonesArray
+	kBranchElemSTLNode    = 41 // member of the objects of an STL collection
 )
 
 type BranchElement struct {
 	branch Branch
 	object Object
 	class  string // class name of referenced object
+	parent string // name of parent class
+	clones string // class name of the objects held by a TClonesArray or STL collection
+	chksum uint32 // checksum of the class
 	vers   int    // version number of class
 	id     int    // element serial number in fInfo
 	btype  int    // branch type
 	stype  int    // branch streamer type
+	max    int    // maximum entries for a TClonesArray or variable array
+
+	bcount  *BranchElement // fBranchCount, as read from file
+	bcount2 *BranchElement // fBranchCount2, as read from file
+
+	count  *Branch // branch holding the collection (for members of the objects of a collection)
+	count2 *Branch // branch holding the sizes of the variable-size arrays of the objects of a collection
 }
 
 func (be *BranchElement) toBranch() *Branch {
@@ -19,7 +43,7 @@ func (be *BranchElement) toBranch() *Branch {
 }
 
 func (be *BranchElement) Class() string {
-	panic("not implemented")
+	return "TBranchElement"
 }
 
 func (be *BranchElement) Name() string {
@@ -49,18 +73,27 @@ func (be *BranchElement) ROOTDecode(b *Buffer) (err error) {
 		be.stype = int(b.ntoi4())
 	} else { // vers >= 8
 		be.class = b.read_tstring()
-		b.read_tstring() // fParentName
-		b.read_tstring() // fCloneName
-		b.ntoi4()        // fCheckSum
-		be.vers = int(b.ntoi4())
+		be.parent = b.read_tstring()
+		be.clones = b.read_tstring()
+		be.chksum = b.ntou4()
+		if vers >= 10 {
+			be.vers = int(b.ntoi2())
+		} else {
+			be.vers = int(b.ntoi4())
+		}
 		be.id = int(b.ntoi4())
 		be.btype = int(b.ntoi4())
 		be.stype = int(b.ntoi4())
-		b.ntoi4() // fMaximum
+		be.max = int(b.ntoi4())
 
-		b.read_object() // fBranchCount  *TBranchElement
-		b.read_object() // fBranchCount2 *TBranchElement
+		if bc, ok := b.read_object().(*BranchElement); ok {
+			be.bcount = bc
+		}
+		if bc, ok := b.read_object().(*BranchElement); ok {
+			be.bcount2 = bc
+		}
 	}
+	be.branch.element = be
 	b.check_byte_count(pos, bcnt, spos, "TBranchElement")
 	return
 }
@@ -70,6 +103,254 @@ func (be *BranchElement) ROOTEncode(b *Buffer) (err error) {
 	return
 }
 
+// streamer_element returns the streamer element of the data member held by
+// this TBranchElement.
+func (branch *Branch) streamer_element() (StreamerElement, error) {
+	be := branch.element
+	si := branch.file.find_streamer_info(be.class, be.vers)
+	if si == nil {
+		si = branch.file.find_streamer_info(be.class, -1)
+	}
+	if si == nil {
+		return nil, fmt.Errorf("groot: no StreamerInfo for class [%s] (branch [%s])", be.class, branch.name)
+	}
+	if be.id < 0 || be.id >= len(si.elmts) || si.elmts[be.id] == nil {
+		return nil, fmt.Errorf("groot: invalid streamer element #%d of class [%s] (branch [%s])",
+			be.id, be.class, branch.name)
+	}
+	return si.elmts[be.id], nil
+}
+
+// read_value reads the value held by this TBranchElement for the given entry:
+// the whole object for a top-level branch, the value of its data member
+// otherwise.
+func (branch *Branch) read_value(entry int64, s *read_state) (v interface{}, err error) {
+	be := branch.element
+	if be.id < 0 {
+		return branch.read_object(entry, s)
+	}
+
+	if be.count != nil {
+		return branch.read_column(entry, s)
+	}
+
+	obj := new_generic_object(be.class)
+	err = branch.read_member(obj, entry, s)
+	if err != nil {
+		return nil, err
+	}
+	elmt, err := branch.streamer_element()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := elmt.(*StreamerBase); ok {
+		return obj, err
+	}
+	return obj.Value(elmt.Name()), err
+}
+
+// read_column reads the values of the data member held by this branch, for
+// all the objects of the collection held by its parent branch.
+func (branch *Branch) read_column(entry int64, s *read_state) (v interface{}, err error) {
+	b, err := branch.element.count.entry_buffer(entry, s)
+	if err != nil {
+		return nil, err
+	}
+	n := int(b.ntoi4())
+	if n < 0 || b.err != nil {
+		return nil, fmt.Errorf("groot: invalid number of objects (%d) in branch [%s]", n, branch.element.count.name)
+	}
+	elmt, err := branch.streamer_element()
+	if err != nil {
+		return nil, err
+	}
+	bb, err := branch.entry_buffer(entry, s)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := new_generic_object(branch.element.class)
+	rt := basic_types[elmt.Type()]
+	if rt == nil {
+		rt = g_iface_type
+	}
+	values := reflect.MakeSlice(reflect.SliceOf(rt), n, n)
+	for i := 0; i < n; i++ {
+		err = obj.read_element(bb, elmt)
+		if err == nil {
+			err = bb.err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("groot: branch [%s], entry %d: %w", branch.name, entry, err)
+		}
+		set_value(values.Index(i), obj.Value(elmt.Name()))
+	}
+	return values.Interface(), err
+}
+
+// read_object reads the object held by a top-level TBranchElement
+func (branch *Branch) read_object(entry int64, s *read_state) (v interface{}, err error) {
+	be := branch.element
+	switch {
+	case be.btype == kBranchElemClones, be.btype == kBranchElemSTL:
+		return branch.read_collection(entry, s)
+
+	case len(branch.branches) == 0:
+		// unsplit branch: the whole object is streamed for each entry
+		b, err := branch.entry_buffer(entry, s)
+		if err != nil {
+			return nil, err
+		}
+		v, err = read_object_value(b, KObject, be.class)
+		if err == nil {
+			err = b.err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("groot: branch [%s], entry %d: %w", branch.name, entry, err)
+		}
+		return v, err
+	}
+
+	// split branch: each data member is held by a sub-branch
+	obj := new_generic_object(be.class)
+	obj.version = be.vers
+	err = branch.read_members(obj, entry, s)
+	if err != nil {
+		return nil, err
+	}
+	return obj, err
+}
+
+// read_members reads the data members held by the sub-branches of this
+// branch into obj
+func (branch *Branch) read_members(obj *GenericObject, entry int64, s *read_state) (err error) {
+	for i := range branch.branches {
+		sub := &branch.branches[i]
+		if sub.element == nil {
+			return fmt.Errorf("groot: branch [%s] is not a TBranchElement", sub.name)
+		}
+		err = sub.read_member(obj, entry, s)
+		if err != nil {
+			return err
+		}
+	}
+	return
+}
+
+// read_member reads the data member held by this branch into obj
+func (branch *Branch) read_member(obj *GenericObject, entry int64, s *read_state) (err error) {
+	be := branch.element
+	elmt, err := branch.streamer_element()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case be.btype == kBranchElemClones, be.btype == kBranchElemSTL:
+		v, err := branch.read_collection(entry, s)
+		if err != nil {
+			return err
+		}
+		obj.set(elmt.Name(), v)
+		return err
+
+	case len(branch.branches) > 0:
+		// split base class or object member
+		if _, ok := elmt.(*StreamerBase); ok {
+			return branch.read_members(obj, entry, s)
+		}
+		sub := new_generic_object(strings.TrimSpace(strings.TrimRight(elmt.TypeName(), "*")))
+		err = branch.read_members(sub, entry, s)
+		if err != nil {
+			return err
+		}
+		obj.set(elmt.Name(), sub)
+		return err
+	}
+
+	b, err := branch.entry_buffer(entry, s)
+	if err != nil {
+		return err
+	}
+	err = obj.read_element(b, elmt)
+	if err == nil {
+		err = b.err
+	}
+	if err != nil {
+		return fmt.Errorf("groot: branch [%s], entry %d: %w", branch.name, entry, err)
+	}
+	return err
+}
+
+// read_collection reads the objects of the split TClonesArray or STL
+// collection held by this branch.
+// the branch holds the number of objects, each of its sub-branches holds
+// the values of one data member for all the objects.
+func (branch *Branch) read_collection(entry int64, s *read_state) (v []interface{}, err error) {
+	be := branch.element
+	b, err := branch.entry_buffer(entry, s)
+	if err != nil {
+		return nil, err
+	}
+	n := int(b.ntoi4())
+	if n < 0 || b.err != nil {
+		return nil, fmt.Errorf("groot: invalid number of objects (%d) in branch [%s]", n, branch.name)
+	}
+
+	class := be.clones
+	if class == "" {
+		// e.g. vector<MyStruct>
+		typename := be.class
+		if be.id >= 0 {
+			elmt, err := branch.streamer_element()
+			if err != nil {
+				return nil, err
+			}
+			typename = elmt.TypeName()
+		}
+		if t, err := new_stl_type(typename); err == nil && t.kind == stl_seq {
+			class = t.elem.name
+		}
+	}
+	objs := make([]*GenericObject, n)
+	for i := range objs {
+		objs[i] = new_generic_object(class)
+	}
+
+	for i := range branch.branches {
+		sub := &branch.branches[i]
+		if sub.element == nil {
+			return nil, fmt.Errorf("groot: branch [%s] is not a TBranchElement", sub.name)
+		}
+		if len(sub.branches) > 0 {
+			return nil, fmt.Errorf("groot: split objects in collections are not supported (branch [%s])", sub.name)
+		}
+		elmt, err := sub.streamer_element()
+		if err != nil {
+			return nil, err
+		}
+		bb, err := sub.entry_buffer(entry, s)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			err = obj.read_element(bb, elmt)
+			if err == nil {
+				err = bb.err
+			}
+			if err != nil {
+				return nil, fmt.Errorf("groot: branch [%s], entry %d: %w", sub.name, entry, err)
+			}
+		}
+	}
+
+	v = make([]interface{}, n)
+	for i, obj := range objs {
+		v[i] = obj
+	}
+	return v, err
+}
+
 func init() {
 	f := func() reflect.Value {
 		o := &BranchElement{}
@@ -0,0 +1,312 @@
+package groot
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hit_sinfo describes the class "Hit", held by the TBranchElements of the
+// tests
+var hit_sinfo = &StreamerInfo{
+	name:      "Hit",
+	classvers: 1,
+	elmts: []StreamerElement{
+		se_basic("fX", "", KDouble, "double"),
+		se_basic("fY", "", KFloat, "float"),
+		se_basic("fId", "", KInt, "int"),
+	},
+}
+
+// hit_size is the size of a "Hit" streamed with its version
+const hit_size = 6 + 8 + 4 + 4
+
+// create_element_tree creates a tree with the TBranchElements:
+//   - "hit": a split "Hit", with a sub-branch per data member,
+//   - "hits": a split TClonesArray of 2 "Hit"s,
+//   - "raw": an unsplit "Hit".
+//
+// groot does not write TBranchElements: the baskets of their branches are
+// written by plain branches, which are then turned into TBranchElements.
+func create_element_tree(t *testing.T, nevts int) *Tree {
+	fname := filepath.Join(t.TempDir(), "elements.root")
+	{
+		f, err := Create(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewTreeWriter(f.Dir(), "tree", "my tree")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			x   float64
+			y   float32
+			id  int32
+			n   int32
+			xs  [2]float64
+			ys  [2]float32
+			ids [2]int32
+			raw [hit_size]uint8
+		)
+		for _, br := range []struct {
+			name string
+			ptr  interface{}
+		}{
+			{"hit.fX", &x}, {"hit.fY", &y}, {"hit.fId", &id},
+			{"hits", &n}, {"hits.fX", &xs}, {"hits.fY", &ys}, {"hits.fId", &ids},
+			{"raw", &raw},
+		} {
+			err = w.Branch(br.name, br.ptr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := 0; i < nevts; i++ {
+			x, y, id = float64(i), float32(i)+0.5, int32(-i)
+			n = 2
+			for j := range xs {
+				xs[j], ys[j], ids[j] = float64(10*i+j), float32(j)+0.25, int32(j)
+			}
+
+			wb, err := NewWBuffer(binary.BigEndian, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pos := wb.write_version(1)
+			wb.dton(float64(2 * i))
+			wb.fton(1.5)
+			wb.i4ton(int32(i))
+			wb.set_byte_count(pos)
+			copy(raw[:], wb.Bytes())
+
+			err = w.Fill()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	obj, err := f.Get("tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := obj.(*Tree)
+	f.sinfos = append(f.sinfos, hit_sinfo)
+
+	element := func(br *Branch, class string, id, btype int) {
+		br.element = &BranchElement{class: class, vers: 1, id: id, btype: btype}
+	}
+	split := func(name, class string, btype, ntype int, brs []Branch) Branch {
+		parent := Branch{name: name, entries: int64(tree.entries)}
+		if btype == kBranchElemClones {
+			// the number of objects is held by the branch itself
+			parent = brs[0]
+			brs = brs[1:]
+		}
+		element(&parent, class, -1, btype)
+		parent.branches = brs
+		for i := range parent.branches {
+			element(&parent.branches[i], "Hit", i, ntype)
+		}
+		parent.set_file(f)
+		return parent
+	}
+	brs := tree.branches
+	tree.branches = []Branch{
+		split("hit", "Hit", kBranchElemLeaf, kBranchElemLeaf, append([]Branch{}, brs[0:3]...)),
+		split("hits", "TClonesArray", kBranchElemClones, kBranchElemClonesNode, append([]Branch{}, brs[3:7]...)),
+		brs[7],
+	}
+	tree.branches[1].element.clones = "Hit"
+	element(&tree.branches[2], "Hit", -1, kBranchElemLeaf)
+	tree.branches[2].set_file(f)
+	tree.attach_leaf_counts()
+	return tree
+}
+
+func TestBranchElement(t *testing.T) {
+	const nevts = 10
+	tree := create_element_tree(t, nevts)
+
+	if br := tree.Branch("hits.fY"); br == nil || br.element.count != tree.Branch("hits") {
+		t.Fatalf("members of the objects of a collection should be read column-wise")
+	}
+	if br := tree.Branch("hit.fY"); br == nil || br.element.count != nil {
+		t.Fatalf("members of a split object should not be read column-wise")
+	}
+
+	r, err := tree.NewReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hit := func(v interface{}) []interface{} {
+		obj, ok := v.(*GenericObject)
+		if !ok || obj.Class() != "Hit" {
+			return []interface{}{v}
+		}
+		return []interface{}{obj.Value("fX"), obj.Value("fY"), obj.Value("fId")}
+	}
+	i := 0
+	for ; r.Next(); i++ {
+		for _, tc := range []struct {
+			name string
+			got  []interface{}
+			want []interface{}
+		}{
+			{"hit", hit(r.Value("hit")), []interface{}{float64(i), float32(i) + 0.5, int32(-i)}},
+			{"raw", hit(r.Value("raw")), []interface{}{float64(2 * i), float32(1.5), int32(i)}},
+		} {
+			if !reflect.DeepEqual(tc.got, tc.want) {
+				t.Errorf("entry %d: %s: got %v, want %v", i, tc.name, tc.got, tc.want)
+			}
+		}
+
+		hits, ok := r.Value("hits").([]interface{})
+		if !ok || len(hits) != 2 {
+			t.Fatalf("entry %d: hits: got %#v", i, r.Value("hits"))
+		}
+		for j := range hits {
+			got := hit(hits[j])
+			want := []interface{}{float64(10*i + j), float32(j) + 0.25, int32(j)}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("entry %d: hit #%d: got %v, want %v", i, j, got, want)
+			}
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if i != nevts {
+		t.Fatalf("read %d entries, want %d", i, nevts)
+	}
+
+	// data members, alone
+	r, err = tree.NewReader("hit.fY", "hits.fX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Entry(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Value("hit.fY"), float32(3.5); got != want {
+		t.Errorf("hit.fY: got %#v, want %#v", got, want)
+	}
+	if got, want := r.Value("hits.fX"), []float64{30, 31}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hits.fX: got %#v, want %#v", got, want)
+	}
+}
+
+func TestBranchElementErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		branch string
+		modify func(tree *Tree)
+		err    string
+	}{
+		{
+			name:   "invalid element",
+			branch: "hit",
+			modify: func(tree *Tree) { tree.Branch("hit.fId").element.id = 3 },
+			err:    "invalid streamer element #3 of class [Hit] (branch [hit.fId])",
+		},
+		{
+			name:   "unknown class",
+			branch: "hits.fX",
+			modify: func(tree *Tree) { tree.Branch("hits.fX").element.class = "Hit2" },
+			err:    "no StreamerInfo for class [Hit2] (branch [hits.fX])",
+		},
+		{
+			name:   "invalid number of objects",
+			branch: "hits",
+			modify: func(tree *Tree) {
+				// the values of "hit.fId" (0, -1) are read as numbers of objects
+				br := tree.Branch("hits")
+				sub := tree.Branch("hit.fId")
+				br.basketSeek, br.basketBytes = sub.basketSeek, sub.basketBytes
+			},
+			err: "invalid number of objects (-1) in branch [hits]",
+		},
+	} {
+		tree := create_element_tree(t, 2)
+		tc.modify(tree)
+		r, err := tree.NewReader(tc.branch)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for r.Next() {
+		}
+		if err := r.Err(); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got err=%v, want %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestBranchElementReadWrite(t *testing.T) {
+	w, err := NewWBuffer(binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := w.write_version(10)
+	br := Branch{name: "hits.fX", title: "fX[hits_]"}
+	err = br.ROOTEncode(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.write_tstring("Hit")
+	w.write_tstring("Event")
+	w.write_tstring("")
+	w.u4ton(0xcafe)
+	w.i2ton(1)
+	w.i4ton(2)
+	w.i4ton(kBranchElemClonesNode)
+	w.i4ton(0)
+	w.i4ton(16)
+	w.write_object(nil) // fBranchCount
+	w.write_object(nil) // fBranchCount2
+	w.set_byte_count(pos)
+
+	b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var be BranchElement
+	err = be.ROOTDecode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("%d bytes left", b.Len())
+	}
+	got := BranchElement{
+		class: be.class, parent: be.parent, clones: be.clones, chksum: be.chksum,
+		vers: be.vers, id: be.id, btype: be.btype, stype: be.stype, max: be.max,
+	}
+	want := BranchElement{
+		class: "Hit", parent: "Event", chksum: 0xcafe,
+		vers: 1, id: 2, btype: kBranchElemClonesNode, max: 16,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if be.Name() != "hits.fX" || be.Title() != "fX[hits_]" || be.branch.element != &be {
+		t.Errorf("got branch name=%q title=%q", be.Name(), be.Title())
+	}
+	if be.bcount != nil || be.bcount2 != nil {
+		t.Errorf("got counts %v %v", be.bcount, be.bcount2)
+	}
+}
+
+// EOF
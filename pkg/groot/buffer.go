@@ -13,27 +13,61 @@ type Buffer struct {
 	data  []byte           // data source
 	buf   *bytes.Buffer    // buffer for more efficient i/o from r
 	klen  uint32           // to compute refs (used in read_class, read_object)
+	file  *File            // file the data comes from (to look up streamer infos)
+	err   error            // first error encountered while reading
+
+	robjs    map[uint32]Object // objects already read, by ref
+	rclasses map[uint32]string // classes already read, by ref
+
+	w        bool                   // whether this buffer is used for writing
+	wobjs    map[interface{}]uint32 // refs of the objects already written
+	wclasses map[string]uint32      // refs of the classes already written
 }
 
 func NewBuffer(data []byte, order binary.ByteOrder, klen uint32) (b *Buffer, err error) {
 	b = &Buffer{
-		order: order,
-		data:  data,
-		klen:  klen,
+		order:    order,
+		data:     data,
+		klen:     klen,
+		robjs:    make(map[uint32]Object),
+		rclasses: make(map[uint32]string),
 	}
 	b.buf = bytes.NewBuffer(b.data)
 	return
 }
 
+// NewWBuffer creates a new buffer for writing.
+// klen is the length of the key header which will precede the content of the
+// buffer on file (to compute refs.)
+func NewWBuffer(order binary.ByteOrder, klen uint32) (b *Buffer, err error) {
+	b = &Buffer{
+		order:    order,
+		buf:      new(bytes.Buffer),
+		klen:     klen,
+		w:        true,
+		wobjs:    make(map[interface{}]uint32),
+		wclasses: make(map[string]uint32),
+	}
+	return
+}
+
 func NewBufferFromKey(k *Key) (b *Buffer, err error) {
 	buf, err := k.Buffer()
 	if err != nil {
 		return
 	}
-	return NewBuffer(buf, k.file.order, uint32(k.keysz))
+	b, err = NewBuffer(buf, k.file.order, uint32(k.keysz))
+	if err != nil {
+		return
+	}
+	b.file = k.file
+	return
 }
 
 func (b *Buffer) Pos() int {
+	if b.w {
+		return b.buf.Len()
+	}
 	return len(b.data) - b.Len()
 }
 
@@ -46,97 +80,137 @@ func (b *Buffer) Bytes() []byte {
 }
 
 func (b *Buffer) clone() *Buffer {
+	return b.clone_at(b.Pos())
+}
+
+// clone_at returns a clone of this buffer, positioned at pos.
+// the clone shares the table of refs of this buffer.
+func (b *Buffer) clone_at(pos int) *Buffer {
 	bb, err := NewBuffer(b.data, b.order, b.klen)
 	if err != nil {
 		return nil
 	}
-	bb.buf.Next(b.Pos())
+	bb.file = b.file
+	bb.robjs = b.robjs
+	bb.rclasses = b.rclasses
+	bb.buf.Next(pos)
 	return bb
 }
 
+// ref returns the ref of the object (or class) starting at pos
+// see TBufferFile::MapObject
+func (b *Buffer) ref(pos int) uint32 {
+	return uint32(pos) + b.klen + kMapOffset
+}
+
+// ref_pos returns the position in this buffer of the object (or class)
+// with the given ref
+func (b *Buffer) ref_pos(ref uint32) int {
+	return int(ref) - int(b.klen) - kMapOffset
+}
+
 func (b *Buffer) rewind_nbytes(nbytes int) {
 	idx := b.Pos()
 	b.buf = bytes.NewBuffer(b.data[idx-nbytes:])
 }
 
+func (b *Buffer) skip_nbytes(nbytes int) {
+	b.buf.Next(nbytes)
+}
+
 func (b *Buffer) read_nbytes(nbytes int) (o []byte) {
+	if !b.check_len(nbytes, 1) {
+		return nil
+	}
 	o = make([]byte, nbytes)
 	_, err := b.buf.Read(o)
-	if err != nil {
-		panic(err)
+	if err != nil && nbytes > 0 {
+		b.set_err(fmt.Errorf("groot: could not read %d bytes at offset %d: %w", nbytes, b.Pos(), err))
 	}
 	return
 }
 
-func (b *Buffer) ntoi2() (o int16) {
-	err := binary.Read(b.buf, b.order, &o)
+// read_value reads the fixed-size value v
+func (b *Buffer) read_value(v interface{}) {
+	if b.err != nil {
+		return
+	}
+	pos := b.Pos()
+	err := binary.Read(b.buf, b.order, v)
 	if err != nil {
-		panic(err)
+		b.set_err(fmt.Errorf("groot: could not read %d bytes at offset %d: %w", binary.Size(v), pos, err))
+	}
+}
+
+// check_len checks that n values of size bytes each can be read from the
+// buffer
+func (b *Buffer) check_len(n, size int) bool {
+	if b.err != nil {
+		return false
 	}
+	if n < 0 || int64(n)*int64(size) > int64(b.Len()) {
+		b.set_err(fmt.Errorf("groot: invalid length %d at offset %d (%d bytes left)", n, b.Pos(), b.Len()))
+		return false
+	}
+	return true
+}
+
+// Err returns the first error encountered while reading from the buffer.
+// Once an error has been encountered, all subsequent reads return zero
+// values.
+func (b *Buffer) Err() error {
+	return b.err
+}
+
+// set_err records err, if it is the first error encountered while reading
+func (b *Buffer) set_err(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *Buffer) ntoi2() (o int16) {
+	b.read_value(&o)
 	return
 }
 
 func (b *Buffer) ntoi4() (o int32) {
-	err := binary.Read(b.buf, b.order, &o)
-	if err != nil {
-		panic(err)
-	}
+	b.read_value(&o)
 	return
 }
 
 func (b *Buffer) ntoi8() (o int64) {
-	err := binary.Read(b.buf, b.order, &o)
-	if err != nil {
-		panic(err)
-	}
+	b.read_value(&o)
 	return
 }
 
 func (b *Buffer) ntobyte() (o byte) {
-	err := binary.Read(b.buf, b.order, &o)
-	if err != nil {
-		panic(err)
-	}
+	b.read_value(&o)
 	return
 }
 
 func (b *Buffer) ntou2() (o uint16) {
-	err := binary.Read(b.buf, b.order, &o)
-	if err != nil {
-		panic(err)
-	}
+	b.read_value(&o)
 	return
 }
 
 func (b *Buffer) ntou4() (o uint32) {
-	err := binary.Read(b.buf, b.order, &o)
-	if err != nil {
-		panic(err)
-	}
+	b.read_value(&o)
 	return
 }
 
 func (b *Buffer) ntou8() (o uint64) {
-	err := binary.Read(b.buf, b.order, &o)
-	if err != nil {
-		panic(err)
-	}
+	b.read_value(&o)
 	return
 }
 
 func (b *Buffer) ntof() (o float32) {
-	err := binary.Read(b.buf, b.order, &o)
-	if err != nil {
-		panic(err)
-	}
+	b.read_value(&o)
 	return
 }
 
 func (b *Buffer) ntod() (o float64) {
-	err := binary.Read(b.buf, b.order, &o)
-	if err != nil {
-		panic(err)
-	}
+	b.read_value(&o)
 	return
 }
 
@@ -152,6 +226,9 @@ func (b *Buffer) read_bool() (o bool) {
 
 func (b *Buffer) read_array_F() (o []float32) {
 	n := int(b.ntou4())
+	if !b.check_len(n, 4) {
+		return nil
+	}
 	o = make([]float32, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntof()
@@ -161,6 +238,9 @@ func (b *Buffer) read_array_F() (o []float32) {
 
 func (b *Buffer) read_array_D() (o []float64) {
 	n := int(b.ntou4())
+	if !b.check_len(n, 8) {
+		return nil
+	}
 	o = make([]float64, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntod()
@@ -170,6 +250,9 @@ func (b *Buffer) read_array_D() (o []float64) {
 
 func (b *Buffer) read_array_S() (o []int16) {
 	n := int(b.ntou4())
+	if !b.check_len(n, 2) {
+		return nil
+	}
 	o = make([]int16, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntoi2()
@@ -179,6 +262,9 @@ func (b *Buffer) read_array_S() (o []int16) {
 
 func (b *Buffer) read_array_I() (o []int32) {
 	n := int(b.ntou4())
+	if !b.check_len(n, 4) {
+		return nil
+	}
 	o = make([]int32, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntoi4()
@@ -188,6 +274,9 @@ func (b *Buffer) read_array_I() (o []int32) {
 
 func (b *Buffer) read_array_L() (o []int64) {
 	n := int(b.ntou4())
+	if !b.check_len(n, 8) {
+		return nil
+	}
 	o = make([]int64, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntoi8()
@@ -197,6 +286,9 @@ func (b *Buffer) read_array_L() (o []int64) {
 
 func (b *Buffer) read_array_C() (o []byte) {
 	n := int(b.ntou4())
+	if !b.check_len(n, 1) {
+		return nil
+	}
 	o = make([]byte, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntobyte()
@@ -206,6 +298,9 @@ func (b *Buffer) read_array_C() (o []byte) {
 
 func (b *Buffer) read_static_array() (o []uint32) {
 	n := int(b.ntou4())
+	if !b.check_len(n, 4) {
+		return nil
+	}
 	o = make([]uint32, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntou4()
@@ -214,6 +309,9 @@ func (b *Buffer) read_static_array() (o []uint32) {
 }
 
 func (b *Buffer) read_fast_array_F(n int) (o []float32) {
+	if !b.check_len(n, 4) {
+		return nil
+	}
 	o = make([]float32, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntof()
@@ -222,6 +320,9 @@ func (b *Buffer) read_fast_array_F(n int) (o []float32) {
 }
 
 func (b *Buffer) read_fast_array_D(n int) (o []float64) {
+	if !b.check_len(n, 8) {
+		return nil
+	}
 	o = make([]float64, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntod()
@@ -230,6 +331,9 @@ func (b *Buffer) read_fast_array_D(n int) (o []float64) {
 }
 
 func (b *Buffer) read_fast_array_S(n int) (o []int16) {
+	if !b.check_len(n, 2) {
+		return nil
+	}
 	o = make([]int16, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntoi2()
@@ -238,6 +342,9 @@ func (b *Buffer) read_fast_array_S(n int) (o []int16) {
 }
 
 func (b *Buffer) read_fast_array_I(n int) (o []int32) {
+	if !b.check_len(n, 4) {
+		return nil
+	}
 	o = make([]int32, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntoi4()
@@ -246,6 +353,9 @@ func (b *Buffer) read_fast_array_I(n int) (o []int32) {
 }
 
 func (b *Buffer) read_fast_array_L(n int) (o []int64) {
+	if !b.check_len(n, 8) {
+		return nil
+	}
 	o = make([]int64, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntoi8()
@@ -254,6 +364,9 @@ func (b *Buffer) read_fast_array_L(n int) (o []int64) {
 }
 
 func (b *Buffer) read_fast_array_UL(n int) (o []uint64) {
+	if !b.check_len(n, 8) {
+		return nil
+	}
 	o = make([]uint64, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntou8()
@@ -262,6 +375,9 @@ func (b *Buffer) read_fast_array_UL(n int) (o []uint64) {
 }
 
 func (b *Buffer) read_fast_array_C(n int) (o []byte) {
+	if !b.check_len(n, 1) {
+		return nil
+	}
 	o = make([]byte, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntobyte()
@@ -270,6 +386,9 @@ func (b *Buffer) read_fast_array_C(n int) (o []byte) {
 }
 
 func (b *Buffer) read_fast_array_tstring(n int) (o []string) {
+	if !b.check_len(n, 1) {
+		return nil
+	}
 	o = make([]string, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.read_tstring()
@@ -278,6 +397,9 @@ func (b *Buffer) read_fast_array_tstring(n int) (o []string) {
 }
 
 func (b *Buffer) read_fast_array(n int) (o []uint32) {
+	if !b.check_len(n, 4) {
+		return nil
+	}
 	o = make([]uint32, n)
 	for i := 0; i < n; i++ {
 		o[i] = b.ntou4()
@@ -294,16 +416,16 @@ func (b *Buffer) read_tstring() string {
 	if n == 0 {
 		return ""
 	}
+	if !b.check_len(n, 1) {
+		return ""
+	}
 	v := b.ntobyte()
 	if v == 0 {
 		return ""
 	}
 	o := make([]byte, n)
 	o[0] = v
-	err := binary.Read(b.buf, b.order, o[1:])
-	if err != nil {
-		panic(err)
-	}
+	b.read_value(o[1:])
 	return string(o)
 }
 
@@ -335,91 +457,120 @@ func (b *Buffer) read_std_string() string {
 	if nwh == 255 {
 		nchars = b.ntoi4()
 	}
-	if nchars < 0 {
-		panic("groot.readStdString: negative char number")
-	}
-	return b.read_string(int(nchars))
+	return string(b.read_nbytes(int(nchars)))
 }
 
 func (b *Buffer) read_version() (vers uint16, pos, bcnt uint32) {
+	if b.err != nil {
+		return
+	}
 
 	bcnt = b.ntou4()
-	if (int64(bcnt) & ^kByteCountMask) != 0 {
+	if (int64(bcnt) & kByteCountMask) != 0 {
 		bcnt = uint32(int64(bcnt) & ^kByteCountMask)
 	} else {
-		panic("groot.Buffer.read_version: too old file")
+		// no byte count. rewind and read the version.
+		b.rewind_nbytes(4)
+		bcnt = 0
 	}
 	vers = b.ntou2()
 	return
 }
 
+// read_tobject reads the TObject part of an object
+func (b *Buffer) read_tobject() (id, bits uint32) {
+	b.read_version()
+	id = b.ntou4()
+	bits = b.ntou4()
+	bits |= kIsOnHeap // by definition de-serialized object is on heap
+	if (bits & kIsReferenced) != 0 {
+		_ = b.read_nbytes(2) // pidf
+	}
+	return
+}
+
 func (b *Buffer) read_object() (o Object) {
+	if b.err != nil {
+		return nil
+	}
 	spos := b.Pos()
-	// before reading object, save start position
-	startbuf := b.clone()
 
 	clsname, bcnt, isref := b.read_class()
 	printf(">>[class=%s] [bcnt=%v] [isref=%v]\n", clsname, bcnt, isref)
 	if isref {
-		bb := b.clone()
-		printf("obj_offset: [%v] -> [%v] -> [%v]\n",
-			bcnt, bcnt-kMapOffset, bcnt-kMapOffset-bb.klen)
-		bb.rewind_nbytes(b.Pos() - int(bcnt-kMapOffset-bb.klen))
-		ii := bb.ntou4()
-		if (ii & kByteCountMask) != 0 {
-			scls := bb.read_class_tag()
-			if scls == "" {
-				panic("groot.Buffer.read_object: read_class_tag did not find a class name")
-			}
-		} else {
-			/* boo */
+		// back-reference to an object already read from this buffer
+		ref := bcnt
+		if o, ok := b.robjs[ref]; ok {
+			return o
 		}
-		/*
-		 // in principle at this point m_pos should be
-		 //   m_buffer+startpos+sizeof(unsigned int)
-		 // but enforce it anyway :
-		 m_pos = m_buffer+startpos+sizeof(unsigned int);
-		*/
-		b = startbuf //FIXME ??
-		b.read_nbytes(4)
-	} else {
-		if clsname == "" {
-			o = nil
-			// m_pos = m_buffer+startpos+bcnt+sizeof(unsigned int);
-			b = startbuf
-			b.read_nbytes(int(bcnt + 4))
-		} else {
-
-			factory := Factory.Get(clsname)
-			if factory == nil {
-				dprintf("**err** no factory for class [%s] (registering a dummy one!)\n", clsname)
-
-				f := func() reflect.Value {
-					o := &dummyObject{}
-					return reflect.ValueOf(o)
-				}
-				Factory.db[clsname] = f
-				factory = Factory.Get(clsname)
-			}
-
-			vv := factory()
-			o = vv.Interface().(Object)
-			if vv, ok := vv.Interface().(ROOTStreamer); ok {
-				err := vv.ROOTDecode(b)
-				if err != nil {
-					panic(err)
-				} else {
-					printf("--decoded[%s/%s]--\n", o.Name(), clsname)
-				}
-			} else {
-				dprintf("**err** class [%s] does not satisfy the ROOTStreamer interface\n", clsname)
-			}
-			b.check_byte_count(0, bcnt, spos, clsname)
+		// the object was skipped (e.g. by a dummy object): decode it now.
+		pos := b.ref_pos(ref)
+		printf("obj_offset: [%v] -> [%v]\n", ref, pos)
+		if pos < 0 || pos >= spos {
+			b.set_err(fmt.Errorf("groot: invalid object ref [%v] at offset %d", ref, spos))
+			return nil
+		}
+		bb := b.clone_at(pos)
+		o = bb.read_object()
+		if bb.err != nil {
+			b.set_err(bb.err)
+			return nil
 		}
+		return o
 	}
+
+	if clsname == "" || b.err != nil {
+		return nil
+	}
+
+	factory := Factory.Get(clsname)
+	if factory == nil && b.streamer_info(clsname, -1) != nil {
+		factory = generic_factory(clsname)
+	}
+	if factory == nil {
+		dprintf("**err** no factory for class [%s] (registering a dummy one!)\n", clsname)
+
+		f := func() reflect.Value {
+			o := &dummyObject{}
+			return reflect.ValueOf(o)
+		}
+		Factory.Add(clsname, f)
+		factory = f
+	}
+
+	vv := factory()
+	o = vv.Interface().(Object)
+
+	// register before decoding the object, to handle self-references
+	b.robjs[b.ref(spos)] = o
+
+	if vv, ok := vv.Interface().(ROOTStreamer); ok {
+		err := vv.ROOTDecode(b)
+		if err == nil {
+			err = b.err
+		}
+		if err != nil {
+			b.err = nil
+			b.set_err(fmt.Errorf("groot: could not decode object of class [%s] at offset %d: %w", clsname, spos, err))
+			return nil
+		}
+		printf("--decoded[%s/%s]--\n", o.Name(), clsname)
+	} else {
+		dprintf("**err** class [%s] does not satisfy the ROOTStreamer interface\n", clsname)
+	}
+	b.check_byte_count(0, bcnt, spos, clsname)
 	return o
 }
 
+// streamer_info returns the StreamerInfo of the given class and class version
+// from the file this buffer is reading from (or nil if there is none.)
+func (b *Buffer) streamer_info(name string, vers int) *StreamerInfo {
+	if b.file == nil {
+		return nil
+	}
+	return b.file.find_streamer_info(name, vers)
+}
+
 func (b *Buffer) read_class() (name string, bcnt uint32, isref bool) {
 
 	//var bufvers = 0
@@ -433,7 +584,7 @@ func (b *Buffer) read_class() (name string, bcnt uint32, isref bool) {
 		printf("read_class: first_int & kByteCountMask\n")
 		clstag := b.read_class_tag()
 		if clstag == "" {
-			panic("groot.Buffer.read_class: empty class tag")
+			b.set_err(fmt.Errorf("groot: empty class tag at offset %d", b.Pos()))
 		}
 		name = clstag
 		bcnt = uint32(int64(i) & ^kByteCountMask)
@@ -449,6 +600,7 @@ func (b *Buffer) read_class() (name string, bcnt uint32, isref bool) {
 }
 
 func (b *Buffer) read_class_tag() (clstag string) {
+	spos := b.Pos()
 	tag := b.ntou4()
 
 	tag_new_class := tag == kNewClassTag
@@ -461,19 +613,28 @@ func (b *Buffer) read_class_tag() (clstag string) {
 
 	if tag_new_class {
 		clstag = b.read_string(80)
+		b.rclasses[b.ref(spos)] = clstag
 		printf("--class+tag: [%v] - kNewClassTag\n", clstag)
 	} else if tag_class_mask {
 		ref := uint32(int64(tag) & (^int64(kClassMask)))
-		printf("--class-tag: [%v] & kClassMask -- ref=%d -- recurse\n",
-			clstag, ref)
-		bb := b.clone()
-		printf("cl_offset: [%v] -> [%v] -> [%v]\n",
-			ref, ref-kMapOffset, ref-kMapOffset-bb.klen)
-		bb.rewind_nbytes(b.Pos() - int(ref-kMapOffset-bb.klen))
+		if name, ok := b.rclasses[ref]; ok {
+			printf("--class-tag: [%v] & kClassMask -- ref=%d\n", name, ref)
+			return name
+		}
+		// the class tag was skipped: read it now.
+		pos := b.ref_pos(ref)
+		printf("--class-tag: ref=%d -> [%v] -- recurse\n", ref, pos)
+		if pos < 0 || pos >= spos {
+			b.set_err(fmt.Errorf("groot: invalid class ref [%v] at offset %d", ref, spos))
+			return ""
+		}
+		bb := b.clone_at(pos)
 		clstag = bb.read_class_tag()
-		//printf("--class-tag: [%v] & kClassMask\n", clstag)
-	} else {
-		panic(fmt.Errorf("groot.read_class_tag: unknown class-tag [%v]", tag))
+		if bb.err != nil {
+			b.set_err(bb.err)
+		}
+	} else if b.err == nil {
+		b.set_err(fmt.Errorf("groot: unknown class tag [%v] at offset %d", tag, spos))
 	}
 	return
 }
@@ -481,12 +642,7 @@ func (b *Buffer) read_class_tag() (clstag string) {
 func (b *Buffer) read_tnamed() (name, title string) {
 	spos := b.Pos()
 	vers, pos, bcnt := b.read_version()
-	id := b.ntou4()
-	bits := b.ntou4()
-	bits |= kIsOnHeap // by definition de-serialized object is on heap
-	if (bits & kIsReferenced) == 0 {
-		_ = b.read_nbytes(2)
-	}
+	id, bits := b.read_tobject()
 	name = b.read_tstring()
 	title = b.read_tstring()
 	printf("read_tnamed: vers=%v pos=%v bcnt=%v id=%v bits=%v name='%v' title='%v'\n",
@@ -506,32 +662,13 @@ func (b *Buffer) read_elements() (elmts []Object) {
 }
 
 func (b *Buffer) read_obj_array() (elmts []Object) {
-	spos := b.Pos()
-	vers, pos, bcnt := b.read_version()
-	if vers > 2 {
-		// skip version
-		b.read_nbytes(2)
-		// skip object bits and unique id
-		b.read_nbytes(8)
-	}
-	name := b.read_tstring()
-
-	nobjs := int(b.ntoi4())
-	lbound := b.ntoi4()
-
-	printf("read_obj_array: vers=%v pos=%v bcnt=%v name='%v' nobjs=%v lbound=%v\n",
-		vers, pos, bcnt, name, nobjs, lbound)
-
-	elmts = make([]Object, 0, nobjs)
-	for i := 0; i < nobjs; i++ {
-		printf("read_obj_array: %d/%d...\n", i, nobjs)
-		obj := b.read_object()
-		printf("read_obj_array: %d/%d...[done]\n", i, nobjs)
-		elmts = append(elmts, obj)
+	var arr ObjArray
+	err := arr.ROOTDecode(b)
+	if err != nil {
+		b.set_err(err)
+		return nil
 	}
-
-	b.check_byte_count(pos, bcnt, spos, "TObjArray")
-	return elmts
+	return arr.elmts
 }
 
 func (b *Buffer) read_attline() (color, style, width uint16) {
@@ -563,19 +700,35 @@ func (b *Buffer) read_attmarker() (color, style uint16, width float32) {
 	return
 }
 
+func (b *Buffer) read_iofeatures() {
+	spos := b.Pos()
+	/*vers*/ _, pos, bcnt := b.read_version()
+	// the content of TIOFeatures depends on its version: skip it.
+	b.skip_nbytes(spos + int(bcnt) + 4 - b.Pos())
+	b.check_byte_count(pos, bcnt, spos, "TIOFeatures")
+}
+
+// write_iofeatures writes a default (empty) TIOFeatures.
+// TIOFeatures is written as a foreign class: a null version, followed by
+// the checksum of the class.
+func (b *Buffer) write_iofeatures() {
+	pos := b.write_version(0)
+	b.u4ton(0x1aa12f10) // checksum
+	b.byteton(0)        // fIOBits
+	b.set_byte_count(pos)
+}
+
 //FIXME
 // readObjectAny
 // readTList
-// readTObjArray
-// readTClonesArray
 // readTCollection
 // readTHashList
 // readTNamed
 // readTCanvas
 
 func (b *Buffer) check_byte_count(start, count uint32, spos int, cls string) bool {
-	if count == 0 {
-		return true
+	if count == 0 || b.err != nil {
+		return b.err == nil
 	}
 
 	lenbuf := uint64(start) + uint64(count) + uint64(unsafe.Sizeof(uint32(0)))
@@ -586,27 +739,445 @@ func (b *Buffer) check_byte_count(start, count uint32, spos int, cls string) boo
 	}
 
 	if diff < lenbuf {
-		err := fmt.Errorf(
-			"buffer.check_count: object of class [%s] read too few bytes (%d missing. expected %d, got %d)",
-			cls, lenbuf-diff, lenbuf, diff)
+		b.set_err(fmt.Errorf(
+			"groot: object of class [%s] at offset %d read too few bytes (%d missing. expected %d, got %d)",
+			cls, spos, lenbuf-diff, lenbuf, diff))
+	} else {
+		b.set_err(fmt.Errorf(
+			"groot: object of class [%s] at offset %d read too many bytes (%d in excess. expected %d, got %d)",
+			cls, spos, diff-lenbuf, lenbuf, diff))
+	}
+	return false
+}
+
+// --- writing ---
+
+func (b *Buffer) write_nbytes(o []byte) {
+	_, err := b.buf.Write(o)
+	if err != nil {
 		panic(err)
+	}
+}
 
-		//printf("**error** %s\n", err.Error())
-		//b.read_nbytes(int(lenbuf-diff))
-		return true
+func (b *Buffer) i2ton(o int16) {
+	err := binary.Write(b.buf, b.order, o)
+	if err != nil {
+		panic(err)
 	}
-	if diff > lenbuf {
-		err := fmt.Errorf(
-			"buffer.check_count: object of class [%s] read too many bytes (%d in excess. expected %d, got %d)",
-			cls, diff-lenbuf, lenbuf, diff)
+}
 
+func (b *Buffer) i4ton(o int32) {
+	err := binary.Write(b.buf, b.order, o)
+	if err != nil {
 		panic(err)
+	}
+}
 
-		//printf("**error** %s\n", err.Error())
-		//b.rewind_nbytes(int(diff -lenbuf))
-		return true
+func (b *Buffer) i8ton(o int64) {
+	err := binary.Write(b.buf, b.order, o)
+	if err != nil {
+		panic(err)
 	}
-	return false
+}
+
+func (b *Buffer) byteton(o byte) {
+	err := b.buf.WriteByte(o)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (b *Buffer) u2ton(o uint16) {
+	err := binary.Write(b.buf, b.order, o)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (b *Buffer) u4ton(o uint32) {
+	err := binary.Write(b.buf, b.order, o)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (b *Buffer) u8ton(o uint64) {
+	err := binary.Write(b.buf, b.order, o)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (b *Buffer) fton(o float32) {
+	err := binary.Write(b.buf, b.order, o)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (b *Buffer) dton(o float64) {
+	err := binary.Write(b.buf, b.order, o)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (b *Buffer) write_bool(o bool) {
+	if o {
+		b.byteton(1)
+	} else {
+		b.byteton(0)
+	}
+}
+
+func (b *Buffer) write_array_I(o []int32) {
+	b.i4ton(int32(len(o)))
+	b.write_fast_array_I(o)
+}
+
+func (b *Buffer) write_array_D(o []float64) {
+	b.i4ton(int32(len(o)))
+	b.write_fast_array_D(o)
+}
+
+func (b *Buffer) write_array_F(o []float32) {
+	b.i4ton(int32(len(o)))
+	b.write_fast_array_F(o)
+}
+
+func (b *Buffer) write_fast_array_I(o []int32) {
+	for _, v := range o {
+		b.i4ton(v)
+	}
+}
+
+func (b *Buffer) write_fast_array_L(o []int64) {
+	for _, v := range o {
+		b.i8ton(v)
+	}
+}
+
+func (b *Buffer) write_fast_array_F(o []float32) {
+	for _, v := range o {
+		b.fton(v)
+	}
+}
+
+func (b *Buffer) write_fast_array_D(o []float64) {
+	for _, v := range o {
+		b.dton(v)
+	}
+}
+
+// tstring_size returns the number of bytes needed to write a TString
+func tstring_size(s string) int {
+	if len(s) < 255 {
+		return 1 + len(s)
+	}
+	return 1 + 4 + len(s)
+}
+
+func (b *Buffer) write_tstring(s string) {
+	n := len(s)
+	if n < 255 {
+		b.byteton(byte(n))
+	} else {
+		b.byteton(255)
+		b.i4ton(int32(n))
+	}
+	b.write_nbytes([]byte(s))
+}
+
+// write_string writes a null-terminated string
+func (b *Buffer) write_string(s string) {
+	b.write_nbytes([]byte(s))
+	b.byteton(0)
+}
+
+// write_version writes a byte count placeholder followed by the version of
+// a class. it returns the position of the byte count, to be later given to
+// set_byte_count.
+func (b *Buffer) write_version(vers uint16) (pos int) {
+	pos = b.Pos()
+	b.u4ton(0)
+	b.u2ton(vers)
+	return
+}
+
+// set_byte_count writes the byte count of the object which starts at pos
+func (b *Buffer) set_byte_count(pos int) {
+	cnt := uint32(b.Pos()-pos-4) | kByteCountMask
+	b.order.PutUint32(b.buf.Bytes()[pos:], cnt)
+}
+
+// write_tobject writes the TObject part of an object
+func (b *Buffer) write_tobject(id, bits uint32) {
+	b.u2ton(1) // TObject version (no byte count)
+	b.u4ton(id)
+	b.u4ton((bits | kIsOnHeap | kNotDeleted) &^ kIsReferenced)
+}
+
+func (b *Buffer) write_tnamed(name, title string) {
+	pos := b.write_version(1)
+	b.write_tobject(0, 0)
+	b.write_tstring(name)
+	b.write_tstring(title)
+	b.set_byte_count(pos)
+}
+
+func (b *Buffer) write_object(o Object) (err error) {
+	if o == nil || (reflect.ValueOf(o).Kind() == reflect.Ptr && reflect.ValueOf(o).IsNil()) {
+		b.u4ton(kNullTag)
+		return
+	}
+
+	ptr := reflect.ValueOf(o).Kind() == reflect.Ptr
+	if ptr {
+		if ref, ok := b.wobjs[o]; ok {
+			b.u4ton(ref)
+			return
+		}
+	}
+
+	vv, ok := o.(ROOTStreamer)
+	if !ok {
+		return fmt.Errorf("groot.Buffer.write_object: class [%s] does not satisfy the ROOTStreamer interface", o.Class())
+	}
+
+	pos := b.Pos()
+	b.u4ton(0) // byte count placeholder
+	b.write_class(o.Class())
+	if ptr {
+		// register before streaming the object, to handle self-references
+		b.wobjs[o] = uint32(pos) + b.klen + kMapOffset
+	}
+	err = vv.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
+	return
+}
+
+func (b *Buffer) write_class(name string) {
+	if ref, ok := b.wclasses[name]; ok {
+		b.u4ton(ref | kClassMask)
+		return
+	}
+	pos := b.Pos()
+	b.u4ton(kNewClassTag)
+	b.write_string(name)
+	b.wclasses[name] = uint32(pos) + b.klen + kMapOffset
+}
+
+// write_elements writes a TObjArray of objects, preceded by its class tag
+func (b *Buffer) write_elements(elmts []Object) (err error) {
+	pos := b.Pos()
+	b.u4ton(0) // byte count placeholder
+	b.write_class("TObjArray")
+	err = b.write_obj_array("", elmts)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
+	return
+}
+
+func (b *Buffer) write_obj_array(name string, elmts []Object) (err error) {
+	arr := ObjArray{name: name, elmts: elmts}
+	return arr.ROOTEncode(b)
+}
+
+func (b *Buffer) write_attline(color, style, width uint16) {
+	pos := b.write_version(2)
+	b.u2ton(color)
+	b.u2ton(style)
+	b.u2ton(width)
+	b.set_byte_count(pos)
+}
+
+func (b *Buffer) write_attfill(color, style uint16) {
+	pos := b.write_version(2)
+	b.u2ton(color)
+	b.u2ton(style)
+	b.set_byte_count(pos)
+}
+
+func (b *Buffer) write_attmarker(color, style uint16, width float32) {
+	pos := b.write_version(2)
+	b.u2ton(color)
+	b.u2ton(style)
+	b.fton(width)
+	b.set_byte_count(pos)
+}
+
+// --- exported API (used by the code generated by groot-gen) ---
+
+func (b *Buffer) ReadI8() int8          { return int8(b.ntobyte()) }
+func (b *Buffer) ReadU8() uint8         { return b.ntobyte() }
+func (b *Buffer) ReadI16() int16        { return b.ntoi2() }
+func (b *Buffer) ReadU16() uint16       { return b.ntou2() }
+func (b *Buffer) ReadI32() int32        { return b.ntoi4() }
+func (b *Buffer) ReadU32() uint32       { return b.ntou4() }
+func (b *Buffer) ReadI64() int64        { return b.ntoi8() }
+func (b *Buffer) ReadU64() uint64       { return b.ntou8() }
+func (b *Buffer) ReadF32() float32      { return b.ntof() }
+func (b *Buffer) ReadF64() float64      { return b.ntod() }
+func (b *Buffer) ReadBool() bool        { return b.read_bool() }
+func (b *Buffer) ReadTString() string   { return b.read_tstring() }
+func (b *Buffer) ReadStdString() string { return read_stl_string(b) }
+
+// ReadDouble32 reads a Double32_t, whose range is given by the title of its
+// data member (e.g. "[0,100,16]")
+func (b *Buffer) ReadDouble32(title string) float64 {
+	return read_double32(b, title, false)
+}
+
+// ReadFloat16 reads a Float16_t, whose range is given by the title of its
+// data member (e.g. "[0,100,16]")
+func (b *Buffer) ReadFloat16(title string) float32 {
+	return float32(read_double32(b, title, true))
+}
+
+// ReadVersion reads the version of a class and its (optional) byte count.
+// The returned values are to be given to CheckByteCount, together with the
+// position of the buffer before the call to ReadVersion.
+func (b *Buffer) ReadVersion() (vers uint16, pos, bcnt uint32) {
+	return b.read_version()
+}
+
+// CheckByteCount checks that the object of class cls, starting at spos,
+// has been fully read.
+func (b *Buffer) CheckByteCount(pos, bcnt uint32, spos int, cls string) {
+	b.check_byte_count(pos, bcnt, spos, cls)
+}
+
+// ReadTObject reads the TObject part of an object
+func (b *Buffer) ReadTObject() (id, bits uint32) {
+	return b.read_tobject()
+}
+
+// ReadTNamed reads the TNamed part of an object
+func (b *Buffer) ReadTNamed() (name, title string) {
+	return b.read_tnamed()
+}
+
+// ReadObject reads a pointer to an object (preceded by its class)
+func (b *Buffer) ReadObject() Object {
+	return b.read_object()
+}
+
+// ReadObjectAny reads an object of class cls, streamed by value
+func (b *Buffer) ReadObjectAny(cls string) (Object, error) {
+	v, err := read_object_value(b, KObject, cls)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := v.(Object)
+	if !ok {
+		return nil, fmt.Errorf("groot: class [%s] does not satisfy the Object interface", cls)
+	}
+	return o, err
+}
+
+// ReadSTL reads an STL container of C++ type typename (e.g. "vector<float>")
+// into the value pointed at by ptr, of the Go type given by STLType.
+func (b *Buffer) ReadSTL(typename string, ptr interface{}) error {
+	t, err := new_stl_container(typename)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Type() != t.rtype {
+		return fmt.Errorf("groot: [%s] can not be read into a %T (want a *%v)", typename, ptr, t.rtype)
+	}
+	v, err := t.read(b)
+	if err != nil {
+		return err
+	}
+	rv.Elem().Set(reflect.Zero(t.rtype))
+	set_value(rv.Elem(), v)
+	return b.err
+}
+
+func (b *Buffer) WriteI8(v int8)          { b.byteton(byte(v)) }
+func (b *Buffer) WriteU8(v uint8)         { b.byteton(v) }
+func (b *Buffer) WriteI16(v int16)        { b.i2ton(v) }
+func (b *Buffer) WriteU16(v uint16)       { b.u2ton(v) }
+func (b *Buffer) WriteI32(v int32)        { b.i4ton(v) }
+func (b *Buffer) WriteU32(v uint32)       { b.u4ton(v) }
+func (b *Buffer) WriteI64(v int64)        { b.i8ton(v) }
+func (b *Buffer) WriteU64(v uint64)       { b.u8ton(v) }
+func (b *Buffer) WriteF32(v float32)      { b.fton(v) }
+func (b *Buffer) WriteF64(v float64)      { b.dton(v) }
+func (b *Buffer) WriteBool(v bool)        { b.write_bool(v) }
+func (b *Buffer) WriteTString(v string)   { b.write_tstring(v) }
+func (b *Buffer) WriteStdString(v string) { write_stl_string(b, v) }
+
+// WriteDouble32 writes a Double32_t, whose range is given by the title of
+// its data member (e.g. "[0,100,16]")
+func (b *Buffer) WriteDouble32(v float64, title string) {
+	write_double32(b, v, title, false)
+}
+
+// WriteFloat16 writes a Float16_t, whose range is given by the title of its
+// data member (e.g. "[0,100,16]")
+func (b *Buffer) WriteFloat16(v float32, title string) {
+	write_double32(b, float64(v), title, true)
+}
+
+// WriteVersion writes a byte count placeholder followed by the version of
+// a class. It returns the position to be given to SetByteCount, once the
+// object has been written.
+func (b *Buffer) WriteVersion(vers uint16) int {
+	return b.write_version(vers)
+}
+
+// SetByteCount writes the byte count of the object which starts at pos
+func (b *Buffer) SetByteCount(pos int) {
+	b.set_byte_count(pos)
+}
+
+// WriteTObject writes the TObject part of an object
+func (b *Buffer) WriteTObject(id, bits uint32) {
+	b.write_tobject(id, bits)
+}
+
+// WriteTNamed writes the TNamed part of an object
+func (b *Buffer) WriteTNamed(name, title string) {
+	b.write_tnamed(name, title)
+}
+
+// WriteObject writes a pointer to an object (preceded by its class)
+func (b *Buffer) WriteObject(o Object) error {
+	return b.write_object(o)
+}
+
+// WriteObjectAny writes the object o by value
+func (b *Buffer) WriteObjectAny(o Object) error {
+	v, ok := o.(ROOTStreamer)
+	if !ok {
+		return fmt.Errorf("groot: class [%s] does not satisfy the ROOTStreamer interface", o.Class())
+	}
+	return v.ROOTEncode(b)
+}
+
+// WriteSTL writes the STL container v, of C++ type typename
+// (e.g. "vector<float>"), element by element.
+// v must be of the Go type given by STLType.
+func (b *Buffer) WriteSTL(typename string, v interface{}) error {
+	t, err := new_stl_container(typename)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		rv = reflect.Zero(t.rtype)
+	}
+	if rv.Type() != t.rtype {
+		return fmt.Errorf("groot: [%s] can not be written from a %T (want a %v)", typename, v, t.rtype)
+	}
+	return t.write_container(b, rv)
 }
 
 // EOF
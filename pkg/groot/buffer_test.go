@@ -0,0 +1,303 @@
+package groot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestReadObjectRefs(t *testing.T) {
+	const klen = 100 // refs account for the key header preceding the buffer
+
+	a := NewObjString("a")
+	arr := NewObjArray("arr", a)
+	arr.elmts = append(arr.elmts, arr) // self-reference
+
+	w, err := NewWBuffer(binary.BigEndian, klen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a, b (class tag reused), a (object ref), arr (a by ref, then itself)
+	for _, obj := range []Object{a, NewObjString("b"), a, arr} {
+		err = w.write_object(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := NewBuffer(w.Bytes(), binary.BigEndian, klen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs := make([]Object, 4)
+	for i := range objs {
+		objs[i] = b.read_object()
+	}
+	if b.err != nil {
+		t.Fatal(b.err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("%d bytes left", b.Len())
+	}
+
+	oa, ok := objs[0].(*ObjString)
+	if !ok || oa.String() != "a" {
+		t.Fatalf("obj #0: got %#v", objs[0])
+	}
+	if ob, ok := objs[1].(*ObjString); !ok || ob.String() != "b" {
+		t.Fatalf("obj #1: got %#v", objs[1])
+	}
+	if objs[2] != oa {
+		t.Errorf("obj #2: got %p, want %p", objs[2], oa)
+	}
+	rarr, ok := objs[3].(*ObjArray)
+	if !ok || rarr.Len() != 2 {
+		t.Fatalf("obj #3: got %#v", objs[3])
+	}
+	if rarr.At(0) != oa {
+		t.Errorf("obj #3: elmt #0: got %p, want %p", rarr.At(0), oa)
+	}
+	if rarr.At(1) != rarr {
+		t.Errorf("obj #3: elmt #1: got %p, want %p", rarr.At(1), rarr)
+	}
+	// one entry per class tag (TObjString, TObjArray), one per object
+	if len(b.rclasses) != 2 || len(b.robjs) != 3 {
+		t.Errorf("got %d classes and %d objects in the ref tables", len(b.rclasses), len(b.robjs))
+	}
+}
+
+func TestReadObjectSkippedRefs(t *testing.T) {
+	a := NewObjString("a")
+	w, err := NewWBuffer(binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range []Object{a, NewObjString("b"), a, a} {
+		err = w.write_object(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// skip a: the class tag of b and the refs to a point to data which was
+	// not read yet.
+	b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := b.read_object(); o == nil {
+		t.Fatal(b.err)
+	}
+	n := b.Pos()
+	b, err = NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.skip_nbytes(n)
+
+	var objs []Object
+	for i := 0; i < 3; i++ {
+		objs = append(objs, b.read_object())
+	}
+	if b.err != nil {
+		t.Fatal(b.err)
+	}
+	if o, ok := objs[0].(*ObjString); !ok || o.String() != "b" {
+		t.Fatalf("obj #0: got %#v", objs[0])
+	}
+	oa, ok := objs[1].(*ObjString)
+	if !ok || oa.String() != "a" {
+		t.Fatalf("obj #1: got %#v", objs[1])
+	}
+	if objs[2] != oa {
+		t.Errorf("obj #2: got %p, want %p", objs[2], oa)
+	}
+}
+
+func TestReadObjectInvalidRefs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		tag  uint32
+		err  string
+	}{
+		{"object ref", 100, "groot: invalid object ref [100] at offset 0"},
+		{"class ref", kByteCountMask | 4, "groot: invalid class ref [100] at offset 4"},
+	} {
+		w, err := NewWBuffer(binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.u4ton(tc.tag)
+		w.u4ton(kClassMask | 100)
+
+		b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o := b.read_object(); o != nil {
+			t.Errorf("%s: got %#v", tc.name, o)
+		}
+		if b.err == nil || !strings.Contains(b.err.Error(), tc.err) {
+			t.Errorf("%s: got err=%v, want %q", tc.name, b.err, tc.err)
+		}
+	}
+}
+
+func TestBufferErrors(t *testing.T) {
+	obj, err := NewWBuffer(binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = obj.write_object(NewObjString("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		write func(w *Buffer)
+		read  func(b *Buffer) interface{}
+		err   string
+	}{
+		{
+			name:  "truncated",
+			write: func(w *Buffer) { w.write_nbytes([]byte{1, 2, 3}) },
+			read: func(b *Buffer) interface{} {
+				i := b.ntoi4()
+				// errors are sticky
+				return []interface{}{i, b.ntobyte()}
+			},
+			err: "groot: could not read 4 bytes at offset 0",
+		},
+		{
+			name:  "invalid array length",
+			write: func(w *Buffer) { w.u4ton(1000) },
+			read:  func(b *Buffer) interface{} { return b.read_array_D() },
+			err:   "groot: invalid length 1000 at offset 4 (0 bytes left)",
+		},
+		{
+			name:  "truncated string",
+			write: func(w *Buffer) { w.byteton(10); w.write_nbytes([]byte("abc")) },
+			read:  func(b *Buffer) interface{} { return b.read_tstring() },
+			err:   "groot: invalid length 10 at offset 1 (3 bytes left)",
+		},
+		{
+			name: "too few bytes",
+			write: func(w *Buffer) {
+				pos := w.write_version(1)
+				w.i4ton(0)
+				w.set_byte_count(pos)
+			},
+			read: func(b *Buffer) interface{} {
+				_, pos, bcnt := b.read_version()
+				return b.check_byte_count(pos, bcnt, 0, "Foo")
+			},
+			err: "groot: object of class [Foo] at offset 0 read too few bytes (4 missing. expected 10, got 6)",
+		},
+		{
+			name: "too many bytes",
+			write: func(w *Buffer) {
+				pos := w.write_version(1)
+				w.set_byte_count(pos)
+				w.i4ton(0)
+			},
+			read: func(b *Buffer) interface{} {
+				_, pos, bcnt := b.read_version()
+				b.ntoi4()
+				return b.check_byte_count(pos, bcnt, 0, "Foo")
+			},
+			err: "groot: object of class [Foo] at offset 0 read too many bytes (4 in excess. expected 6, got 10)",
+		},
+		{
+			name:  "empty class name",
+			write: func(w *Buffer) { w.u4ton(kByteCountMask | 10); w.u4ton(kNewClassTag); w.byteton(0) },
+			read:  func(b *Buffer) interface{} { return b.read_object() },
+			err:   "groot: empty class tag at offset 9",
+		},
+		{
+			name: "invalid object",
+			write: func(w *Buffer) {
+				data := append([]byte(nil), obj.Bytes()...)
+				data[bytes.Index(data, []byte("\x05hello"))] = 200
+				w.write_nbytes(data)
+			},
+			read: func(b *Buffer) interface{} { return b.read_object() },
+			err:  "groot: could not decode object of class [TObjString] at offset 0: groot: invalid length 200",
+		},
+	} {
+		w, err := NewWBuffer(binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc.write(w)
+		b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := tc.read(b)
+		if err := b.Err(); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got err=%v, want %q", tc.name, err, tc.err)
+		}
+		if ints, ok := v.([]interface{}); ok && (ints[0] != int32(0) || ints[1] != byte(0)) {
+			t.Errorf("%s: got %v, want zero values", tc.name, ints)
+		}
+	}
+}
+
+func TestDouble32(t *testing.T) {
+	for _, tc := range []struct {
+		title   string
+		float16 bool
+		v       float64
+		want    float64
+		tol     float64
+		nbytes  int
+	}{
+		{"", false, 1.1, float64(float32(1.1)), 0, 4},
+		{"[0,100,16]", false, 50, 50, 0, 4},
+		{"[0,100,16] above xmax", false, 150, 100, 0, 4},
+		{"[0,100,16] below xmin", false, -5, 0, 0, 4},
+		{"[-pi,pi]", false, 1, 1, 2 * math.Pi / (1 << 32), 4},
+		{"[0,0,8]", false, 3, 3, 0, 3},
+		{"[0,0]", false, 1.1, float64(float32(1.1)), 0, 4},
+		{"", true, 0.25, 0.25, 0, 3},
+		{"", true, 1.1, 1.1, 1.1 / (1 << 12), 3},
+		{"[0,0,10]", true, 1.5, 1.5, 0, 3},
+		{"[0,0,10]", true, -2, -2, 0, 3},
+		{"[-1,1,8]", true, 0.5, 0.5, 2.0 / (1 << 8), 4},
+	} {
+		w, err := NewWBuffer(binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.float16 {
+			w.WriteFloat16(float32(tc.v), tc.title)
+		} else {
+			w.WriteDouble32(tc.v, tc.title)
+		}
+		if n := len(w.Bytes()); n != tc.nbytes {
+			t.Errorf("%q (float16=%v): wrote %d bytes, want %d", tc.title, tc.float16, n, tc.nbytes)
+		}
+
+		b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got float64
+		if tc.float16 {
+			got = float64(b.ReadFloat16(tc.title))
+		} else {
+			got = b.ReadDouble32(tc.title)
+		}
+		if b.Err() != nil || b.Len() != 0 {
+			t.Fatalf("%q (float16=%v): err=%v, %d bytes left", tc.title, tc.float16, b.Err(), b.Len())
+		}
+		if math.Abs(got-tc.want) > tc.tol {
+			t.Errorf("%q (float16=%v): got %v, want %v", tc.title, tc.float16, got, tc.want)
+		}
+	}
+}
+
+// EOF
@@ -0,0 +1,145 @@
+package groot
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ClonesArray is an array of objects of the same class (TClonesArray).
+// The objects are decoded as GenericObjects, from the StreamerInfo of their
+// class.
+type ClonesArray struct {
+	name   string
+	class  string // class of the objects
+	vers   int    // version of the class of the objects
+	lbound int32  // lower bound of the array
+	elmts  []Object
+}
+
+func (arr *ClonesArray) Class() string {
+	return "TClonesArray"
+}
+
+func (arr *ClonesArray) Name() string {
+	return arr.name
+}
+
+func (arr *ClonesArray) Title() string {
+	return "An array of clone objects"
+}
+
+// ElementClass returns the class of the objects of the array
+func (arr *ClonesArray) ElementClass() string {
+	return arr.class
+}
+
+// Len returns the number of objects in the array
+func (arr *ClonesArray) Len() int {
+	return len(arr.elmts)
+}
+
+// At returns the i-th object of the array
+func (arr *ClonesArray) At(i int) Object {
+	return arr.elmts[i]
+}
+
+// Elements returns the objects of the array
+func (arr *ClonesArray) Elements() []Object {
+	return arr.elmts
+}
+
+// LowerBound returns the lower bound of the array
+func (arr *ClonesArray) LowerBound() int {
+	return int(arr.lbound)
+}
+
+// see TClonesArray::Streamer
+func (arr *ClonesArray) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[clones-array] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	if vers <= 2 {
+		return fmt.Errorf("groot: too old version of TClonesArray (%d)", vers)
+	}
+
+	var bits uint32 = kBypassStreamer
+	if vers > 3 {
+		_, bits = b.read_tobject()
+	}
+	arr.name = b.read_tstring()
+
+	// the class of the objects, as "name;version"
+	clsv := b.read_tstring()
+	arr.class = clsv
+	arr.vers = -1
+	if i := strings.Index(clsv, ";"); i >= 0 {
+		arr.class = clsv[:i]
+		arr.vers, err = strconv.Atoi(clsv[i+1:])
+		if err != nil {
+			return fmt.Errorf("groot: invalid class version in TClonesArray [%s]", clsv)
+		}
+	}
+
+	nobjs := int(b.ntoi4())
+	if nobjs < 0 {
+		nobjs = -nobjs
+	}
+	arr.lbound = b.ntoi4()
+	if !b.check_len(nobjs, 1) {
+		return b.err
+	}
+	printf("[clones-array] name='%v' class='%v' vers=%v nobjs=%v\n",
+		arr.name, arr.class, arr.vers, nobjs)
+
+	arr.elmts = make([]Object, 0, nobjs)
+	if bits&kBypassStreamer != 0 {
+		si := b.streamer_info(arr.class, arr.vers)
+		if si == nil {
+			return fmt.Errorf("groot: no StreamerInfo for class [%s] (version=%d)", arr.class, arr.vers)
+		}
+		objs, err := read_memberwise_objects(b, si, nobjs)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objs {
+			arr.elmts = append(arr.elmts, obj)
+		}
+	} else {
+		for i := 0; i < nobjs; i++ {
+			if b.ntobyte() == 0 {
+				arr.elmts = append(arr.elmts, nil)
+				continue
+			}
+			obj, err := b.ReadObjectAny(arr.class)
+			if err != nil {
+				return err
+			}
+			arr.elmts = append(arr.elmts, obj)
+		}
+	}
+
+	b.check_byte_count(pos, bcnt, spos, "TClonesArray")
+	return
+}
+
+func (arr *ClonesArray) ROOTEncode(b *Buffer) (err error) {
+	return fmt.Errorf("groot: writing TClonesArray is not supported")
+}
+
+func init() {
+	f := func() reflect.Value {
+		o := &ClonesArray{}
+		return reflect.ValueOf(o)
+	}
+	Factory.db["TClonesArray"] = f
+	Factory.db["*groot.ClonesArray"] = f
+}
+
+// check interfaces
+var _ Object = (*ClonesArray)(nil)
+var _ Collection = (*ClonesArray)(nil)
+var _ ROOTStreamer = (*ClonesArray)(nil)
+
+// EOF
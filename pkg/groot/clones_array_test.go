@@ -0,0 +1,181 @@
+package groot
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// write_clones_header writes the header of a TClonesArray of n objects of
+// class ("name;version")
+func write_clones_header(w *Buffer, bits uint32, class string, n int) int {
+	pos := w.write_version(4)
+	w.write_tobject(0, bits)
+	w.write_tstring("tracks")
+	w.write_tstring(class)
+	w.i4ton(int32(n))
+	w.i4ton(1)
+	return pos
+}
+
+func TestClonesArray(t *testing.T) {
+	type track struct {
+		px, py float64
+		q, m   float32
+	}
+	trks := []track{{50, 1.5, 1, 0.5}, {25, -1, -1, 0.25}}
+
+	for _, tc := range []struct {
+		name  string
+		write func(w *Buffer)
+		nils  int // index of the empty slot, if any
+	}{
+		{
+			name: "member-wise",
+			write: func(w *Buffer) {
+				pos := write_clones_header(w, kBypassStreamer, "Track;2", len(trks))
+				for range trks {
+					w.write_tobject(0, 0)
+				}
+				for _, trk := range trks {
+					write_double32(w, trk.px, "[0,100,16] px", false)
+				}
+				for _, trk := range trks {
+					write_double32(w, trk.py, "py", false)
+				}
+				for _, trk := range trks {
+					write_double32(w, float64(trk.q), "[0,0,10] charge", true)
+				}
+				for _, trk := range trks {
+					write_double32(w, float64(trk.m), "mass", true)
+				}
+				w.set_byte_count(pos)
+			},
+			nils: -1,
+		},
+		{
+			name: "object-wise",
+			write: func(w *Buffer) {
+				pos := write_clones_header(w, 0, "Track;2", len(trks)+1)
+				w.byteton(1)
+				write_generic_track(w, trks[0].px, trks[0].py, trks[0].q, trks[0].m)
+				w.byteton(0)
+				w.byteton(1)
+				write_generic_track(w, trks[1].px, trks[1].py, trks[1].q, trks[1].m)
+				w.set_byte_count(pos)
+			},
+			nils: 1,
+		},
+	} {
+		w, err := NewWBuffer(binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc.write(w)
+		b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.file = &File{sinfos: generic_sinfos}
+
+		var arr ClonesArray
+		err = arr.ROOTDecode(b)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if b.Len() != 0 {
+			t.Errorf("%s: %d bytes left", tc.name, b.Len())
+		}
+		if arr.Name() != "tracks" || arr.ElementClass() != "Track" || arr.LowerBound() != 1 {
+			t.Errorf("%s: got name=%q class=%q lbound=%d",
+				tc.name, arr.Name(), arr.ElementClass(), arr.LowerBound())
+		}
+
+		var elmts []Object
+		for i, elmt := range arr.Elements() {
+			if i == tc.nils {
+				if elmt != nil {
+					t.Errorf("%s: elmt #%d: got %v, want nil", tc.name, i, elmt)
+				}
+				continue
+			}
+			elmts = append(elmts, elmt)
+		}
+		if len(elmts) != len(trks) {
+			t.Errorf("%s: got %d tracks, want %d", tc.name, len(elmts), len(trks))
+			continue
+		}
+		for i, trk := range trks {
+			obj := elmts[i].(*GenericObject)
+			got := []interface{}{obj.Value("fPx"), obj.Value("fPy"), obj.Value("fQ"), obj.Value("fM")}
+			want := []interface{}{trk.px, trk.py, trk.q, trk.m}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: track #%d: got %v, want %v", tc.name, i, got, want)
+			}
+		}
+	}
+}
+
+func TestClonesArrayErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		write func(w *Buffer)
+		err   string
+	}{
+		{
+			name: "old version",
+			write: func(w *Buffer) {
+				pos := w.write_version(2)
+				w.set_byte_count(pos)
+			},
+			err: "groot: too old version of TClonesArray (2)",
+		},
+		{
+			name: "invalid class version",
+			write: func(w *Buffer) {
+				pos := write_clones_header(w, 0, "Track;x", 0)
+				w.set_byte_count(pos)
+			},
+			err: "groot: invalid class version in TClonesArray [Track;x]",
+		},
+		{
+			name: "unknown class version",
+			write: func(w *Buffer) {
+				pos := write_clones_header(w, kBypassStreamer, "Track;5", 1)
+				w.write_tobject(0, 0)
+				w.set_byte_count(pos)
+			},
+			err: "groot: no StreamerInfo for class [Track] (version=5)",
+		},
+		{
+			name: "unknown class",
+			write: func(w *Buffer) {
+				pos := write_clones_header(w, 0, "Hit;1", 1)
+				w.byteton(1)
+				w.set_byte_count(pos)
+			},
+			err: "no factory nor StreamerInfo for class [Hit]",
+		},
+	} {
+		w, err := NewWBuffer(binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc.write(w)
+		b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.file = &File{sinfos: generic_sinfos}
+
+		var arr ClonesArray
+		err = arr.ROOTDecode(b)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got err=%v, want %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+// EOF
@@ -1,55 +1,58 @@
 package groot
 
-// constants for the streamers
+// types of the streamer elements (see StreamerElement.Type)
+// see TVirtualStreamerInfo::EReadWrite
 const (
-	kBase       = 0
-	kChar       = 1
-	kShort      = 2
-	kInt        = 3
-	kLong       = 4
-	kFloat      = 5
-	kCounter    = 6
-	kCharStar   = 7
-	kDouble     = 8
-	kDouble32   = 9
-	kLegacyChar = 10
-	kUChar      = 11
-	kUShort     = 12
-	kUInt       = 13
-	kULong      = 14
-	kBits       = 15
-	kLong64     = 16
-	kULong64    = 17
-	kBool       = 18
-	kFloat16    = 19
-	kOffsetL    = 20
-	kOffsetP    = 40
-	kObject     = 61
-	kAny        = 62
-	kObjectp    = 63
-	kObjectP    = 64
-	kTString    = 65
-	kTObject    = 66
-	kTNamed     = 67
-	kAnyp       = 68
-	kAnyP       = 69
-	kAnyPnoVT   = 70
-	kSTLp       = 71
+	KBase       = 0
+	KChar       = 1
+	KShort      = 2
+	KInt        = 3
+	KLong       = 4
+	KFloat      = 5
+	KCounter    = 6
+	KCharStar   = 7
+	KDouble     = 8
+	KDouble32   = 9
+	KLegacyChar = 10
+	KUChar      = 11
+	KUShort     = 12
+	KUInt       = 13
+	KULong      = 14
+	KBits       = 15
+	KLong64     = 16
+	KULong64    = 17
+	KBool       = 18
+	KFloat16    = 19
+	KOffsetL    = 20
+	KOffsetP    = 40
+	KObject     = 61
+	KAny        = 62
+	KObjectp    = 63
+	KObjectP    = 64
+	KTString    = 65
+	KTObject    = 66
+	KTNamed     = 67
+	KAnyp       = 68
+	KAnyP       = 69
+	KAnyPnoVT   = 70
+	KSTLp       = 71
 
-	kSkip  = 100
-	kSkipL = 120
-	kSkipP = 140
+	KSkip  = 100
+	KSkipL = 120
+	KSkipP = 140
 
-	kConv  = 200
-	kConvL = 220
-	kConvP = 240
+	KConv  = 200
+	KConvL = 220
+	KConvP = 240
 
-	kSTL       = 300
-	kSTLstring = 365
+	KSTL       = 300
+	KSTLstring = 365
 
-	kStreamer   = 500
-	kStreamLoop = 501
+	KStreamer   = 500
+	KStreamLoop = 501
+)
 
+const (
 	kByteCountMask = 0x40000000
 )
 
@@ -61,11 +64,21 @@ const (
 	kMapOffset      = 2
 	kByteCountVMask = 0x4000
 
+	// version bit of STL containers streamed member-wise
+	kStreamedMemberWise = 1 << 14
+
 	kIsReferenced = 1 << 4
 	kIsOnHeap     = 0x01000000
+	kNotDeleted   = 0x02000000
+
+	// TClonesArray bit: objects are streamed member-wise
+	kBypassStreamer = 1 << 12
 
 	//baskets
 	kDisplacementMask = 0xFF000000
+
+	// number of baskets of a branch fetched at once from remote files
+	kPrefetchBaskets = 16
 )
 
 // EOF
@@ -27,6 +27,10 @@ const (
 	sz_uint64 = 8
 
 	g_START_BIG_FILE = 2000000000
+
+	kBEGIN = 100 // first used byte in file, following the file header
+
+	g_ROOT_VERSION = 62206 // ROOT version used when writing files
 )
 
 // EOF
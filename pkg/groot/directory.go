@@ -2,8 +2,9 @@ package groot
 
 import (
 	"fmt"
-	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,6 +12,11 @@ import (
 type Directory struct {
 	file        *File
 	keys        []Key
+	dirs        []*Directory  // sub-directories created for writing
+	trees       []*TreeWriter // trees being written in this directory
+	name        string
+	title       string
+	uuid        [16]byte
 	ctime       time.Time // time of directory's creation
 	mtime       time.Time // time of directory's last modification
 	nbytes_keys uint32    // number of bytes for the keys
@@ -76,6 +82,9 @@ func (d *Directory) from_buffer(b *Buffer) (err error) {
 	printf("dir-seek-dir: %v\n", d.seek_dir)
 	printf("dir-seek-parent: %v\n", d.seek_parent)
 	printf("dir-seek-keys: %v\n", d.seek_keys)
+	if b.err != nil {
+		return fmt.Errorf("groot: could not read directory header: %w", b.err)
+	}
 	return err
 }
 
@@ -103,15 +112,264 @@ func (d *Directory) ROOTDecode(b *Buffer) (err error) {
 	printf("dir-seek-parent: %v\n", d.seek_parent)
 	printf("dir-seek-keys: %v\n", d.seek_keys)
 
+	if b.err != nil {
+		return b.err
+	}
+	_, err = d.read_keys()
+	return err
+}
+
+func (d *Directory) ROOTEncode(b *Buffer) (err error) {
+	version := uint16(5)
+	big := d.seek_dir > g_START_BIG_FILE ||
+		d.seek_parent > g_START_BIG_FILE ||
+		d.seek_keys > g_START_BIG_FILE
+	if big {
+		version += 1000
+	}
+	b.u2ton(version)
+	b.u4ton(time2datime(d.ctime))
+	b.u4ton(time2datime(d.mtime))
+	b.u4ton(d.nbytes_keys)
+	b.u4ton(d.nbytes_name)
+	if big {
+		b.i8ton(d.seek_dir)
+		b.i8ton(d.seek_parent)
+		b.i8ton(d.seek_keys)
+	} else {
+		b.i4ton(int32(d.seek_dir))
+		b.i4ton(int32(d.seek_parent))
+		b.i4ton(int32(d.seek_keys))
+	}
+	b.u2ton(1) // TUUID version
+	b.write_nbytes(d.uuid[:])
+	if !big {
+		// reserve space for 64b seeks
+		for i := 0; i < 3; i++ {
+			b.i4ton(0)
+		}
+	}
+	return
+}
+
+// Class returns the ROOT class name of this directory
+func (d *Directory) Class() string {
+	return "TDirectory"
+}
+
+// Name returns the name of this directory
+func (d *Directory) Name() string {
+	return d.name
+}
+
+// Title returns the title of this directory
+func (d *Directory) Title() string {
+	return d.title
+}
+
+// Get returns the object registered under the given name in this directory.
+// The name may hold the path to a sub-directory ("dir/sub/name") and a cycle
+// number ("name;2").
+// The object with the highest cycle is returned when no cycle is given.
+func (d *Directory) Get(name string) (obj Object, err error) {
+	name = strings.TrimLeft(name, "/")
+	if i := strings.Index(name, "/"); i >= 0 {
+		obj, err = d.Get(name[:i])
+		if err != nil {
+			return nil, err
+		}
+		sub, ok := obj.(*Directory)
+		if !ok {
+			return nil, fmt.Errorf("groot: [%s] is not a directory (class=%s)", name[:i], obj.Class())
+		}
+		return sub.Get(name[i+1:])
+	}
+
+	cycle := -1
+	if i := strings.LastIndex(name, ";"); i >= 0 {
+		cycle, err = strconv.Atoi(name[i+1:])
+		if err != nil || cycle < 0 {
+			return nil, fmt.Errorf("groot: invalid cycle number in [%s]", name)
+		}
+		name = name[:i]
+	}
+
+	var key *Key
+	for i := range d.keys {
+		k := &d.keys[i]
+		if k.name != name {
+			continue
+		}
+		if cycle >= 0 && int(k.cycle) == cycle {
+			key = k
+			break
+		}
+		if cycle < 0 && (key == nil || k.cycle > key.cycle) {
+			key = k
+		}
+	}
+	if key == nil {
+		if cycle >= 0 {
+			return nil, fmt.Errorf("groot: no key [%s;%d] in directory [%s]", name, cycle, d.name)
+		}
+		return nil, fmt.Errorf("groot: no key [%s] in directory [%s]", name, d.name)
+	}
+
+	return key.Object()
+}
+
+// Mkdir creates a new sub-directory in this directory.
+func (d *Directory) Mkdir(name string) (sub *Directory, err error) {
+	if !d.file.writable {
+		return nil, fmt.Errorf("groot: file [%s] is not writable", d.file.name)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("groot: invalid directory name")
+	}
+	for i := range d.keys {
+		if d.keys[i].name == name {
+			return nil, fmt.Errorf("groot: key [%s] already exists", name)
+		}
+	}
+
+	now := time.Now()
+	sub = &Directory{
+		file:        d.file,
+		keys:        make([]Key, 0),
+		name:        name,
+		title:       name,
+		uuid:        new_uuid(),
+		ctime:       now,
+		mtime:       now,
+		seek_parent: d.seek_dir,
+	}
+
+	key, err := new_key_for(d, sub.name, sub.title, "TDirectory", sub.ROOTEncode)
+	if err != nil {
+		return nil, err
+	}
+	sub.seek_dir = key.seek_key
+	sub.nbytes_name = uint32(key.keysz)
+
+	// update the directory record, now that its location is known
+	b, err := NewWBuffer(d.file.order, 0)
+	if err != nil {
+		return nil, err
+	}
+	err = sub.ROOTEncode(b)
+	if err != nil {
+		return nil, err
+	}
+	copy(key.buffer[key.keysz:], b.Bytes())
+
+	err = key.write_file()
+	if err != nil {
+		return nil, err
+	}
+	d.keys = append(d.keys, *key)
+	d.dirs = append(d.dirs, sub)
+	d.mtime = now
+	return sub, err
+}
+
+// Put writes the object obj under the given name in this directory.
+// If an object with the same name already exists, a new cycle is created.
+func (d *Directory) Put(name string, obj Object) (err error) {
+	if !d.file.writable {
+		return fmt.Errorf("groot: file [%s] is not writable", d.file.name)
+	}
+	vv, ok := obj.(ROOTStreamer)
+	if !ok {
+		return fmt.Errorf("groot: class [%s] does not satisfy the ROOTStreamer interface", obj.Class())
+	}
+
+	key, err := new_key_for(d, name, obj.Title(), obj.Class(), vv.ROOTEncode)
 	if err != nil {
 		return err
 	}
-	_, err = d.read_keys()
+	err = key.write_file()
+	if err != nil {
+		return err
+	}
+	d.keys = append(d.keys, *key)
+	d.mtime = time.Now()
+	d.file.add_streamer_info(obj.Class())
 	return err
 }
 
-func (d *Directory) ROOTEncode(buffer *Buffer) error {
-	panic("groot.Directory.ROOTEncode: sorry, not implemented")
+// next_cycle returns the cycle number for a new key with the given name
+func (d *Directory) next_cycle(name string) uint16 {
+	cycle := uint16(0)
+	for i := range d.keys {
+		if d.keys[i].name == name && d.keys[i].cycle > cycle {
+			cycle = d.keys[i].cycle
+		}
+	}
+	return cycle + 1
+}
+
+// close writes the trees which are still being filled and the keys of this
+// directory (and of its sub-directories) and updates the directory record on
+// file.
+func (d *Directory) close() (err error) {
+	for _, sub := range d.dirs {
+		err = sub.close()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, tree := range d.trees {
+		err = tree.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = d.write_keys()
+	if err != nil {
+		return err
+	}
+	return d.write_dir_header()
+}
+
+// write_keys writes the list of keys of this directory as a single record.
+// see ROOT::TDirectoryFile::WriteKeys
+func (d *Directory) write_keys() (err error) {
+	class, name, title := "TDirectory", d.name, d.title
+	if d == &d.file.root_dir {
+		class, name, title = "TFile", d.file.name, d.file.title
+	}
+
+	key, err := new_key_for(d, name, title, class, func(b *Buffer) error {
+		b.i4ton(int32(len(d.keys)))
+		for i := range d.keys {
+			d.keys[i].fill_buffer(b)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	d.seek_keys = key.seek_key
+	d.nbytes_keys = key.nbytes
+	return key.write_file()
+}
+
+// write_dir_header (re)writes the directory record on file.
+// see ROOT::TDirectoryFile::WriteDirHeader
+func (d *Directory) write_dir_header() (err error) {
+	b, err := NewWBuffer(d.file.order, 0)
+	if err != nil {
+		return err
+	}
+	d.mtime = time.Now()
+	err = d.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	_, err = d.file.w.WriteAt(b.Bytes(), d.seek_dir+int64(d.nbytes_name))
+	return err
 }
 
 func (d *Directory) SetFile(f *File) error {
@@ -127,26 +385,24 @@ func (d *Directory) SetFile(f *File) error {
 func (d *Directory) read_keys() (nkeys int, err error) {
 
 	printf("--read_keys-- %v %v\n", d.seek_keys, d.nbytes_keys)
-	hdr, err := NewKey(d.file, d.seek_keys, d.nbytes_keys)
+	err = d.file.check_range(d.seek_keys, int64(d.nbytes_keys))
 	if err != nil {
-		printf("groot.Directory.read_keys: %v\n", err.Error())
 		return -1, err
 	}
-	if hdr == nil {
-		return -1, fmt.Errorf("groot: invalid header key")
-	}
 
-	cur, err := d.file.f.Seek(0, os.SEEK_CUR)
+	hdr, err := NewKey(d.file, d.seek_keys, d.nbytes_keys)
 	if err != nil {
 		printf("groot.Directory.read_keys: %v\n", err.Error())
 		return -1, err
 	}
-	defer d.file.f.Seek(cur, os.SEEK_SET)
+	if hdr == nil {
+		return -1, fmt.Errorf("groot: invalid header key")
+	}
 
 	buf := make([]byte, int(d.nbytes_keys))
 
 	printf("--- %v %v\n", len(buf), d.seek_keys)
-	_, err = d.file.f.ReadAt(buf, d.seek_keys)
+	err = d.file.read_at(buf, d.seek_keys)
 	if err != nil {
 		printf("seek_keys: %v\n", d.seek_keys)
 		printf("len(buf): %v\n", len(buf))
@@ -166,6 +422,9 @@ func (d *Directory) read_keys() (nkeys int, err error) {
 
 	nkeys = int(b.ntoi4())
 	printf("dir-nkeys: %v\n", nkeys)
+	if !b.check_len(nkeys, 1) {
+		return -1, fmt.Errorf("groot: could not read the keys of directory [%s]: %w", d.Name(), b.err)
+	}
 
 	d.keys = make([]Key, nkeys)
 	for i := 0; i < nkeys; i++ {
@@ -191,7 +450,12 @@ func init() {
 	}
 
 	Factory.db["TDirectory"] = new_dir
+	Factory.db["TDirectoryFile"] = new_dir
 	Factory.db["*groot.Directory"] = new_dir
 }
 
+// check interfaces
+var _ Object = (*Directory)(nil)
+var _ ROOTStreamer = (*Directory)(nil)
+
 // EOF
@@ -0,0 +1,59 @@
+package groot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDirectoryGet(t *testing.T) {
+	f := create_dir_file(t)
+
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{"h", "v2"},
+		{"h;1", "v1"},
+		{"h;2", "v2"},
+		{"/h", "v2"},
+		{"dir/h", "dir"},
+		{"dir/h;1", "dir"},
+		{"dir/sub/h", "sub"},
+	} {
+		obj, err := f.Get(tc.name)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got := obj.(*ObjString).String(); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	obj, err := f.Get("dir/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir, ok := obj.(*Directory); !ok || dir.Name() != "sub" || len(dir.Keys()) != 1 {
+		t.Errorf("dir/sub: got %#v", obj)
+	}
+
+	for _, tc := range []struct {
+		name string
+		err  string
+	}{
+		{"x", "groot: no key [x] in directory"},
+		{"h;3", "groot: no key [h;3] in directory"},
+		{"h;x", "groot: invalid cycle number in [h;x]"},
+		{"h;-1", "groot: invalid cycle number in [h;-1]"},
+		{"h/x", "groot: [h] is not a directory (class=TObjString)"},
+		{"dir/x", "groot: no key [x] in directory [dir]"},
+	} {
+		_, err := f.Get(tc.name)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("%s: got err=%v, want %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+// EOF
@@ -2,19 +2,25 @@ package groot
 
 import (
 	"reflect"
+	"sync"
 )
 
 type FactoryFct func() reflect.Value
 
 type factory struct {
+	mu sync.RWMutex
 	db map[string]FactoryFct // a registry of all factory functions by class name
 }
 
 func (f *factory) NumKey() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	return len(f.db)
 }
 
 func (f *factory) Keys() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	keys := make([]string, 0, len(f.db))
 	for k := range f.db {
 		keys = append(keys, k)
@@ -23,11 +29,15 @@ func (f *factory) Keys() []string {
 }
 
 func (f *factory) HasKey(n string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	_, ok := f.db[n]
 	return ok
 }
 
 func (f *factory) Get(n string) FactoryFct {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	fct, ok := f.db[n]
 	if ok {
 		return fct
@@ -35,6 +45,14 @@ func (f *factory) Get(n string) FactoryFct {
 	return nil
 }
 
+// Add registers the factory function fct for the class n.
+// An already registered factory is replaced.
+func (f *factory) Add(n string, fct FactoryFct) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.db[n] = fct
+}
+
 // the registry of all factory functions, by class name
 var Factory factory = factory{
 	db: make(map[string]FactoryFct),
@@ -0,0 +1,279 @@
+package groot
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreate(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "create.root")
+
+	type event struct {
+		I32 int32      `groot:"i32"`
+		F64 float64    `groot:"f64"`
+		Arr [3]float32 `groot:"arr"`
+		Str string     `groot:"str"`
+	}
+	newEvent := func(i int) event {
+		return event{
+			I32: int32(i),
+			F64: float64(i) * 1.5,
+			Arr: [3]float32{float32(i), float32(-i), 2},
+			Str: fmt.Sprintf("evt-%d", i),
+		}
+	}
+	const nevts = 1000
+
+	h1 := NewH1F("h1", "my h1", 10, 0, 10)
+	h2 := NewH2D("h2", "my h2", 5, 0, 5, 4, -2, 2)
+	for i := 0; i < 100; i++ {
+		h1.Fill(float64(i%12), 1)
+		h2.Fill(float64(i%5), float64(i%4)-2, 0.5)
+	}
+	gr, err := NewGraphAsymmErrors("gr", "my graph",
+		[]float64{1, 2, 3}, []float64{2, 4, 6},
+		[]float64{0.1, 0.2, 0.3}, []float64{0.2, 0.3, 0.4},
+		[]float64{1, 1, 1}, []float64{2, 2, 2},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		f, err := Create(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, obj := range []struct {
+			name string
+			obj  Object
+		}{
+			{"h1", h1},
+			{"h2", h2},
+			{"gr", gr},
+			{"str", NewObjString("hello")},
+		} {
+			err = f.Dir().Put(obj.name, obj.obj)
+			if err != nil {
+				t.Fatalf("could not put %s: %v", obj.name, err)
+			}
+		}
+
+		w, err := NewTreeWriter(f.Dir(), "tree", "my tree")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var evt event
+		for _, br := range []struct {
+			name string
+			ptr  interface{}
+		}{
+			{"i32", &evt.I32},
+			{"f64", &evt.F64},
+			{"arr", &evt.Arr},
+			{"str", &evt.Str},
+		} {
+			err = w.Branch(br.name, br.ptr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := 0; i < nevts; i++ {
+			evt = newEvent(i)
+			err = w.Fill()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// checksums of the StreamerInfos written by ROOT 6
+	for _, tc := range []struct {
+		class  string
+		vers   int
+		chksum uint32
+	}{
+		{"TH1F", 3, 0xe2939644},
+		{"TH1", 8, 0x1c3740c4},
+		{"TAxis", 10, 0x5a496e70},
+		{"TAttLine", 2, 0x94074549},
+		{"TAttFill", 2, 0xffd92a92},
+		{"TAttMarker", 2, 0x291d8bec},
+		{"TH2D", 4, 0x7fba82f0},
+		{"TH2", 5, 0x0182347f},
+		{"TGraphAsymmErrors", 3, 0xcc46af3b},
+		{"TGraph", 4, 0x05f7f465},
+		{"TObject", 1, 0x901bc02d},
+		{"TNamed", 1, 0xdfb74a3c},
+		{"TObjString", 1, 0x9c8e4800},
+		{"TList", 5, 0x69c5c3bb},
+		{"TObjArray", 3, 0xa99e6552},
+		{"TTree", 20, 0x7264e07f},
+		{"ROOT::TIOFeatures", 1, 0x1aa12f10},
+		{"TBranch", 13, 0x10978aac},
+		{"TLeafI", 1, 0x7e6aae19},
+		{"TLeafF", 1, 0x3add9d72},
+		{"TLeafD", 1, 0x118e8776},
+		{"TLeafC", 1, 0xfbe3b2f3},
+	} {
+		si, err := f.StreamerInfo(tc.class, tc.vers)
+		if err != nil {
+			t.Errorf("missing streamer info: %v", err)
+			continue
+		}
+		if si.CheckSum() != tc.chksum {
+			t.Errorf("%s: got checksum 0x%08x, want 0x%08x", tc.class, si.CheckSum(), tc.chksum)
+		}
+	}
+
+	obj, err := f.Get("h1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := obj.(*H1F); !reflect.DeepEqual(got.Bins(), h1.Bins()) || got.Entries() != h1.Entries() {
+		t.Errorf("h1: got bins=%v entries=%v, want bins=%v entries=%v",
+			got.Bins(), got.Entries(), h1.Bins(), h1.Entries())
+	}
+
+	obj, err = f.Get("gr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := obj.(*GraphAsymmErrors); !reflect.DeepEqual(got.Y(), gr.Y()) {
+		t.Errorf("gr: got y=%v, want y=%v", got.Y(), gr.Y())
+	}
+
+	obj, err = f.Get("str")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := obj.(*ObjString).String(); got != "hello" {
+		t.Errorf("str: got %q, want %q", got, "hello")
+	}
+
+	// the streamer infos written to file must describe the objects as they
+	// were streamed: decode them without their factories.
+	for _, name := range []string{"h1", "h2", "gr", "str", "tree"} {
+		var key *Key
+		for i := range f.Dir().Keys() {
+			if k := &f.Dir().Keys()[i]; k.Name() == name {
+				key = k
+			}
+		}
+		if key == nil {
+			t.Errorf("no key [%s]", name)
+			continue
+		}
+		b, err := NewBufferFromKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj := new_generic_object(key.Class())
+		err = obj.ROOTDecode(b)
+		if err != nil {
+			t.Errorf("could not decode %s with its streamer info: %v", name, err)
+			continue
+		}
+		if name != "str" && obj.Name() != name {
+			t.Errorf("%s: got name %q", name, obj.Name())
+		}
+	}
+
+	obj, err = f.Get("tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := obj.(*Tree)
+	if tree.Entries() != nevts {
+		t.Fatalf("got %d entries, want %d", tree.Entries(), nevts)
+	}
+	var evt event
+	r, err := tree.NewStructReader(&evt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for r.Next() {
+		if want := newEvent(int(r.Cur())); evt != want {
+			t.Fatalf("entry %d: got %+v, want %+v", r.Cur(), evt, want)
+		}
+		n++
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != nevts {
+		t.Fatalf("read %d entries, want %d", n, nevts)
+	}
+}
+
+func TestCorruptedFile(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "corrupted.root")
+	{
+		f, err := Create(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = f.Dir().Put("str", NewObjString("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a truncated file
+	err = ioutil.WriteFile(fname, data[:50], 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Open(fname)
+	if err == nil || !strings.Contains(err.Error(), "could not read header of file") {
+		t.Errorf("truncated file: got err=%v", err)
+	}
+
+	// a string longer than its object
+	i := bytes.Index(data, []byte("\x05hello"))
+	if i < 0 {
+		t.Fatalf("no string in file")
+	}
+	data[i] = 200
+	err = ioutil.WriteFile(fname, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, err = f.Get("str")
+	want := "groot: could not decode key [str;1] of class [TObjString] (offset="
+	if err == nil || !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), "invalid length 200") {
+		t.Errorf("corrupted object: got err=%v, want %q", err, want)
+	}
+}
+
+// EOF
@@ -0,0 +1,760 @@
+package groot
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// GenericObject is an object of a class without a registered factory.
+// It is decoded using the StreamerInfo of its class, as stored in the file.
+//
+// The members of the base classes are flattened into the object.
+// Members groot can not decode (e.g. of classes with a custom streamer) are
+// kept as RawMember values.
+type GenericObject struct {
+	class   string
+	version int
+	names   []string               // names of the members, in streaming order
+	values  map[string]interface{} // values of the members, by name
+}
+
+// RawMember holds the undecoded bytes of a member of a GenericObject
+type RawMember struct {
+	typename string
+	data     []byte
+}
+
+// TypeName returns the name of the type of the member
+func (m *RawMember) TypeName() string {
+	return m.typename
+}
+
+// Bytes returns the bytes of the member, as streamed (with its byte count
+// and version.)
+func (m *RawMember) Bytes() []byte {
+	return m.data
+}
+
+func new_generic_object(class string) *GenericObject {
+	return &GenericObject{
+		class:   class,
+		version: -1,
+		names:   make([]string, 0),
+		values:  make(map[string]interface{}),
+	}
+}
+
+// generic_factory returns a factory creating generic objects of the given class
+func generic_factory(class string) FactoryFct {
+	return func() reflect.Value {
+		return reflect.ValueOf(new_generic_object(class))
+	}
+}
+
+func (obj *GenericObject) Class() string {
+	return obj.class
+}
+
+// Name returns the value of the fName member (if any)
+func (obj *GenericObject) Name() string {
+	name, _ := obj.values["fName"].(string)
+	return name
+}
+
+// Title returns the value of the fTitle member (if any)
+func (obj *GenericObject) Title() string {
+	title, _ := obj.values["fTitle"].(string)
+	return title
+}
+
+// Version returns the version of the class of this object
+func (obj *GenericObject) Version() int {
+	return obj.version
+}
+
+// Members returns the names of the members of this object, in streaming order
+func (obj *GenericObject) Members() []string {
+	return obj.names
+}
+
+// Value returns the value of the named member, or nil if there is no such
+// member.
+func (obj *GenericObject) Value(name string) interface{} {
+	return obj.values[name]
+}
+
+func (obj *GenericObject) set(name string, v interface{}) {
+	if _, dup := obj.values[name]; !dup {
+		obj.names = append(obj.names, name)
+	}
+	obj.values[name] = v
+}
+
+func (obj *GenericObject) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[generic] class=%s vers=%v pos=%v bcnt=%v\n", obj.class, vers, pos, bcnt)
+
+	var si *StreamerInfo
+	if vers == 0 && b.file != nil {
+		// foreign class: the version is followed by the checksum of the class
+		si = b.file.find_streamer_info_by_checksum(obj.class, b.ntou4())
+	} else {
+		si = b.streamer_info(obj.class, int(vers))
+	}
+	if si == nil {
+		return fmt.Errorf("groot: no StreamerInfo for class [%s] (version=%d)", obj.class, vers)
+	}
+	obj.version = int(si.classvers)
+
+	for _, elmt := range si.elmts {
+		if elmt == nil {
+			return fmt.Errorf("groot: class [%s] has an unknown streamer element", obj.class)
+		}
+		err = obj.read_element(b, elmt)
+		if err == nil {
+			err = b.err
+		}
+		if err != nil {
+			return fmt.Errorf("groot: class [%s], member [%s]: %w", obj.class, elmt.Name(), err)
+		}
+	}
+
+	b.check_byte_count(pos, bcnt, spos, obj.class)
+	return b.err
+}
+
+func (obj *GenericObject) ROOTEncode(b *Buffer) (err error) {
+	return fmt.Errorf("groot: encoding generic objects (class [%s]) is not supported", obj.class)
+}
+
+// read_memberwise_objects decodes n objects described by si, streamed
+// member by member: all the values of the first member, then all the values
+// of the second member, etc...
+// see TStreamerInfo::ReadBufferClones
+func read_memberwise_objects(b *Buffer, si *StreamerInfo, n int) (objs []*GenericObject, err error) {
+	objs = make([]*GenericObject, n)
+	for i := range objs {
+		objs[i] = new_generic_object(si.Name())
+		objs[i].version = int(si.classvers)
+	}
+	for _, elmt := range si.elmts {
+		if elmt == nil {
+			return nil, fmt.Errorf("groot: class [%s] has an unknown streamer element", si.Name())
+		}
+		for _, obj := range objs {
+			err = obj.read_element(b, elmt)
+			if err == nil {
+				err = b.err
+			}
+			if err != nil {
+				return nil, fmt.Errorf("groot: class [%s], member [%s]: %w", si.Name(), elmt.Name(), err)
+			}
+		}
+	}
+	return objs, err
+}
+
+// read_element reads the value of the member described by elmt
+// see TStreamerInfo::ReadBuffer
+func (obj *GenericObject) read_element(b *Buffer, elmt StreamerElement) (err error) {
+	name := elmt.Name()
+	etype := elmt.Type()
+
+	switch se := elmt.(type) {
+	case *StreamerBase:
+		return obj.read_base(b, se)
+
+	case *StreamerBasicPointer:
+		// [count] pointer to an array of builtin types
+		n, err := obj.count(se.countname)
+		if err != nil {
+			return err
+		}
+		isarray := b.ntobyte()
+		if isarray == 0 {
+			n = 0
+		}
+		v, err := read_basic_array(b, elmt.Title(), etype-KOffsetP, n)
+		if err != nil {
+			return err
+		}
+		obj.set(name, v)
+		return err
+
+	case *StreamerLoop:
+		return obj.read_loop(b, se)
+	}
+
+	switch {
+	case etype == KCharStar:
+		n := int(b.ntoi4())
+		if n > 0 {
+			obj.set(name, string(b.read_nbytes(n)))
+		} else {
+			obj.set(name, "")
+		}
+
+	case etype > KBase && etype < KOffsetL:
+		var v interface{}
+		if elmt.ArrLen() > 0 {
+			v, err = read_basic_array(b, elmt.Title(), etype, elmt.ArrLen())
+		} else {
+			v, err = read_basic(b, elmt.Title(), etype)
+		}
+		if err != nil {
+			return err
+		}
+		obj.set(name, v)
+
+	case etype > KOffsetL && etype < KOffsetP:
+		v, err := read_basic_array(b, elmt.Title(), etype-KOffsetL, elmt.ArrLen())
+		if err != nil {
+			return err
+		}
+		obj.set(name, v)
+
+	case etype == KTString, etype == KTObject, etype == KTNamed,
+		etype == KObject, etype == KAny, etype == KObjectp, etype == KAnyp:
+		// object(s) streamed by value
+		if elmt.ArrLen() <= 0 {
+			v, err := read_object_value(b, etype, elmt.TypeName())
+			if err != nil {
+				return err
+			}
+			obj.set(name, v)
+			return err
+		}
+		if !b.check_len(elmt.ArrLen(), 1) {
+			return b.err
+		}
+		v := make([]interface{}, elmt.ArrLen())
+		for i := range v {
+			v[i], err = read_object_value(b, etype, elmt.TypeName())
+			if err != nil {
+				return err
+			}
+		}
+		obj.set(name, v)
+
+	case etype == KObjectP, etype == KAnyP:
+		// pointer(s) to object(s)
+		if elmt.ArrLen() <= 0 {
+			obj.set(name, b.read_object())
+			return err
+		}
+		if !b.check_len(elmt.ArrLen(), 1) {
+			return b.err
+		}
+		v := make([]Object, elmt.ArrLen())
+		for i := range v {
+			v[i] = b.read_object()
+		}
+		obj.set(name, v)
+
+	case etype == KSTLstring, is_stl_string(elmt):
+		obj.set(name, read_stl_string(b))
+
+	case etype == KSTL, etype == KStreamer:
+		if _, ok := elmt.(*StreamerSTL); ok {
+			t, err := new_stl_type(elmt.TypeName())
+			if err != nil {
+				return err
+			}
+			v, err := t.read(b)
+			if err != nil {
+				return err
+			}
+			obj.set(name, v)
+			return err
+		}
+		// classes with custom streamers: keep the raw bytes.
+		spos := b.Pos()
+		vers, pos, bcnt := b.read_version()
+		if bcnt == 0 {
+			return fmt.Errorf("can not skip member of type %d (no byte count)", etype)
+		}
+		printf("[generic] skipping [%s] (type=%d vers=%d bcnt=%d)\n", name, etype, vers, bcnt)
+		b.skip_nbytes(spos + int(bcnt) + 4 - b.Pos())
+		if !b.check_byte_count(pos, bcnt, spos, elmt.TypeName()) {
+			return b.err
+		}
+		data := make([]byte, b.Pos()-spos)
+		copy(data, b.data[spos:b.Pos()])
+		obj.set(name, &RawMember{typename: elmt.TypeName(), data: data})
+
+	default:
+		return fmt.Errorf("member type %d (%s) is not supported", etype, elmt.TypeName())
+	}
+	return err
+}
+
+// read_loop reads the [count] objects (or pointers to objects) described by
+// se, into a []interface{} (or a []Object.)
+// see TStreamerInfo::ReadBuffer (kStreamLoop)
+func (obj *GenericObject) read_loop(b *Buffer, se *StreamerLoop) (err error) {
+	if se.ArrLen() > 0 {
+		return fmt.Errorf("arrays of [count] arrays of objects are not supported")
+	}
+	n, err := obj.count(se.countname)
+	if err != nil {
+		return err
+	}
+
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[generic] loop [%s] (n=%d vers=%d bcnt=%d)\n", se.Name(), n, vers, bcnt)
+	if !b.check_len(n, 1) {
+		return b.err
+	}
+
+	var v interface{}
+	if strings.Contains(se.TypeName(), "**") {
+		objs := make([]Object, n)
+		for i := range objs {
+			objs[i] = b.read_object()
+			if b.err != nil {
+				return b.err
+			}
+		}
+		v = objs
+	} else {
+		objs := make([]interface{}, n)
+		for i := range objs {
+			objs[i], err = read_object_value(b, KObject, se.TypeName())
+			if err != nil {
+				return err
+			}
+		}
+		v = objs
+	}
+	if !b.check_byte_count(pos, bcnt, spos, se.TypeName()) {
+		return b.err
+	}
+	obj.set(se.Name(), v)
+	return err
+}
+
+// read_base reads the members of the base class described by se
+func (obj *GenericObject) read_base(b *Buffer, se *StreamerBase) (err error) {
+	switch se.name {
+	case "TObject":
+		id, bits := b.read_tobject()
+		obj.set("fUniqueID", id)
+		obj.set("fBits", bits)
+		return
+
+	case "TNamed":
+		name, title := b.read_tnamed()
+		obj.set("fName", name)
+		obj.set("fTitle", title)
+		return
+
+	case "TArrayC", "TArrayS", "TArrayI", "TArrayL", "TArrayL64", "TArrayF", "TArrayD":
+		// custom streamers, without a version: their StreamerInfo (if any)
+		// does not describe how they are streamed.
+		v, err := read_object_value(b, KObject, se.name)
+		if err != nil {
+			return err
+		}
+		obj.set(se.name, v)
+		return nil
+	}
+
+	if b.streamer_info(se.name, -1) != nil {
+		base := new_generic_object(se.name)
+		err = base.ROOTDecode(b)
+		if err != nil {
+			return err
+		}
+		for _, name := range base.names {
+			obj.set(name, base.values[name])
+		}
+		return
+	}
+
+	v, err := read_object_value(b, KObject, se.name)
+	if err != nil {
+		return err
+	}
+	obj.set(se.name, v)
+	return
+}
+
+// count returns the (already read) value of the named member, to be used
+// as the size of an array
+func (obj *GenericObject) count(name string) (int, error) {
+	switch v := obj.values[name].(type) {
+	case int8:
+		return int(v), nil
+	case uint8:
+		return int(v), nil
+	case int16:
+		return int(v), nil
+	case uint16:
+		return int(v), nil
+	case int32:
+		return int(v), nil
+	case uint32:
+		return int(v), nil
+	case int64:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	}
+	return 0, fmt.Errorf("no counter [%s]", name)
+}
+
+// read_object_value reads an object of class typename, streamed by value
+func read_object_value(b *Buffer, etype int, typename string) (v interface{}, err error) {
+	switch etype {
+	case KTString:
+		return b.read_tstring(), err
+	case KTObject:
+		o := new_generic_object("TObject")
+		id, bits := b.read_tobject()
+		o.set("fUniqueID", id)
+		o.set("fBits", bits)
+		return o, err
+	case KTNamed:
+		o := new_generic_object("TNamed")
+		name, title := b.read_tnamed()
+		o.set("fName", name)
+		o.set("fTitle", title)
+		return o, err
+	}
+
+	typename = strings.TrimSpace(strings.TrimRight(typename, "*"))
+	if is_stl_container(typename) {
+		t, err := new_stl_type(typename)
+		if err != nil {
+			return nil, err
+		}
+		return t.read(b)
+	}
+	factory := Factory.Get(typename)
+	if factory == nil && b.streamer_info(typename, -1) != nil {
+		factory = generic_factory(typename)
+	}
+	if factory == nil {
+		return nil, fmt.Errorf("no factory nor StreamerInfo for class [%s]", typename)
+	}
+	vv, ok := factory().Interface().(ROOTStreamer)
+	if !ok {
+		return nil, fmt.Errorf("class [%s] does not satisfy the ROOTStreamer interface", typename)
+	}
+	err = vv.ROOTDecode(b)
+	if err != nil {
+		return nil, err
+	}
+	return vv, err
+}
+
+// basic_types maps the type of a streamer element to its Go type
+var basic_types = map[int]reflect.Type{
+	KChar:     reflect.TypeOf(int8(0)),
+	KShort:    reflect.TypeOf(int16(0)),
+	KInt:      reflect.TypeOf(int32(0)),
+	KLong:     reflect.TypeOf(int64(0)),
+	KFloat:    reflect.TypeOf(float32(0)),
+	KCounter:  reflect.TypeOf(int32(0)),
+	KDouble:   reflect.TypeOf(float64(0)),
+	KDouble32: reflect.TypeOf(float64(0)),
+	KUChar:    reflect.TypeOf(uint8(0)),
+	KUShort:   reflect.TypeOf(uint16(0)),
+	KUInt:     reflect.TypeOf(uint32(0)),
+	KULong:    reflect.TypeOf(uint64(0)),
+	KBits:     reflect.TypeOf(uint32(0)),
+	KLong64:   reflect.TypeOf(int64(0)),
+	KULong64:  reflect.TypeOf(uint64(0)),
+	KBool:     reflect.TypeOf(false),
+	KFloat16:  reflect.TypeOf(float32(0)),
+}
+
+// read_basic reads a value of a builtin type.
+// title is the title of the data member (which holds the range of Double32_t
+// and Float16_t members.)
+func read_basic(b *Buffer, title string, etype int) (v interface{}, err error) {
+	switch etype {
+	case KChar:
+		return int8(b.ntobyte()), err
+	case KShort:
+		return b.ntoi2(), err
+	case KInt, KCounter:
+		return b.ntoi4(), err
+	case KLong, KLong64:
+		return b.ntoi8(), err
+	case KFloat:
+		return b.ntof(), err
+	case KDouble:
+		return b.ntod(), err
+	case KUChar:
+		return b.ntobyte(), err
+	case KUShort:
+		return b.ntou2(), err
+	case KUInt, KBits:
+		return b.ntou4(), err
+	case KULong, KULong64:
+		return b.ntou8(), err
+	case KBool:
+		return b.read_bool(), err
+	case KDouble32:
+		return read_double32(b, title, false), err
+	case KFloat16:
+		return float32(read_double32(b, title, true)), err
+	}
+	return nil, fmt.Errorf("builtin type %d is not supported", etype)
+}
+
+// write_basic writes the value v of a builtin type.
+// v must be of the Go type given by basic_types.
+func write_basic(b *Buffer, title string, etype int, v interface{}) (err error) {
+	rt, ok := basic_types[etype]
+	if !ok {
+		return fmt.Errorf("builtin type %d is not supported", etype)
+	}
+	if reflect.TypeOf(v) != rt {
+		return fmt.Errorf("builtin type %d can not be written from a %T", etype, v)
+	}
+	switch etype {
+	case KChar:
+		b.byteton(byte(v.(int8)))
+	case KShort:
+		b.i2ton(v.(int16))
+	case KInt, KCounter:
+		b.i4ton(v.(int32))
+	case KLong, KLong64:
+		b.i8ton(v.(int64))
+	case KFloat:
+		b.fton(v.(float32))
+	case KDouble:
+		b.dton(v.(float64))
+	case KUChar:
+		b.byteton(v.(uint8))
+	case KUShort:
+		b.u2ton(v.(uint16))
+	case KUInt, KBits:
+		b.u4ton(v.(uint32))
+	case KULong, KULong64:
+		b.u8ton(v.(uint64))
+	case KBool:
+		b.write_bool(v.(bool))
+	case KDouble32:
+		write_double32(b, v.(float64), title, false)
+	case KFloat16:
+		write_double32(b, float64(v.(float32)), title, true)
+	}
+	return err
+}
+
+// read_basic_array reads n values of a builtin type, into a slice
+func read_basic_array(b *Buffer, title string, etype, n int) (v interface{}, err error) {
+	rt, ok := basic_types[etype]
+	if !ok {
+		return nil, fmt.Errorf("builtin type %d is not supported", etype)
+	}
+	if !b.check_len(n, 1) {
+		return nil, b.err
+	}
+	slice := reflect.MakeSlice(reflect.SliceOf(rt), n, n)
+	for i := 0; i < n; i++ {
+		v, err := read_basic(b, title, etype)
+		if err != nil {
+			return nil, err
+		}
+		if b.err != nil {
+			return nil, b.err
+		}
+		slice.Index(i).Set(reflect.ValueOf(v))
+	}
+	return slice.Interface(), err
+}
+
+// read_double32 reads a Double32_t (or a Float16_t if float16 is true),
+// whose on-file representation depends on the range given in the title of
+// the member (e.g. "[0,100,16]")
+// see TBufferFile::ReadDouble32 and TBufferFile::ReadWithFactor
+func read_double32(b *Buffer, title string, float16 bool) float64 {
+	xmin, xmax, nbits, ok := parse_range(title)
+	switch {
+	case ok && xmax > xmin:
+		bigint := uint64(0xffffffff)
+		if nbits < 32 {
+			bigint = 1 << uint(nbits)
+		}
+		factor := float64(bigint) / (xmax - xmin)
+		return float64(b.ntou4())/factor + xmin
+	case ok && nbits <= 14:
+		// no range, but an explicit number of bits: truncated mantissa
+		return read_with_nbits(b, nbits)
+	case float16:
+		return read_with_nbits(b, 12)
+	}
+	return float64(b.ntof())
+}
+
+// read_with_nbits reads a float stored with a truncated mantissa
+// see TBufferFile::ReadWithNbits
+func read_with_nbits(b *Buffer, nbits int) float64 {
+	exp := uint32(b.ntobyte())
+	man := uint32(b.ntou2())
+	v := exp << 23
+	v |= (man & ((1 << uint(nbits+1)) - 1)) << uint(23-nbits)
+	f := math.Float32frombits(v)
+	if (1<<uint(nbits+1))&man != 0 {
+		f = -f
+	}
+	return float64(f)
+}
+
+// write_double32 writes a Double32_t (or a Float16_t if float16 is true)
+// see TBufferFile::WriteDouble32 and TBufferFile::WriteWithFactor
+func write_double32(b *Buffer, v float64, title string, float16 bool) {
+	xmin, xmax, nbits, ok := parse_range(title)
+	switch {
+	case ok && xmax > xmin:
+		bigint := uint64(0xffffffff)
+		if nbits < 32 {
+			bigint = 1 << uint(nbits)
+		}
+		factor := float64(bigint) / (xmax - xmin)
+		if v < xmin {
+			v = xmin
+		}
+		if v > xmax {
+			v = xmax
+		}
+		b.u4ton(uint32(0.5 + factor*(v-xmin)))
+	case ok && nbits <= 14:
+		// no range, but an explicit number of bits: truncated mantissa
+		write_with_nbits(b, v, nbits)
+	case float16:
+		write_with_nbits(b, v, 12)
+	default:
+		b.fton(float32(v))
+	}
+}
+
+// write_with_nbits writes a float with a mantissa truncated to nbits
+// see TBufferFile::WriteWithNbits
+func write_with_nbits(b *Buffer, v float64, nbits int) {
+	f := float32(v)
+	i := math.Float32bits(f)
+	exp := byte(0xff & ((i << 1) >> 24))
+	man := uint16(((1 << uint(nbits+1)) - 1) & (i >> uint(23-nbits-1)))
+	man++
+	man >>= 1
+	if man&(1<<uint(nbits)) != 0 {
+		man = (1 << uint(nbits)) - 1
+	}
+	if f < 0 {
+		man |= 1 << uint(nbits+1)
+	}
+	b.byteton(exp)
+	b.u2ton(man)
+}
+
+// parse_range parses the range specification "[xmin,xmax(,nbits)]" which
+// may start the title of a Double32_t or Float16_t member.
+// nbits is 32 when it is not given (or invalid.)
+// see TStreamerElement::GetRange
+func parse_range(title string) (xmin, xmax float64, nbits int, ok bool) {
+	title = strings.TrimSpace(title)
+	if !strings.HasPrefix(title, "[") {
+		return
+	}
+	end := strings.Index(title, "]")
+	if end < 0 {
+		return
+	}
+	toks := strings.Split(title[1:end], ",")
+	if len(toks) < 2 {
+		return
+	}
+	nbits = 32
+	parse := func(s string) (float64, bool) {
+		s = strings.Replace(strings.TrimSpace(s), " ", "", -1)
+		sign := 1.0
+		if strings.HasPrefix(s, "-") {
+			sign = -1
+			s = s[1:]
+		}
+		switch s {
+		case "pi", "Pi", "PI":
+			return sign * math.Pi, true
+		case "2pi", "2*pi", "twopi":
+			return sign * 2 * math.Pi, true
+		case "pi/2":
+			return sign * math.Pi / 2, true
+		case "pi/4":
+			return sign * math.Pi / 4, true
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		return sign * v, err == nil
+	}
+	var okmin, okmax bool
+	xmin, okmin = parse(toks[0])
+	xmax, okmax = parse(toks[1])
+	if !okmin || !okmax {
+		return
+	}
+	if len(toks) > 2 {
+		n, err := strconv.Atoi(strings.TrimSpace(toks[2]))
+		if err != nil {
+			return
+		}
+		nbits = n
+	}
+	if nbits < 2 || nbits > 32 {
+		nbits = 32
+	}
+	return xmin, xmax, nbits, true
+}
+
+// is_stl_string returns whether elmt describes a std::string member
+func is_stl_string(elmt StreamerElement) bool {
+	switch se := elmt.(type) {
+	case *StreamerSTLstring:
+		return true
+	case *StreamerSTL:
+		return se.stltype == KSTLstring || se.TypeName() == "string"
+	}
+	return false
+}
+
+// read_stl_string reads a std::string, which may be preceded by a byte
+// count and version header.
+func read_stl_string(b *Buffer) string {
+	bb := b.clone()
+	if cnt := bb.ntou4(); cnt&kByteCountMask != 0 {
+		bcnt := int(cnt &^ kByteCountMask)
+		bb.ntou2() // version
+		n := int(bb.ntobyte())
+		hdr := 1
+		if n == 255 {
+			n = int(bb.ntoi4())
+			hdr += 4
+		}
+		if bcnt == 2+hdr+n {
+			b.read_version()
+		}
+	}
+	return b.read_std_string()
+}
+
+// write_stl_string writes a std::string, preceded by a byte count and
+// version header.
+func write_stl_string(b *Buffer, s string) {
+	pos := b.write_version(1)
+	b.write_tstring(s)
+	b.set_byte_count(pos)
+}
+
+// check interfaces
+var _ Object = (*GenericObject)(nil)
+var _ ROOTStreamer = (*GenericObject)(nil)
+
+// EOF
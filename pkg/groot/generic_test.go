@@ -0,0 +1,247 @@
+package groot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// generic_sinfos describes the classes "Track" and "Event", which have no
+// factory and are decoded as GenericObjects.
+var generic_sinfos = []*StreamerInfo{
+	{
+		name:      "Track",
+		classvers: 2,
+		elmts: []StreamerElement{
+			se_base("TObject", "", 1),
+			se_basic("fPx", "[0,100,16] px", KDouble32, "Double32_t"),
+			se_basic("fPy", "py", KDouble32, "Double32_t"),
+			se_basic("fQ", "[0,0,10] charge", KFloat16, "Float16_t"),
+			se_basic("fM", "mass", KFloat16, "Float16_t"),
+		},
+	},
+	{
+		name:      "Event",
+		classvers: 3,
+		elmts: []StreamerElement{
+			se_base("TNamed", "", 1),
+			se_basic("fN", "number of tracks", KInt, "int"),
+			se_basic("fLabel", "", KCharStar, "char*"),
+			se_basic_ptr("fE", "[fN]", KFloat, "float", "fN", "Event", 3),
+			&StreamerLoop{
+				seBase: seBase{
+					name:     "fTracks",
+					title:    "[fN]",
+					etype:    KStreamLoop,
+					typename: "Track*",
+				},
+				countvers:  3,
+				countname:  "fN",
+				countclass: "Event",
+			},
+			&StreamerLoop{
+				seBase: seBase{
+					name:     "fPtrs",
+					title:    "[fN]",
+					etype:    KStreamLoop,
+					typename: "Track**",
+				},
+				countvers:  3,
+				countname:  "fN",
+				countclass: "Event",
+			},
+			&StreamerObjectAny{
+				seBase: seBase{
+					name:     "fCustom",
+					etype:    KStreamer,
+					typename: "Custom",
+				},
+			},
+			se_basic("fEnd", "", KUShort, "unsigned short"),
+		},
+	},
+}
+
+// write_generic_track writes a "Track", streamed by value
+func write_generic_track(b *Buffer, px, py float64, q, m float32) {
+	pos := b.write_version(2)
+	b.write_tobject(0, 0)
+	write_double32(b, px, "[0,100,16] px", false)
+	write_double32(b, py, "py", false)
+	write_double32(b, float64(q), "[0,0,10] charge", true)
+	write_double32(b, float64(m), "mass", true)
+	b.set_byte_count(pos)
+}
+
+func TestGenericObject(t *testing.T) {
+	w, err := NewWBuffer(binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evt := w.write_version(3)
+	w.write_tnamed("evt", "my event")
+	w.i4ton(2)
+	w.i4ton(5)
+	w.write_nbytes([]byte("hello"))
+	w.byteton(1)
+	w.fton(1.5)
+	w.fton(2.5)
+
+	pos := w.write_version(1)
+	write_generic_track(w, 50, 1.25, 1.5, 0.5)
+	write_generic_track(w, 25, -3.5, -2, 0.25)
+	w.set_byte_count(pos)
+
+	pos = w.write_version(1)
+	for _, px := range []float64{75, 0} {
+		beg := w.Pos()
+		w.u4ton(0)
+		w.write_class("Track")
+		write_generic_track(w, px, 0, 0, 0)
+		w.set_byte_count(beg)
+	}
+	w.set_byte_count(pos)
+
+	custom := w.Pos()
+	pos = w.write_version(7)
+	w.i8ton(42)
+	w.set_byte_count(pos)
+	raw := append([]byte(nil), w.Bytes()[custom:]...)
+
+	w.u2ton(0xbeef)
+	w.set_byte_count(evt)
+
+	b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.file = &File{sinfos: generic_sinfos}
+
+	obj := new_generic_object("Event")
+	err = obj.ROOTDecode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("%d bytes left", b.Len())
+	}
+
+	if obj.Version() != 3 || obj.Name() != "evt" || obj.Title() != "my event" {
+		t.Fatalf("got version=%d name=%q title=%q", obj.Version(), obj.Name(), obj.Title())
+	}
+	want := []string{"fName", "fTitle", "fN", "fLabel", "fE", "fTracks", "fPtrs", "fCustom", "fEnd"}
+	if got := obj.Members(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got members %q, want %q", got, want)
+	}
+	for _, tc := range []struct {
+		name string
+		want interface{}
+	}{
+		{"fN", int32(2)},
+		{"fLabel", "hello"},
+		{"fE", []float32{1.5, 2.5}},
+		{"fEnd", uint16(0xbeef)},
+	} {
+		if got := obj.Value(tc.name); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %#v, want %#v", tc.name, got, tc.want)
+		}
+	}
+
+	tracks, ok := obj.Value("fTracks").([]interface{})
+	if !ok || len(tracks) != 2 {
+		t.Fatalf("fTracks: got %#v", obj.Value("fTracks"))
+	}
+	for i, want := range []struct {
+		px, py float64
+		q, m   float32
+	}{
+		{50, 1.25, 1.5, 0.5},
+		{25, -3.5, -2, 0.25},
+	} {
+		trk := tracks[i].(*GenericObject)
+		if trk.Class() != "Track" || trk.Version() != 2 {
+			t.Errorf("track #%d: got class=%s version=%d", i, trk.Class(), trk.Version())
+		}
+		got := []interface{}{trk.Value("fPx"), trk.Value("fPy"), trk.Value("fQ"), trk.Value("fM")}
+		exp := []interface{}{want.px, want.py, want.q, want.m}
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("track #%d: got %v, want %v", i, got, exp)
+		}
+	}
+
+	ptrs, ok := obj.Value("fPtrs").([]Object)
+	if !ok || len(ptrs) != 2 {
+		t.Fatalf("fPtrs: got %#v", obj.Value("fPtrs"))
+	}
+	for i, px := range []float64{75, 0} {
+		if got := ptrs[i].(*GenericObject).Value("fPx"); got != px {
+			t.Errorf("ptr #%d: got px=%v, want %v", i, got, px)
+		}
+	}
+
+	rm, ok := obj.Value("fCustom").(*RawMember)
+	if !ok {
+		t.Fatalf("fCustom: got %#v", obj.Value("fCustom"))
+	}
+	if rm.TypeName() != "Custom" || !bytes.Equal(rm.Bytes(), raw) {
+		t.Errorf("fCustom: got type=%s bytes=%v, want bytes=%v", rm.TypeName(), rm.Bytes(), raw)
+	}
+}
+
+func TestGenericObjectErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		write func(w *Buffer)
+		err   string
+	}{
+		{
+			name: "unknown class version",
+			write: func(w *Buffer) {
+				pos := w.write_version(4)
+				w.set_byte_count(pos)
+			},
+			err: "no StreamerInfo for class [Event] (version=4)",
+		},
+		{
+			name: "truncated",
+			write: func(w *Buffer) {
+				pos := w.write_version(3)
+				w.write_tnamed("evt", "")
+				w.i4ton(2)
+				w.set_byte_count(pos)
+			},
+			err: "member [fLabel]",
+		},
+		{
+			name: "invalid count",
+			write: func(w *Buffer) {
+				pos := w.write_version(3)
+				w.write_tnamed("evt", "")
+				w.i4ton(-1)
+				w.i4ton(0)
+				w.byteton(1)
+				w.set_byte_count(pos)
+			},
+			err: "member [fE]: groot: invalid length -1",
+		},
+	} {
+		w, err := NewWBuffer(binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc.write(w)
+		b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.file = &File{sinfos: generic_sinfos}
+		err = new_generic_object("Event").ROOTDecode(b)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got err=%v, want %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+// EOF
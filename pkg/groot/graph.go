@@ -0,0 +1,370 @@
+package groot
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Graph is a graph of (x,y) points (TGraph)
+type Graph struct {
+	name   string
+	title  string
+	line   AttLine
+	fill   AttFill
+	marker AttMarker
+
+	x     []float64 // x values of the points
+	y     []float64 // y values of the points
+	funcs Object    // list of functions associated to the graph
+	histo Object    // histogram used for drawing the axes
+	min   float64   // minimum value for plotting
+	max   float64   // maximum value for plotting
+	opt   string    // drawing options (version 5 and later)
+}
+
+// NewGraph returns a new graph made of the points (x[i],y[i])
+func NewGraph(name, title string, x, y []float64) (*Graph, error) {
+	if len(x) != len(y) {
+		return nil, fmt.Errorf("groot: graph [%s] has %d x values and %d y values", name, len(x), len(y))
+	}
+	return &Graph{
+		name:   name,
+		title:  title,
+		line:   AttLine{color: 1, style: 1, width: 1},
+		fill:   AttFill{color: 0, style: 1001},
+		marker: AttMarker{color: 1, style: 1, size: 1},
+		x:      x,
+		y:      y,
+		min:    -1111,
+		max:    -1111,
+	}, nil
+}
+
+func (g *Graph) Class() string {
+	return "TGraph"
+}
+
+func (g *Graph) Name() string {
+	return g.name
+}
+
+func (g *Graph) Title() string {
+	return g.title
+}
+
+// AttLine returns the line attributes of the graph
+func (g *Graph) AttLine() *AttLine {
+	return &g.line
+}
+
+// AttFill returns the fill area attributes of the graph
+func (g *Graph) AttFill() *AttFill {
+	return &g.fill
+}
+
+// AttMarker returns the marker attributes of the graph
+func (g *Graph) AttMarker() *AttMarker {
+	return &g.marker
+}
+
+// Len returns the number of points of the graph
+func (g *Graph) Len() int {
+	return len(g.x)
+}
+
+// XY returns the coordinates of the i-th point
+func (g *Graph) XY(i int) (x, y float64) {
+	return g.x[i], g.y[i]
+}
+
+// X returns the x values of the points
+func (g *Graph) X() []float64 {
+	return g.x
+}
+
+// Y returns the y values of the points
+func (g *Graph) Y() []float64 {
+	return g.y
+}
+
+// Maximum returns the maximum value set for plotting (-1111 if unset)
+func (g *Graph) Maximum() float64 {
+	return g.max
+}
+
+// Minimum returns the minimum value set for plotting (-1111 if unset)
+func (g *Graph) Minimum() float64 {
+	return g.min
+}
+
+// read_graph_array reads an array of n doubles, streamed as a pointer
+// see TStreamerInfo::ReadBuffer::ReadBasicPointer
+func read_graph_array(b *Buffer, n int) []float64 {
+	if b.ntobyte() == 0 {
+		return make([]float64, n)
+	}
+	return b.read_fast_array_D(n)
+}
+
+// write_graph_array writes an array of doubles, streamed as a pointer
+func write_graph_array(b *Buffer, o []float64) {
+	if len(o) == 0 {
+		b.byteton(0)
+		return
+	}
+	b.byteton(1)
+	b.write_fast_array_D(o)
+}
+
+func (g *Graph) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[graph] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	if vers < 2 {
+		return fmt.Errorf("groot: too old version of TGraph (%d)", vers)
+	}
+
+	g.name, g.title = b.read_tnamed()
+	for _, att := range []ROOTStreamer{&g.line, &g.fill, &g.marker} {
+		err = att.ROOTDecode(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	n := int(b.ntoi4())
+	if !b.check_len(n, 2) {
+		return b.err
+	}
+	g.x = read_graph_array(b, n)
+	g.y = read_graph_array(b, n)
+	g.funcs = b.read_object()
+	g.histo = b.read_object()
+	g.min = b.ntod()
+	g.max = b.ntod()
+	if vers >= 5 {
+		g.opt = b.read_tstring()
+	}
+
+	b.check_byte_count(pos, bcnt, spos, "TGraph")
+	return
+}
+
+func (g *Graph) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(4)
+	b.write_tnamed(g.name, g.title)
+	for _, att := range []ROOTStreamer{&g.line, &g.fill, &g.marker} {
+		err = att.ROOTEncode(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.i4ton(int32(len(g.x)))
+	write_graph_array(b, g.x)
+	write_graph_array(b, g.y)
+
+	// ROOT expects the list of functions to always be there
+	funcs := g.funcs
+	if funcs == nil {
+		funcs = &List{}
+	}
+	err = b.write_object(funcs)
+	if err != nil {
+		return err
+	}
+	err = b.write_object(g.histo)
+	if err != nil {
+		return err
+	}
+	b.dton(g.min)
+	b.dton(g.max)
+
+	b.set_byte_count(pos)
+	return
+}
+
+// GraphErrors is a graph of (x,y) points with symmetric errors (TGraphErrors)
+type GraphErrors struct {
+	Graph
+	ex []float64 // errors on the x values
+	ey []float64 // errors on the y values
+}
+
+// NewGraphErrors returns a new graph made of the points (x[i],y[i]) with
+// the errors ex[i] and ey[i]
+func NewGraphErrors(name, title string, x, y, ex, ey []float64) (*GraphErrors, error) {
+	g, err := NewGraph(name, title, x, y)
+	if err != nil {
+		return nil, err
+	}
+	if len(ex) != len(x) || len(ey) != len(x) {
+		return nil, fmt.Errorf("groot: graph [%s] has inconsistent numbers of errors", name)
+	}
+	return &GraphErrors{Graph: *g, ex: ex, ey: ey}, nil
+}
+
+func (g *GraphErrors) Class() string {
+	return "TGraphErrors"
+}
+
+// XError returns the error on the x value of the i-th point
+func (g *GraphErrors) XError(i int) float64 {
+	return g.ex[i]
+}
+
+// YError returns the error on the y value of the i-th point
+func (g *GraphErrors) YError(i int) float64 {
+	return g.ey[i]
+}
+
+// EX returns the errors on the x values of the points
+func (g *GraphErrors) EX() []float64 {
+	return g.ex
+}
+
+// EY returns the errors on the y values of the points
+func (g *GraphErrors) EY() []float64 {
+	return g.ey
+}
+
+func (g *GraphErrors) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[graph-errs] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	err = g.Graph.ROOTDecode(b)
+	if err != nil {
+		return err
+	}
+	n := len(g.x)
+	g.ex = read_graph_array(b, n)
+	g.ey = read_graph_array(b, n)
+	b.check_byte_count(pos, bcnt, spos, "TGraphErrors")
+	return
+}
+
+func (g *GraphErrors) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(3)
+	err = g.Graph.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	write_graph_array(b, g.ex)
+	write_graph_array(b, g.ey)
+	b.set_byte_count(pos)
+	return
+}
+
+// GraphAsymmErrors is a graph of (x,y) points with asymmetric errors
+// (TGraphAsymmErrors)
+type GraphAsymmErrors struct {
+	Graph
+	exlo []float64 // low errors on the x values
+	exhi []float64 // high errors on the x values
+	eylo []float64 // low errors on the y values
+	eyhi []float64 // high errors on the y values
+}
+
+// NewGraphAsymmErrors returns a new graph made of the points (x[i],y[i])
+// with the low and high errors exlo[i], exhi[i], eylo[i] and eyhi[i]
+func NewGraphAsymmErrors(name, title string, x, y, exlo, exhi, eylo, eyhi []float64) (*GraphAsymmErrors, error) {
+	g, err := NewGraph(name, title, x, y)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range [][]float64{exlo, exhi, eylo, eyhi} {
+		if len(e) != len(x) {
+			return nil, fmt.Errorf("groot: graph [%s] has inconsistent numbers of errors", name)
+		}
+	}
+	return &GraphAsymmErrors{
+		Graph: *g,
+		exlo:  exlo,
+		exhi:  exhi,
+		eylo:  eylo,
+		eyhi:  eyhi,
+	}, nil
+}
+
+func (g *GraphAsymmErrors) Class() string {
+	return "TGraphAsymmErrors"
+}
+
+// XError returns the low and high errors on the x value of the i-th point
+func (g *GraphAsymmErrors) XError(i int) (lo, hi float64) {
+	return g.exlo[i], g.exhi[i]
+}
+
+// YError returns the low and high errors on the y value of the i-th point
+func (g *GraphAsymmErrors) YError(i int) (lo, hi float64) {
+	return g.eylo[i], g.eyhi[i]
+}
+
+func (g *GraphAsymmErrors) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[graph-asymm-errs] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	err = g.Graph.ROOTDecode(b)
+	if err != nil {
+		return err
+	}
+	n := len(g.x)
+	g.exlo = read_graph_array(b, n)
+	g.exhi = read_graph_array(b, n)
+	g.eylo = read_graph_array(b, n)
+	g.eyhi = read_graph_array(b, n)
+	b.check_byte_count(pos, bcnt, spos, "TGraphAsymmErrors")
+	return
+}
+
+func (g *GraphAsymmErrors) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(3)
+	err = g.Graph.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	for _, e := range [][]float64{g.exlo, g.exhi, g.eylo, g.eyhi} {
+		write_graph_array(b, e)
+	}
+	b.set_byte_count(pos)
+	return
+}
+
+func init() {
+	{
+		f := func() reflect.Value {
+			o := &Graph{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TGraph"] = f
+		Factory.db["*groot.Graph"] = f
+	}
+
+	{
+		f := func() reflect.Value {
+			o := &GraphErrors{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TGraphErrors"] = f
+		Factory.db["*groot.GraphErrors"] = f
+	}
+
+	{
+		f := func() reflect.Value {
+			o := &GraphAsymmErrors{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TGraphAsymmErrors"] = f
+		Factory.db["*groot.GraphAsymmErrors"] = f
+	}
+}
+
+// check interfaces
+var _ Object = (*Graph)(nil)
+var _ ROOTStreamer = (*Graph)(nil)
+var _ Object = (*GraphErrors)(nil)
+var _ ROOTStreamer = (*GraphErrors)(nil)
+var _ Object = (*GraphAsymmErrors)(nil)
+var _ ROOTStreamer = (*GraphAsymmErrors)(nil)
+
+// EOF
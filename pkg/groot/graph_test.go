@@ -0,0 +1,191 @@
+package groot
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGraphReadWrite(t *testing.T) {
+	x := []float64{1, 2, 3}
+	y := []float64{2, 4, -1}
+
+	{
+		want, err := NewGraph("gr", "my graph", x, y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want.AttMarker().size = 2
+		var got Graph
+		write_read(t, want, &got)
+		// the list of functions is always written
+		if _, ok := got.funcs.(*List); !ok {
+			t.Errorf("TGraph: got funcs %#v", got.funcs)
+		}
+		got.funcs = nil
+		if !reflect.DeepEqual(got, *want) {
+			t.Errorf("TGraph: got %+v, want %+v", got, *want)
+		}
+		if x, y := got.XY(2); got.Len() != 3 || x != 3 || y != -1 {
+			t.Errorf("TGraph: got len=%d xy=(%v,%v)", got.Len(), x, y)
+		}
+		if got.Minimum() != -1111 || got.Maximum() != -1111 {
+			t.Errorf("TGraph: got min=%v max=%v", got.Minimum(), got.Maximum())
+		}
+	}
+
+	{
+		want, err := NewGraphErrors("gre", "my graph", x, y, []float64{0.1, 0.2, 0.3}, []float64{1, 2, 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got GraphErrors
+		write_read(t, want, &got)
+		if !reflect.DeepEqual(got.X(), x) || !reflect.DeepEqual(got.Y(), y) ||
+			!reflect.DeepEqual(got.EX(), want.EX()) || !reflect.DeepEqual(got.EY(), want.EY()) {
+			t.Errorf("TGraphErrors: got %+v, want %+v", got, *want)
+		}
+		if got.Class() != "TGraphErrors" || got.Name() != "gre" || got.XError(1) != 0.2 || got.YError(2) != 3 {
+			t.Errorf("TGraphErrors: got class=%s name=%s ex[1]=%v ey[2]=%v",
+				got.Class(), got.Name(), got.XError(1), got.YError(2))
+		}
+	}
+
+	{
+		want, err := NewGraphAsymmErrors("gra", "my graph", x, y,
+			[]float64{1, 2, 3}, []float64{4, 5, 6}, []float64{7, 8, 9}, []float64{10, 11, 12})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got GraphAsymmErrors
+		write_read(t, want, &got)
+		got.funcs = nil
+		if !reflect.DeepEqual(got, *want) {
+			t.Errorf("TGraphAsymmErrors: got %+v, want %+v", got, *want)
+		}
+		xlo, xhi := got.XError(1)
+		ylo, yhi := got.YError(2)
+		if xlo != 2 || xhi != 5 || ylo != 9 || yhi != 12 {
+			t.Errorf("TGraphAsymmErrors: got x errors (%v,%v), y errors (%v,%v)", xlo, xhi, ylo, yhi)
+		}
+	}
+
+	// an empty graph
+	{
+		want, err := NewGraphErrors("empty", "", nil, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got GraphErrors
+		write_read(t, want, &got)
+		if got.Len() != 0 || len(got.EX()) != 0 || len(got.EY()) != 0 {
+			t.Errorf("empty: got %+v", got)
+		}
+	}
+}
+
+func TestNewGraphErrors(t *testing.T) {
+	x := []float64{1, 2}
+	_, err := NewGraph("gr", "", x, []float64{1})
+	if err == nil || err.Error() != "groot: graph [gr] has 2 x values and 1 y values" {
+		t.Errorf("TGraph: got err=%v", err)
+	}
+	_, err = NewGraphErrors("gre", "", x, x, x, []float64{1})
+	if err == nil || err.Error() != "groot: graph [gre] has inconsistent numbers of errors" {
+		t.Errorf("TGraphErrors: got err=%v", err)
+	}
+	_, err = NewGraphAsymmErrors("gra", "", x, x, x, x, nil, x)
+	if err == nil || err.Error() != "groot: graph [gra] has inconsistent numbers of errors" {
+		t.Errorf("TGraphAsymmErrors: got err=%v", err)
+	}
+}
+
+// write_graph writes a TGraph of the given version, with the x values
+// streamed as a null pointer
+func write_graph(w *Buffer, vers uint16, y []float64) {
+	pos := w.write_version(vers)
+	w.write_tnamed("gr", "")
+	w.write_attline(1, 1, 1)
+	w.write_attfill(0, 1001)
+	w.write_attmarker(1, 1, 1)
+	w.i4ton(int32(len(y)))
+	w.byteton(0)
+	write_graph_array(w, y)
+	w.write_object(nil)
+	w.write_object(nil)
+	w.dton(-1)
+	w.dton(10)
+	if vers >= 5 {
+		w.write_tstring("AP")
+	}
+	w.set_byte_count(pos)
+}
+
+func TestGraphDecode(t *testing.T) {
+	w, err := NewWBuffer(binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write_graph(w, 5, []float64{1, 2})
+	b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Graph
+	err = got.ROOTDecode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("%d bytes left", b.Len())
+	}
+	if !reflect.DeepEqual(got.X(), []float64{0, 0}) || !reflect.DeepEqual(got.Y(), []float64{1, 2}) ||
+		got.opt != "AP" || got.Minimum() != -1 || got.Maximum() != 10 {
+		t.Errorf("got %+v", got)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		write func(w *Buffer)
+		err   string
+	}{
+		{
+			name: "too old version",
+			write: func(w *Buffer) {
+				pos := w.write_version(1)
+				w.set_byte_count(pos)
+			},
+			err: "groot: too old version of TGraph (1)",
+		},
+		{
+			name: "invalid number of points",
+			write: func(w *Buffer) {
+				pos := w.write_version(4)
+				w.write_tnamed("gr", "")
+				w.write_attline(1, 1, 1)
+				w.write_attfill(0, 1001)
+				w.write_attmarker(1, 1, 1)
+				w.i4ton(1000)
+				w.set_byte_count(pos)
+			},
+			err: "invalid length 1000",
+		},
+	} {
+		w, err := NewWBuffer(binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc.write(w)
+		b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = new(Graph).ROOTDecode(b)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got err=%v, want %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+// EOF
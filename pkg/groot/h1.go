@@ -0,0 +1,523 @@
+package groot
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// H1 is the base class of all histograms (TH1)
+type H1 struct {
+	name   string
+	title  string
+	line   AttLine
+	fill   AttFill
+	marker AttMarker
+
+	ncells   int       // number of bins, including under- and overflows
+	xaxis    Axis      // x axis descriptor
+	yaxis    Axis      // y axis descriptor
+	zaxis    Axis      // z axis descriptor
+	baroff   int16     // (1000*offset) for bar charts or legos
+	barwidth int16     // (1000*width) for bar charts or legos
+	entries  float64   // number of entries
+	tsumw    float64   // total sum of weights
+	tsumw2   float64   // total sum of squares of weights
+	tsumwx   float64   // total sum of weight*x
+	tsumwx2  float64   // total sum of weight*x*x
+	max      float64   // maximum value for plotting
+	min      float64   // minimum value for plotting
+	norm     float64   // normalization factor
+	contour  []float64 // levels for contour plots
+	sumw2    []float64 // sum of squares of weights, per bin
+	opt      string    // histogram options
+	funcs    Object    // list of functions associated to the histogram
+	buffer   []float64 // entry buffer
+	erropt   int32     // option for the bin statistical errors
+	statovf  int32     // whether to use the overflows in the statistics
+
+	bins []float64 // bin contents, including under- and overflows
+}
+
+// new_h1 returns a new histogram base with nbins bins of equal width along x
+func new_h1(name, title string, ncells, nbins int, xmin, xmax float64) H1 {
+	return H1{
+		name:     name,
+		title:    title,
+		line:     AttLine{color: 602, style: 1, width: 1},
+		fill:     AttFill{color: 0, style: 1001},
+		marker:   AttMarker{color: 1, style: 1, size: 1},
+		ncells:   ncells,
+		xaxis:    *new_axis("xaxis", nbins, xmin, xmax),
+		yaxis:    *new_axis("yaxis", 1, 0, 1),
+		zaxis:    *new_axis("zaxis", 1, 0, 1),
+		barwidth: 1000,
+		max:      -1111,
+		min:      -1111,
+		sumw2:    make([]float64, ncells),
+		statovf:  2, // kNeutral
+		bins:     make([]float64, ncells),
+	}
+}
+
+func (h *H1) Class() string {
+	return "TH1"
+}
+
+func (h *H1) Name() string {
+	return h.name
+}
+
+func (h *H1) Title() string {
+	return h.title
+}
+
+// XAxis returns the x axis of the histogram
+func (h *H1) XAxis() *Axis {
+	return &h.xaxis
+}
+
+// YAxis returns the y axis of the histogram
+func (h *H1) YAxis() *Axis {
+	return &h.yaxis
+}
+
+// ZAxis returns the z axis of the histogram
+func (h *H1) ZAxis() *Axis {
+	return &h.zaxis
+}
+
+// AttLine returns the line attributes of the histogram
+func (h *H1) AttLine() *AttLine {
+	return &h.line
+}
+
+// AttFill returns the fill area attributes of the histogram
+func (h *H1) AttFill() *AttFill {
+	return &h.fill
+}
+
+// AttMarker returns the marker attributes of the histogram
+func (h *H1) AttMarker() *AttMarker {
+	return &h.marker
+}
+
+// NBins returns the number of bins along x (under- and overflow excluded)
+func (h *H1) NBins() int {
+	return h.xaxis.nbins
+}
+
+// Entries returns the number of entries of the histogram
+func (h *H1) Entries() float64 {
+	return h.entries
+}
+
+// SumW returns the total sum of weights
+func (h *H1) SumW() float64 {
+	return h.tsumw
+}
+
+// SumW2 returns the total sum of squares of weights
+func (h *H1) SumW2() float64 {
+	return h.tsumw2
+}
+
+// SumWX returns the total sum of weight*x
+func (h *H1) SumWX() float64 {
+	return h.tsumwx
+}
+
+// SumWX2 returns the total sum of weight*x*x
+func (h *H1) SumWX2() float64 {
+	return h.tsumwx2
+}
+
+// Maximum returns the maximum value set for plotting (-1111 if unset)
+func (h *H1) Maximum() float64 {
+	return h.max
+}
+
+// Minimum returns the minimum value set for plotting (-1111 if unset)
+func (h *H1) Minimum() float64 {
+	return h.min
+}
+
+// Mean returns the mean of the x values of the histogram
+func (h *H1) Mean() float64 {
+	if h.tsumw == 0 {
+		return 0
+	}
+	return h.tsumwx / h.tsumw
+}
+
+// StdDev returns the standard deviation of the x values of the histogram
+func (h *H1) StdDev() float64 {
+	if h.tsumw == 0 {
+		return 0
+	}
+	mean := h.tsumwx / h.tsumw
+	return math.Sqrt(math.Abs(h.tsumwx2/h.tsumw - mean*mean))
+}
+
+// Sumw2 returns the sum of squares of weights of each bin (under- and
+// overflow included), or nil if they were not stored.
+func (h *H1) Sumw2() []float64 {
+	return h.sumw2
+}
+
+// Bins returns the contents of all the bins (under- and overflow included)
+func (h *H1) Bins() []float64 {
+	return h.bins
+}
+
+// BinContent returns the content of bin i.
+// 0 is the underflow bin, NBins()+1 the overflow bin.
+func (h *H1) BinContent(i int) float64 {
+	if i < 0 || i >= len(h.bins) {
+		return 0
+	}
+	return h.bins[i]
+}
+
+// BinError returns the statistical error of bin i.
+// 0 is the underflow bin, NBins()+1 the overflow bin.
+func (h *H1) BinError(i int) float64 {
+	if i < 0 || i >= len(h.bins) {
+		return 0
+	}
+	if len(h.sumw2) > 0 {
+		return math.Sqrt(h.sumw2[i])
+	}
+	return math.Sqrt(math.Abs(h.bins[i]))
+}
+
+// Underflow returns the content of the underflow bin
+func (h *H1) Underflow() float64 {
+	return h.BinContent(0)
+}
+
+// Overflow returns the content of the overflow bin
+func (h *H1) Overflow() float64 {
+	return h.BinContent(h.xaxis.nbins + 1)
+}
+
+// Fill adds the weight w to the bin holding x
+func (h *H1) Fill(x, w float64) {
+	i := h.xaxis.FindBin(x)
+	h.fill_bin(i, w)
+	if i == 0 || i > h.xaxis.nbins {
+		return
+	}
+	h.tsumw += w
+	h.tsumw2 += w * w
+	h.tsumwx += w * x
+	h.tsumwx2 += w * x * x
+}
+
+// fill_bin adds the weight w to the (global) bin i
+func (h *H1) fill_bin(i int, w float64) {
+	h.entries++
+	if i < 0 || i >= len(h.bins) {
+		return
+	}
+	h.bins[i] += w
+	if len(h.sumw2) > 0 {
+		h.sumw2[i] += w * w
+	}
+}
+
+func (h *H1) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[th1] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	if vers < 4 {
+		return fmt.Errorf("groot: too old version of TH1 (%d)", vers)
+	}
+
+	h.name, h.title = b.read_tnamed()
+	for _, att := range []ROOTStreamer{&h.line, &h.fill, &h.marker} {
+		err = att.ROOTDecode(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	h.ncells = int(b.ntoi4())
+	for _, axis := range []*Axis{&h.xaxis, &h.yaxis, &h.zaxis} {
+		err = axis.ROOTDecode(b)
+		if err != nil {
+			return err
+		}
+	}
+	h.baroff = b.ntoi2()
+	h.barwidth = b.ntoi2()
+	h.entries = b.ntod()
+	h.tsumw = b.ntod()
+	h.tsumw2 = b.ntod()
+	h.tsumwx = b.ntod()
+	h.tsumwx2 = b.ntod()
+	h.max = b.ntod()
+	h.min = b.ntod()
+	h.norm = b.ntod()
+	h.contour = b.read_array_D()
+	h.sumw2 = b.read_array_D()
+	h.opt = b.read_tstring()
+	// fFunctions is never null: the list is streamed in place
+	funcs := &List{}
+	err = funcs.ROOTDecode(b)
+	if err != nil {
+		return err
+	}
+	h.funcs = funcs
+	n := int(b.ntoi4()) // fBufferSize
+	if b.ntobyte() != 0 {
+		h.buffer = b.read_fast_array_D(n)
+	}
+	if vers >= 7 {
+		h.erropt = b.ntoi4()
+	}
+	if vers >= 8 {
+		h.statovf = b.ntoi4()
+	}
+
+	b.check_byte_count(pos, bcnt, spos, "TH1")
+	return
+}
+
+func (h *H1) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(8)
+	b.write_tnamed(h.name, h.title)
+	for _, att := range []ROOTStreamer{&h.line, &h.fill, &h.marker} {
+		err = att.ROOTEncode(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.i4ton(int32(h.ncells))
+	for _, axis := range []*Axis{&h.xaxis, &h.yaxis, &h.zaxis} {
+		err = axis.ROOTEncode(b)
+		if err != nil {
+			return err
+		}
+	}
+	b.i2ton(h.baroff)
+	b.i2ton(h.barwidth)
+	b.dton(h.entries)
+	b.dton(h.tsumw)
+	b.dton(h.tsumw2)
+	b.dton(h.tsumwx)
+	b.dton(h.tsumwx2)
+	b.dton(h.max)
+	b.dton(h.min)
+	b.dton(h.norm)
+	b.write_array_D(h.contour)
+	b.write_array_D(h.sumw2)
+	b.write_tstring(h.opt)
+
+	// fFunctions is never null: the list is streamed in place
+	funcs, ok := h.funcs.(ROOTStreamer)
+	if !ok {
+		funcs = &List{}
+	}
+	err = funcs.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+
+	b.i4ton(int32(len(h.buffer)))
+	if len(h.buffer) > 0 {
+		b.byteton(1)
+		b.write_fast_array_D(h.buffer)
+	} else {
+		b.byteton(0)
+	}
+	b.i4ton(h.erropt)
+	b.i4ton(h.statovf)
+
+	b.set_byte_count(pos)
+	return
+}
+
+// H1F is a 1-dim histogram with one float per bin (TH1F)
+type H1F struct {
+	H1
+}
+
+// NewH1F returns a new 1-dim histogram with nbins bins of equal width
+// between xmin and xmax.
+func NewH1F(name, title string, nbins int, xmin, xmax float64) *H1F {
+	return &H1F{new_h1(name, title, nbins+2, nbins, xmin, xmax)}
+}
+
+func (h *H1F) Class() string {
+	return "TH1F"
+}
+
+func (h *H1F) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[th1f] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	err = h.H1.ROOTDecode(b)
+	if err != nil {
+		return err
+	}
+	arr := b.read_array_F()
+	h.bins = make([]float64, len(arr))
+	for i, v := range arr {
+		h.bins[i] = float64(v)
+	}
+	b.check_byte_count(pos, bcnt, spos, "TH1F")
+	return
+}
+
+func (h *H1F) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(3)
+	err = h.H1.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	arr := make([]float32, len(h.bins))
+	for i, v := range h.bins {
+		arr[i] = float32(v)
+	}
+	b.write_array_F(arr)
+	b.set_byte_count(pos)
+	return
+}
+
+// H1D is a 1-dim histogram with one double per bin (TH1D)
+type H1D struct {
+	H1
+}
+
+// NewH1D returns a new 1-dim histogram with nbins bins of equal width
+// between xmin and xmax.
+func NewH1D(name, title string, nbins int, xmin, xmax float64) *H1D {
+	return &H1D{new_h1(name, title, nbins+2, nbins, xmin, xmax)}
+}
+
+func (h *H1D) Class() string {
+	return "TH1D"
+}
+
+func (h *H1D) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[th1d] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	err = h.H1.ROOTDecode(b)
+	if err != nil {
+		return err
+	}
+	h.bins = b.read_array_D()
+	b.check_byte_count(pos, bcnt, spos, "TH1D")
+	return
+}
+
+func (h *H1D) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(3)
+	err = h.H1.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.write_array_D(h.bins)
+	b.set_byte_count(pos)
+	return
+}
+
+// H1I is a 1-dim histogram with one int per bin (TH1I)
+type H1I struct {
+	H1
+}
+
+// NewH1I returns a new 1-dim histogram with nbins bins of equal width
+// between xmin and xmax.
+func NewH1I(name, title string, nbins int, xmin, xmax float64) *H1I {
+	return &H1I{new_h1(name, title, nbins+2, nbins, xmin, xmax)}
+}
+
+func (h *H1I) Class() string {
+	return "TH1I"
+}
+
+func (h *H1I) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[th1i] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	err = h.H1.ROOTDecode(b)
+	if err != nil {
+		return err
+	}
+	arr := b.read_array_I()
+	h.bins = make([]float64, len(arr))
+	for i, v := range arr {
+		h.bins[i] = float64(v)
+	}
+	b.check_byte_count(pos, bcnt, spos, "TH1I")
+	return
+}
+
+func (h *H1I) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(3)
+	err = h.H1.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	arr := make([]int32, len(h.bins))
+	for i, v := range h.bins {
+		arr[i] = int32(v)
+	}
+	b.write_array_I(arr)
+	b.set_byte_count(pos)
+	return
+}
+
+func init() {
+	{
+		f := func() reflect.Value {
+			o := &H1{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TH1"] = f
+		Factory.db["*groot.H1"] = f
+	}
+
+	{
+		f := func() reflect.Value {
+			o := &H1F{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TH1F"] = f
+		Factory.db["*groot.H1F"] = f
+	}
+
+	{
+		f := func() reflect.Value {
+			o := &H1D{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TH1D"] = f
+		Factory.db["*groot.H1D"] = f
+	}
+
+	{
+		f := func() reflect.Value {
+			o := &H1I{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TH1I"] = f
+		Factory.db["*groot.H1I"] = f
+	}
+}
+
+// check interfaces
+var _ Object = (*H1)(nil)
+var _ ROOTStreamer = (*H1)(nil)
+var _ Object = (*H1F)(nil)
+var _ ROOTStreamer = (*H1F)(nil)
+var _ Object = (*H1D)(nil)
+var _ ROOTStreamer = (*H1D)(nil)
+var _ Object = (*H1I)(nil)
+var _ ROOTStreamer = (*H1I)(nil)
+
+// EOF
@@ -0,0 +1,140 @@
+package groot
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestH1Fill(t *testing.T) {
+	h := NewH1D("h1", "h1", 4, 0, 4)
+	for _, x := range []float64{-1, 0.5, 1.5, 1.5, 3.5, 4, math.NaN(), math.Inf(-1)} {
+		h.Fill(x, 2)
+	}
+
+	if got, want := h.Bins(), []float64{4, 2, 4, 0, 2, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got bins %v, want %v", got, want)
+	}
+	if got, want := h.Entries(), 8.0; got != want {
+		t.Errorf("got entries %v, want %v", got, want)
+	}
+	if got, want := h.BinError(2), 2*math.Sqrt2; got != want {
+		t.Errorf("got error %v, want %v", got, want)
+	}
+
+	// under- and overflows (NaN included) do not enter the statistics
+	for _, tc := range []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"sumw", h.SumW(), 8},
+		{"sumw2", h.SumW2(), 16},
+		{"sumwx", h.SumWX(), 2 * (0.5 + 1.5 + 1.5 + 3.5)},
+		{"sumwx2", h.SumWX2(), 2 * (0.25 + 2.25 + 2.25 + 12.25)},
+		{"mean", h.Mean(), 1.75},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestH2Fill(t *testing.T) {
+	h := NewH2F("h2", "h2", 2, 0, 2, 3, 0, 3)
+	h.Fill(0.5, 1.5, 1)
+	h.Fill(1.5, 2.5, 3)
+	h.Fill(math.NaN(), 0.5, 1)
+	h.Fill(0.5, math.NaN(), 1)
+	h.Fill(-1, 5, 1)
+
+	for _, tc := range []struct {
+		ix, iy int
+		want   float64
+	}{
+		{1, 2, 1},
+		{2, 3, 3},
+		{3, 1, 1},
+		{1, 4, 1},
+		{0, 4, 1},
+		{1, 1, 0},
+	} {
+		if got := h.BinContent(tc.ix, tc.iy); got != tc.want {
+			t.Errorf("bin (%d,%d): got %v, want %v", tc.ix, tc.iy, got, tc.want)
+		}
+	}
+	if got, want := h.Entries(), 5.0; got != want {
+		t.Errorf("got entries %v, want %v", got, want)
+	}
+	for _, tc := range []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"sumw", h.SumW(), 4},
+		{"sumwx", h.SumWX(), 0.5 + 3*1.5},
+		{"sumwy", h.SumWY(), 1.5 + 3*2.5},
+		{"sumwxy", h.SumWXY(), 0.5*1.5 + 3*1.5*2.5},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestH2ReadWrite(t *testing.T) {
+	want := NewH2D("h2", "my h2", 2, 0, 2, 2, -1, 1)
+	want.Fill(0.5, 0.5, 2)
+	want.Fill(1.5, -0.5, 1)
+	want.Fill(5, 0, 1)
+
+	w, err := NewWBuffer(binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = want.ROOTEncode(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got H2D
+	err = got.ROOTDecode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("%d bytes left", b.Len())
+	}
+
+	if got.Name() != want.Name() || got.Title() != want.Title() {
+		t.Errorf("got name=%q title=%q", got.Name(), got.Title())
+	}
+	if got.NBinsX() != want.NBinsX() || got.NBinsY() != want.NBinsY() {
+		t.Errorf("got nbins=(%d,%d)", got.NBinsX(), got.NBinsY())
+	}
+	if !reflect.DeepEqual(got.Bins(), want.Bins()) || !reflect.DeepEqual(got.Sumw2(), want.Sumw2()) {
+		t.Errorf("got bins=%v sumw2=%v, want bins=%v sumw2=%v",
+			got.Bins(), got.Sumw2(), want.Bins(), want.Sumw2())
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"entries", got.Entries(), want.Entries()},
+		{"sumw", got.SumW(), want.SumW()},
+		{"sumwx2", got.SumWX2(), want.SumWX2()},
+		{"sumwy", got.SumWY(), want.SumWY()},
+		{"sumwxy", got.SumWXY(), want.SumWXY()},
+		{"ymin", got.YAxis().XMin(), -1},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+// EOF
@@ -0,0 +1,264 @@
+package groot
+
+import (
+	"math"
+	"reflect"
+)
+
+// H2 is the base class of all 2-dim histograms (TH2)
+type H2 struct {
+	H1
+	scale   float64 // scale factor
+	tsumwy  float64 // total sum of weight*y
+	tsumwy2 float64 // total sum of weight*y*y
+	tsumwxy float64 // total sum of weight*x*y
+}
+
+// new_h2 returns a new 2-dim histogram base with bins of equal width
+func new_h2(name, title string, nx int, xmin, xmax float64, ny int, ymin, ymax float64) H2 {
+	h := H2{
+		H1:    new_h1(name, title, (nx+2)*(ny+2), nx, xmin, xmax),
+		scale: 1,
+	}
+	h.yaxis = *new_axis("yaxis", ny, ymin, ymax)
+	return h
+}
+
+func (h *H2) Class() string {
+	return "TH2"
+}
+
+// NBinsX returns the number of bins along x (under- and overflow excluded)
+func (h *H2) NBinsX() int {
+	return h.xaxis.nbins
+}
+
+// NBinsY returns the number of bins along y (under- and overflow excluded)
+func (h *H2) NBinsY() int {
+	return h.yaxis.nbins
+}
+
+// SumWY returns the total sum of weight*y
+func (h *H2) SumWY() float64 {
+	return h.tsumwy
+}
+
+// SumWY2 returns the total sum of weight*y*y
+func (h *H2) SumWY2() float64 {
+	return h.tsumwy2
+}
+
+// SumWXY returns the total sum of weight*x*y
+func (h *H2) SumWXY() float64 {
+	return h.tsumwxy
+}
+
+// MeanY returns the mean of the y values of the histogram
+func (h *H2) MeanY() float64 {
+	if h.tsumw == 0 {
+		return 0
+	}
+	return h.tsumwy / h.tsumw
+}
+
+// StdDevY returns the standard deviation of the y values of the histogram
+func (h *H2) StdDevY() float64 {
+	if h.tsumw == 0 {
+		return 0
+	}
+	mean := h.tsumwy / h.tsumw
+	return math.Sqrt(math.Abs(h.tsumwy2/h.tsumw - mean*mean))
+}
+
+// bin returns the global bin number of bin (ix,iy)
+func (h *H2) bin(ix, iy int) int {
+	nx := h.xaxis.nbins + 2
+	if ix < 0 || ix >= nx || iy < 0 || iy >= h.yaxis.nbins+2 {
+		return -1
+	}
+	return ix + nx*iy
+}
+
+// BinContent returns the content of bin (ix,iy).
+// 0 is the underflow bin, NBinsX()+1 (NBinsY()+1) the overflow bin.
+func (h *H2) BinContent(ix, iy int) float64 {
+	return h.H1.BinContent(h.bin(ix, iy))
+}
+
+// BinError returns the statistical error of bin (ix,iy).
+// 0 is the underflow bin, NBinsX()+1 (NBinsY()+1) the overflow bin.
+func (h *H2) BinError(ix, iy int) float64 {
+	return h.H1.BinError(h.bin(ix, iy))
+}
+
+// Fill adds the weight w to the bin holding (x,y)
+func (h *H2) Fill(x, y, w float64) {
+	ix := h.xaxis.FindBin(x)
+	iy := h.yaxis.FindBin(y)
+	h.fill_bin(h.bin(ix, iy), w)
+	if ix == 0 || ix > h.xaxis.nbins || iy == 0 || iy > h.yaxis.nbins {
+		return
+	}
+	h.tsumw += w
+	h.tsumw2 += w * w
+	h.tsumwx += w * x
+	h.tsumwx2 += w * x * x
+	h.tsumwy += w * y
+	h.tsumwy2 += w * y * y
+	h.tsumwxy += w * x * y
+}
+
+func (h *H2) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[th2] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	err = h.H1.ROOTDecode(b)
+	if err != nil {
+		return err
+	}
+	h.scale = b.ntod()
+	h.tsumwy = b.ntod()
+	h.tsumwy2 = b.ntod()
+	h.tsumwxy = b.ntod()
+	b.check_byte_count(pos, bcnt, spos, "TH2")
+	return
+}
+
+func (h *H2) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(5)
+	err = h.H1.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.dton(h.scale)
+	b.dton(h.tsumwy)
+	b.dton(h.tsumwy2)
+	b.dton(h.tsumwxy)
+	b.set_byte_count(pos)
+	return
+}
+
+// H2F is a 2-dim histogram with one float per bin (TH2F)
+type H2F struct {
+	H2
+}
+
+// NewH2F returns a new 2-dim histogram with nx (ny) bins of equal width
+// between xmin and xmax (ymin and ymax.)
+func NewH2F(name, title string, nx int, xmin, xmax float64, ny int, ymin, ymax float64) *H2F {
+	return &H2F{new_h2(name, title, nx, xmin, xmax, ny, ymin, ymax)}
+}
+
+func (h *H2F) Class() string {
+	return "TH2F"
+}
+
+func (h *H2F) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[th2f] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	err = h.H2.ROOTDecode(b)
+	if err != nil {
+		return err
+	}
+	arr := b.read_array_F()
+	h.bins = make([]float64, len(arr))
+	for i, v := range arr {
+		h.bins[i] = float64(v)
+	}
+	b.check_byte_count(pos, bcnt, spos, "TH2F")
+	return
+}
+
+func (h *H2F) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(4)
+	err = h.H2.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	arr := make([]float32, len(h.bins))
+	for i, v := range h.bins {
+		arr[i] = float32(v)
+	}
+	b.write_array_F(arr)
+	b.set_byte_count(pos)
+	return
+}
+
+// H2D is a 2-dim histogram with one double per bin (TH2D)
+type H2D struct {
+	H2
+}
+
+// NewH2D returns a new 2-dim histogram with nx (ny) bins of equal width
+// between xmin and xmax (ymin and ymax.)
+func NewH2D(name, title string, nx int, xmin, xmax float64, ny int, ymin, ymax float64) *H2D {
+	return &H2D{new_h2(name, title, nx, xmin, xmax, ny, ymin, ymax)}
+}
+
+func (h *H2D) Class() string {
+	return "TH2D"
+}
+
+func (h *H2D) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[th2d] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	err = h.H2.ROOTDecode(b)
+	if err != nil {
+		return err
+	}
+	h.bins = b.read_array_D()
+	b.check_byte_count(pos, bcnt, spos, "TH2D")
+	return
+}
+
+func (h *H2D) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(4)
+	err = h.H2.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.write_array_D(h.bins)
+	b.set_byte_count(pos)
+	return
+}
+
+func init() {
+	{
+		f := func() reflect.Value {
+			o := &H2{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TH2"] = f
+		Factory.db["*groot.H2"] = f
+	}
+
+	{
+		f := func() reflect.Value {
+			o := &H2F{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TH2F"] = f
+		Factory.db["*groot.H2F"] = f
+	}
+
+	{
+		f := func() reflect.Value {
+			o := &H2D{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TH2D"] = f
+		Factory.db["*groot.H2D"] = f
+	}
+}
+
+// check interfaces
+var _ Object = (*H2)(nil)
+var _ ROOTStreamer = (*H2)(nil)
+var _ Object = (*H2F)(nil)
+var _ ROOTStreamer = (*H2F)(nil)
+var _ Object = (*H2D)(nil)
+var _ ROOTStreamer = (*H2D)(nil)
+
+// EOF
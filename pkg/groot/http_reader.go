@@ -0,0 +1,162 @@
+package groot
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+const (
+	http_readahead  = 256 * 1024       // minimum number of bytes fetched by a request
+	http_cache_size = 64 * 1024 * 1024 // maximum number of bytes held by the cache
+	http_max_ranges = 64               // maximum number of ranges of a multi-range request
+	http_max_gap    = 4 * 1024         // ranges closer than this are merged
+)
+
+// HTTPReader reads the content of a remote file over HTTP, with range
+// requests.
+// Fetched data is held in a read-ahead cache, and the ranges of data about
+// to be read (e.g. the baskets of a branch) are fetched with multi-range
+// requests.
+type HTTPReader struct {
+	url    string
+	size   int64
+	client *http.Client
+	cache  *read_cache
+}
+
+// NewHTTPReader returns a reader of the file located at url.
+// The server must support range requests.
+func NewHTTPReader(url string) (r *HTTPReader, err error) {
+	r = &HTTPReader{
+		url:    url,
+		client: http.DefaultClient,
+		cache:  new_read_cache(http_cache_size),
+	}
+
+	resp, err := r.client.Head(url)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("groot: could not access [%s]: %s", url, resp.Status)
+	}
+	if resp.ContentLength < 0 {
+		return nil, fmt.Errorf("groot: could not retrieve the size of [%s]", url)
+	}
+	r.size = resp.ContentLength
+	return r, err
+}
+
+// Name returns the URL of the remote file
+func (r *HTTPReader) Name() string {
+	return r.url
+}
+
+// Size returns the size of the remote file
+func (r *HTTPReader) Size() int64 {
+	return r.size
+}
+
+// Close releases the data held by the cache
+func (r *HTTPReader) Close() error {
+	r.cache.reset()
+	return nil
+}
+
+// ReadAt implements io.ReaderAt.
+// Reads which are not served by the cache fetch at least http_readahead bytes.
+func (r *HTTPReader) ReadAt(p []byte, off int64) (n int, err error) {
+	return r.cache.read_at(p, off, r.size, http_readahead, r.fetch)
+}
+
+// prefetch fetches the given ranges into the cache, with as few requests as
+// possible.
+func (r *HTTPReader) prefetch(spans []span) error {
+	return r.cache.prefetch(spans, r.size, http_max_gap, http_max_ranges, r.fetch)
+}
+
+// fetch retrieves the given ranges from the server
+func (r *HTTPReader) fetch(spans []span) (chunks []read_chunk, err error) {
+	ranges := make([]string, len(spans))
+	for i, s := range spans {
+		ranges[i] = fmt.Sprintf("%d-%d", s.off, s.off+s.len-1)
+	}
+
+	req, err := http.NewRequest("GET", r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", "bytes="+strings.Join(ranges, ","))
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		// the server ignored the Range header and is sending the whole file
+		return nil, fmt.Errorf("groot: server of [%s] does not support range requests", r.url)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("groot: range request on [%s] failed: %s", r.url, resp.Status)
+	}
+
+	mtype, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mtype != "multipart/byteranges" {
+		// a single range
+		c, err := read_http_chunk(resp.Header.Get("Content-Range"), resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return []read_chunk{c}, nil
+	}
+
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		c, err := read_http_chunk(part.Header.Get("Content-Range"), part)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, nil
+}
+
+// read_http_chunk reads the data of the range described by the Content-Range
+// header hdr (e.g. "bytes 0-99/1234") from r
+func read_http_chunk(hdr string, r io.Reader) (c read_chunk, err error) {
+	var beg, end int64
+	_, err = fmt.Sscanf(hdr, "bytes %d-%d/", &beg, &end)
+	if err != nil || end < beg {
+		return c, fmt.Errorf("groot: invalid Content-Range [%s]", hdr)
+	}
+	c.off = beg
+	c.data = make([]byte, int(end-beg+1))
+	_, err = io.ReadFull(r, c.data)
+	if err != nil {
+		return c, err
+	}
+	// drain what the server may have sent in excess
+	_, err = io.Copy(ioutil.Discard, r)
+	return c, err
+}
+
+// check interfaces
+var _ io.ReaderAt = (*HTTPReader)(nil)
+var _ io.Closer = (*HTTPReader)(nil)
+var _ prefetcher = (*HTTPReader)(nil)
+
+// EOF
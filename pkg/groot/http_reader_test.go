@@ -0,0 +1,254 @@
+package groot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// new_http_data returns the content of the files served by the tests
+func new_http_data(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+// http_server serves data with http.ServeContent, which handles single and
+// multi-range requests, and records the Range header of each request.
+type http_server struct {
+	mu     sync.Mutex
+	data   []byte
+	ranges []string
+}
+
+func (srv *http_server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == "GET" {
+		srv.mu.Lock()
+		srv.ranges = append(srv.ranges, req.Header.Get("Range"))
+		srv.mu.Unlock()
+	}
+	http.ServeContent(w, req, "file.root", time.Time{}, bytes.NewReader(srv.data))
+}
+
+func (srv *http_server) requests() []string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return append([]string(nil), srv.ranges...)
+}
+
+func TestHTTPReaderReadAt(t *testing.T) {
+	data := new_http_data(3*http_readahead + 123)
+	srv := &http_server{data: data}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	r, err := NewHTTPReader(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if got, want := r.Size(), int64(len(data)); got != want {
+		t.Fatalf("got size=%d, want %d", got, want)
+	}
+
+	for _, tc := range []struct {
+		off  int64
+		n    int
+		reqs int // number of requests sent to the server so far
+	}{
+		{off: 10, n: 100, reqs: 1},
+		{off: 1000, n: 10, reqs: 1}, // served by the read-ahead cache
+		{off: http_readahead, n: 2 * http_readahead, reqs: 2},
+		{off: 2 * http_readahead, n: 100, reqs: 2},
+	} {
+		p := make([]byte, tc.n)
+		n, err := r.ReadAt(p, tc.off)
+		if err != nil {
+			t.Fatalf("off=%d: %v", tc.off, err)
+		}
+		if n != tc.n {
+			t.Fatalf("off=%d: got n=%d, want %d", tc.off, n, tc.n)
+		}
+		if !bytes.Equal(p, data[tc.off:tc.off+int64(tc.n)]) {
+			t.Fatalf("off=%d: invalid data", tc.off)
+		}
+		if got := len(srv.requests()); got != tc.reqs {
+			t.Fatalf("off=%d: got %d requests, want %d", tc.off, got, tc.reqs)
+		}
+	}
+
+	want := fmt.Sprintf("bytes=10-%d", 10+http_readahead-1)
+	if got := srv.requests()[0]; got != want {
+		t.Fatalf("got Range=%q, want %q", got, want)
+	}
+}
+
+func TestHTTPReaderEOF(t *testing.T) {
+	data := new_http_data(1000)
+	ts := httptest.NewServer(&http_server{data: data})
+	defer ts.Close()
+
+	r, err := NewHTTPReader(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	p := make([]byte, 100)
+	n, err := r.ReadAt(p, 950)
+	if err != io.EOF {
+		t.Fatalf("got err=%v, want io.EOF", err)
+	}
+	if n != 50 || !bytes.Equal(p[:n], data[950:]) {
+		t.Fatalf("got n=%d data=%v, want n=50", n, p[:n])
+	}
+
+	for _, off := range []int64{1000, 2000} {
+		n, err = r.ReadAt(p, off)
+		if n != 0 || err != io.EOF {
+			t.Fatalf("off=%d: got n=%d err=%v, want n=0 err=io.EOF", off, n, err)
+		}
+	}
+
+	_, err = r.ReadAt(p, -1)
+	if err == nil {
+		t.Fatalf("expected an error for a negative offset")
+	}
+}
+
+func TestHTTPReaderPrefetch(t *testing.T) {
+	data := new_http_data(4 * 1024 * 1024)
+	srv := &http_server{data: data}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	r, err := NewHTTPReader(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	spans := []span{
+		{off: 3000000, len: 100},
+		{off: 1000, len: 100},
+		{off: 1100 + http_max_gap/2, len: 100}, // merged with the previous one
+		{off: 2000000, len: 5000},
+		{off: int64(len(data)) - 10, len: 20}, // outside of the file
+	}
+	err = r.prefetch(spans)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := srv.requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1: %q", len(reqs), reqs)
+	}
+	want := fmt.Sprintf("bytes=1000-%d,2000000-2004999,3000000-3000099", 1100+http_max_gap/2+100-1)
+	if reqs[0] != want {
+		t.Fatalf("got Range=%q, want %q", reqs[0], want)
+	}
+
+	// the prefetched spans are served from the cache
+	for _, s := range spans[:4] {
+		p := make([]byte, s.len)
+		_, err := r.ReadAt(p, s.off)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p, data[s.off:s.off+s.len]) {
+			t.Fatalf("span %v: invalid data", s)
+		}
+	}
+	if got := len(srv.requests()); got != 1 {
+		t.Fatalf("got %d requests, want 1", got)
+	}
+
+	// nothing left to prefetch
+	err = r.prefetch(spans[:4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(srv.requests()); got != 1 {
+		t.Fatalf("got %d requests, want 1", got)
+	}
+}
+
+func TestHTTPReaderNoRange(t *testing.T) {
+	data := new_http_data(1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// ignore the Range header
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.WriteHeader(http.StatusOK)
+		if req.Method == "GET" {
+			w.Write(data)
+		}
+	}))
+	defer ts.Close()
+
+	r, err := NewHTTPReader(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	_, err = r.ReadAt(make([]byte, 10), 0)
+	if err == nil || !strings.Contains(err.Error(), "does not support range requests") {
+		t.Fatalf("got err=%v", err)
+	}
+}
+
+func TestMergeSpans(t *testing.T) {
+	for _, tc := range []struct {
+		spans []span
+		gap   int64
+		want  []span
+	}{
+		{
+			spans: nil,
+			want:  nil,
+		},
+		{
+			spans: []span{{10, 5}},
+			want:  []span{{10, 5}},
+		},
+		{
+			spans: []span{{20, 5}, {0, 5}, {10, 5}},
+			gap:   0,
+			want:  []span{{0, 5}, {10, 5}, {20, 5}},
+		},
+		{
+			spans: []span{{20, 5}, {0, 5}, {10, 5}},
+			gap:   5,
+			want:  []span{{0, 25}},
+		},
+		{
+			spans: []span{{0, 5}, {5, 5}, {20, 5}},
+			gap:   0,
+			want:  []span{{0, 10}, {20, 5}},
+		},
+		{
+			// overlapping and contained spans
+			spans: []span{{0, 10}, {2, 3}, {8, 10}, {30, 1}},
+			gap:   4,
+			want:  []span{{0, 18}, {30, 1}},
+		},
+	} {
+		got := merge_spans(tc.spans, tc.gap)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("merge_spans(gap=%d): got %v, want %v", tc.gap, got, tc.want)
+		}
+	}
+}
+
+// EOF
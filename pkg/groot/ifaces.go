@@ -46,6 +46,17 @@ type Object interface {
 	Title() string
 }
 
+// Collection represents a ROOT collection of objects
+type Collection interface {
+	Object
+
+	// Len returns the number of objects in the collection
+	Len() int
+
+	// At returns the i-th object of the collection
+	At(i int) Object
+}
+
 // ClassFactory creates ROOT classes
 type ClassFactory interface {
 	Create(name string) Class
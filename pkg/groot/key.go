@@ -1,6 +1,7 @@
 package groot
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -48,6 +49,8 @@ type Key struct {
 	class           string    // object class name
 	name            string    // name of the object
 	title           string    // title of the object
+
+	obj Object // decoded object (cached)
 }
 
 func NewKey(f *File, pos int64, nbytes uint32) (k *Key, err error) {
@@ -111,6 +114,98 @@ func (k *Key) init_from_buffer(b *Buffer) (err error) {
 	k.title = b.read_tstring()
 	printf("key-title [%v]\n", k.title)
 
+	if b.err != nil {
+		return fmt.Errorf("groot: could not read key header: %w", b.err)
+	}
+	return err
+}
+
+// new_key_for creates a new key in directory dir, holding the data
+// serialized by encode.
+// space for the key is allocated at the end of the file, but the key is not
+// written to file: see Key.write_file.
+func new_key_for(dir *Directory, name, title, class string, encode func(b *Buffer) error) (k *Key, err error) {
+	f := dir.file
+	k = &Key{
+		file:            f,
+		version:         4,
+		date:            time.Now(),
+		cycle:           dir.next_cycle(name),
+		seek_parent_dir: dir.seek_dir,
+		class:           class,
+		name:            name,
+		title:           title,
+	}
+	if f.end > g_START_BIG_FILE {
+		k.version += 1000
+	}
+	k.keysz = uint16(k.key_size())
+
+	b, err := NewWBuffer(f.order, uint32(k.keysz))
+	if err != nil {
+		return nil, err
+	}
+	err = encode(b)
+	if err != nil {
+		return nil, err
+	}
+	data := b.Bytes()
+	k.objsz = uint32(len(data))
+	k.nbytes = uint32(k.keysz) + k.objsz
+	k.seek_key = f.alloc(int64(k.nbytes))
+
+	w, err := NewWBuffer(f.order, 0)
+	if err != nil {
+		return nil, err
+	}
+	k.fill_buffer(w)
+	w.write_nbytes(data)
+	k.buffer = w.Bytes()
+	return k, err
+}
+
+// key_size returns the number of bytes needed to write the key structure
+func (k *Key) key_size() int {
+	nbytes := sz_int32 // fNbytes
+	nbytes += sz_int16 // fVersion
+	nbytes += sz_int32 // fObjlen
+	nbytes += sz_int32 // fDatime
+	nbytes += sz_int16 // fKeylen
+	nbytes += sz_int16 // fCycle
+	if k.version > 1000 {
+		nbytes += 2 * sz_int64 // fSeekKey, fSeekPdir
+	} else {
+		nbytes += 2 * sz_int32 // fSeekKey, fSeekPdir
+	}
+	nbytes += tstring_size(k.class)
+	nbytes += tstring_size(k.name)
+	nbytes += tstring_size(k.title)
+	return nbytes
+}
+
+// fill_buffer writes the key structure into the buffer
+func (k *Key) fill_buffer(b *Buffer) {
+	b.u4ton(k.nbytes)
+	b.u2ton(uint16(k.version))
+	b.u4ton(k.objsz)
+	b.u4ton(time2datime(k.date))
+	b.u2ton(k.keysz)
+	b.u2ton(k.cycle)
+	if k.version > 1000 {
+		b.i8ton(k.seek_key)
+		b.i8ton(k.seek_parent_dir)
+	} else {
+		b.i4ton(int32(k.seek_key))
+		b.i4ton(int32(k.seek_parent_dir))
+	}
+	b.write_tstring(k.class)
+	b.write_tstring(k.name)
+	b.write_tstring(k.title)
+}
+
+// write_file writes the key (and the object it holds) to file
+func (k *Key) write_file() (err error) {
+	_, err = k.file.w.WriteAt(k.buffer, k.seek_key)
 	return err
 }
 
@@ -135,6 +230,14 @@ func (k *Key) Buffer() (buf []byte, err error) {
 	printf("seek-key: %v\n", k.seek_key)
 	printf("compressed: %v\n", (k.objsz > (k.nbytes - uint32(k.keysz))))
 
+	err = k.file.check_range(k.seek_key, int64(k.nbytes))
+	if err != nil {
+		return nil, err
+	}
+	if uint32(k.keysz) > k.nbytes || int32(k.objsz) < 0 {
+		return nil, fmt.Errorf("groot: invalid key header (keysz=%d, objsz=%d, nbytes=%d)", k.keysz, k.objsz, k.nbytes)
+	}
+
 	if k.objsz <= (k.nbytes - uint32(k.keysz)) {
 		bufsz := int(k.nbytes - uint32(k.keysz))
 		if bufsz < int(k.nbytes) {
@@ -142,73 +245,133 @@ func (k *Key) Buffer() (buf []byte, err error) {
 		}
 		buf = make([]byte, bufsz)
 		printf("*** %v %v\n", len(buf), k.seek_key)
-		_, err = k.file.f.ReadAt(buf, k.seek_key)
+		err = k.file.read_at(buf, k.seek_key)
 		if err != nil {
 			return []byte{}, err
 		}
 
-		//
-		k.buffer = make([]byte, len(buf))
-		copy(k.buffer, buf)
-
 		// extract the pure object-buffer
 		buf = buf[k.keysz:]
 	} else {
 		// have to decompress
-		// size of compressed buffer
-		compsz := int(k.objsz + uint32(k.keysz))
-		compbuf := make([]byte, compsz)
+		compbuf := make([]byte, int(k.nbytes))
 
-		_, err = k.file.f.ReadAt(compbuf, k.seek_key)
+		err = k.file.read_at(compbuf, k.seek_key)
 		if err != nil {
 			return []byte{}, err
 		}
-		buf, err = unzip_root_buffer(compbuf[k.keysz:])
+		if err := check_unzip_size(compbuf[k.keysz:], int(k.objsz)); err != nil {
+			return []byte{}, fmt.Errorf("groot: invalid compressed payload for key [%s] of class [%s] (offset=%d, nbytes=%d, objsz=%d): %v",
+				k.name, k.class, k.seek_key, k.nbytes, k.objsz, err)
+		}
+		buf = make([]byte, int(k.objsz))
+		err = unzip_root_buffer(buf, compbuf[k.keysz:], k.file.unzipers)
 		if err != nil {
-			return []byte{}, err
+			return []byte{}, fmt.Errorf("groot: could not decompress key [%s] of class [%s] (offset=%d, nbytes=%d, objsz=%d): %v",
+				k.name, k.class, k.seek_key, k.nbytes, k.objsz, err)
 		}
-		//
-		k.buffer = make([]byte, 0, len(buf)+int(k.keysz))
-		k.buffer = append(k.buffer, compbuf[:k.keysz]...)
-		k.buffer = append(k.buffer, buf...)
 	}
 	return
 }
 
-func (k *Key) Value() (v interface{}) {
+// Value decodes and returns the object held by this key.
+// Value may be called concurrently: all callers get the same object.
+func (k *Key) Value() (v interface{}, err error) {
+	k.file.mu.Lock()
+	obj := k.obj
+	k.file.mu.Unlock()
+	if obj != nil {
+		return obj, err
+	}
+
+	v, err = k.decode()
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(Object)
+	if !ok {
+		return v, err
+	}
+	if dir, ok := obj.(*Directory); ok {
+		// the name of a sub-directory is only held by its key
+		dir.name = k.name
+		dir.title = k.title
+	}
+
+	k.file.mu.Lock()
+	defer k.file.mu.Unlock()
+	if k.obj == nil {
+		k.obj = obj
+	}
+	return k.obj, err
+}
+
+// Object decodes and returns the object held by this key.
+// The object is decoded only once: subsequent calls return the same value.
+func (k *Key) Object() (obj Object, err error) {
+	v, err := k.Value()
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(Object)
+	if !ok {
+		return nil, k.errorf(fmt.Errorf("type %T does not implement groot.Object", v))
+	}
+	return obj, err
+}
+
+// decode decodes the object held by this key from its buffer
+func (k *Key) decode() (v interface{}, err error) {
 	factory := Factory.Get(k.Class())
+	if factory == nil && k.file.find_streamer_info(k.Class(), -1) != nil {
+		// decode the object from the streamer info of its class
+		factory = generic_factory(k.Class())
+	}
 	if factory == nil {
-		printf("**err** no factory for class [%s]\n", k.Class())
-		return v
+		return nil, k.errorf(fmt.Errorf("no factory for class [%s]", k.Class()))
 	}
 
 	vv := factory()
 	if vv, ok := vv.Interface().(FileSetter); ok {
-		err := vv.SetFile(k.file)
+		err = vv.SetFile(k.file)
 		if err != nil {
-			return v
+			return nil, k.errorf(err)
 		}
 	}
 	if vv, ok := vv.Interface().(ROOTStreamer); ok {
 		buf, err := NewBufferFromKey(k)
 		if err != nil {
-			return v
+			return nil, k.errorf(err)
 		}
 		err = vv.ROOTDecode(buf)
+		if err == nil {
+			err = buf.Err()
+		}
 		if err != nil {
-			return v
+			return nil, k.errorf(err)
 		}
 	} else {
 		dprintf("**err** class [%s] does not satisfy the ROOTStreamer interface\n", k.Class())
 	}
 	v = vv.Interface()
-	return v
+	return v, err
+}
+
+// errorf wraps err with the name, class and location on file of this key
+func (k *Key) errorf(err error) error {
+	return fmt.Errorf("groot: could not decode key [%s;%d] of class [%s] (offset=%d): %w",
+		k.name, k.cycle, k.class, k.seek_key, err)
 }
 
 func (k *Key) Size() uint32 {
 	return k.objsz
 }
 
+// Cycle returns the cycle number of this key
+func (k *Key) Cycle() int {
+	return int(k.cycle)
+}
+
 func (k *Key) Class() string {
 	return k.class
 }
@@ -0,0 +1,98 @@
+package groot
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// create_dir_file creates a file holding the TObjStrings:
+//   - "h;1" and "h;2", at the top-level,
+//   - "dir/h" and "dir/sub/h", in sub-directories.
+func create_dir_file(t *testing.T) *File {
+	fname := filepath.Join(t.TempDir(), "dirs.root")
+	{
+		f, err := Create(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		put := func(dir *Directory, name, value string) {
+			err := dir.Put(name, NewObjString(value))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		put(f.Dir(), "h", "v1")
+		put(f.Dir(), "h", "v2")
+		dir, err := f.Dir().Mkdir("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		put(dir, "h", "dir")
+		sub, err := dir.Mkdir("sub")
+		if err != nil {
+			t.Fatal(err)
+		}
+		put(sub, "h", "sub")
+		err = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestKeyObject(t *testing.T) {
+	f := create_dir_file(t)
+	key := &f.Dir().Keys()[0]
+	if key.Name() != "h" || key.Cycle() != 1 || key.Class() != "TObjString" {
+		t.Fatalf("got key name=%s cycle=%d class=%s", key.Name(), key.Cycle(), key.Class())
+	}
+
+	// the object is decoded once, whatever the number of callers
+	objs := make([]Object, 8)
+	var wg sync.WaitGroup
+	for i := range objs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			obj, err := key.Object()
+			if err != nil {
+				t.Error(err)
+			}
+			objs[i] = obj
+		}(i)
+	}
+	wg.Wait()
+	for i, obj := range objs {
+		if obj != objs[0] {
+			t.Errorf("object #%d: got %p, want %p", i, obj, objs[0])
+		}
+	}
+	if got := objs[0].(*ObjString).String(); got != "v1" {
+		t.Errorf("got %q, want %q", got, "v1")
+	}
+	if v, err := key.Value(); err != nil || v != objs[0] {
+		t.Errorf("got value %p (err=%v), want %p", v, err, objs[0])
+	}
+
+	// errors are not cached
+	bad := f.Dir().Keys()[1]
+	bad.class = "Unknown"
+	for i := 0; i < 2; i++ {
+		_, err := bad.Object()
+		want := "groot: could not decode key [h;2] of class [Unknown]"
+		if err == nil || !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), "no factory for class [Unknown]") {
+			t.Errorf("call #%d: got err=%v, want %q", i, err, want)
+		}
+	}
+}
+
+// EOF
@@ -10,7 +10,6 @@ type LeafB struct {
 	base baseLeaf
 	min  byte
 	max  byte
-	data []byte
 }
 
 func (leaf *LeafB) toBaseLeaf() *baseLeaf {
@@ -39,15 +38,46 @@ func (leaf *LeafB) ROOTDecode(b *Buffer) (err error) {
 	}
 	leaf.min = b.ntobyte()
 	leaf.max = b.ntobyte()
-	leaf.data = make([]byte, int(leaf.base.length))
-	printf("leafI min=%v max=%v len=%d\n", leaf.min, leaf.max, len(leaf.data))
+	printf("leafI min=%v max=%v len=%d\n", leaf.min, leaf.max, leaf.base.length)
 	b.check_byte_count(pos, bcnt, spos, "LeafB")
 	return
 }
 
 func (leaf *LeafB) ROOTEncode(b *Buffer) (err error) {
-	//FIXME
-	panic("not implemented")
+	pos := b.write_version(1)
+	err = leaf.base.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.byteton(leaf.min)
+	b.byteton(leaf.max)
+	b.set_byte_count(pos)
+	return
+}
+
+// read_basket reads UChar_t values into a []uint8 if the leaf is unsigned,
+// Char_t values into a []int8 otherwise.
+func (leaf *LeafB) read_basket(b *Buffer, n int, data interface{}) (interface{}, error) {
+	if leaf.base.unsigned {
+		v, _ := data.([]uint8)
+		if cap(v) < n {
+			v = make([]uint8, n)
+		}
+		v = v[:n]
+		for i := range v {
+			v[i] = b.ntobyte()
+		}
+		return v, b.err
+	}
+	v, _ := data.([]int8)
+	if cap(v) < n {
+		v = make([]int8, n)
+	}
+	v = v[:n]
+	for i := range v {
+		v[i] = int8(b.ntobyte())
+	}
+	return v, b.err
 }
 
 // leaf of shorts
@@ -56,7 +86,6 @@ type LeafS struct {
 	base baseLeaf
 	min  int16
 	max  int16
-	data []int16
 }
 
 func (leaf *LeafS) toBaseLeaf() *baseLeaf {
@@ -85,15 +114,46 @@ func (leaf *LeafS) ROOTDecode(b *Buffer) (err error) {
 	}
 	leaf.min = b.ntoi2()
 	leaf.max = b.ntoi2()
-	leaf.data = make([]int16, int(leaf.base.length))
-	printf("leafI min=%v max=%v len=%d\n", leaf.min, leaf.max, len(leaf.data))
+	printf("leafI min=%v max=%v len=%d\n", leaf.min, leaf.max, leaf.base.length)
 	b.check_byte_count(pos, bcnt, spos, "LeafS")
 	return
 }
 
 func (leaf *LeafS) ROOTEncode(b *Buffer) (err error) {
-	//FIXME
-	panic("not implemented")
+	pos := b.write_version(1)
+	err = leaf.base.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.i2ton(leaf.min)
+	b.i2ton(leaf.max)
+	b.set_byte_count(pos)
+	return
+}
+
+// read_basket reads UShort_t values into a []uint16 if the leaf is unsigned,
+// Short_t values into a []int16 otherwise.
+func (leaf *LeafS) read_basket(b *Buffer, n int, data interface{}) (interface{}, error) {
+	if leaf.base.unsigned {
+		v, _ := data.([]uint16)
+		if cap(v) < n {
+			v = make([]uint16, n)
+		}
+		v = v[:n]
+		for i := range v {
+			v[i] = b.ntou2()
+		}
+		return v, b.err
+	}
+	v, _ := data.([]int16)
+	if cap(v) < n {
+		v = make([]int16, n)
+	}
+	v = v[:n]
+	for i := range v {
+		v[i] = b.ntoi2()
+	}
+	return v, b.err
 }
 
 // leaf of ints
@@ -102,7 +162,6 @@ type LeafI struct {
 	base baseLeaf
 	min  int32
 	max  int32
-	data []int
 }
 
 func (leaf *LeafI) toBaseLeaf() *baseLeaf {
@@ -131,15 +190,46 @@ func (leaf *LeafI) ROOTDecode(b *Buffer) (err error) {
 	}
 	leaf.min = b.ntoi4()
 	leaf.max = b.ntoi4()
-	leaf.data = make([]int, int(leaf.base.length))
-	printf("leafI min=%v max=%v len=%d\n", leaf.min, leaf.max, len(leaf.data))
+	printf("leafI min=%v max=%v len=%d\n", leaf.min, leaf.max, leaf.base.length)
 	b.check_byte_count(pos, bcnt, spos, "LeafI")
 	return
 }
 
 func (leaf *LeafI) ROOTEncode(b *Buffer) (err error) {
-	//FIXME
-	panic("not implemented")
+	pos := b.write_version(1)
+	err = leaf.base.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.i4ton(leaf.min)
+	b.i4ton(leaf.max)
+	b.set_byte_count(pos)
+	return
+}
+
+// read_basket reads UInt_t values into a []uint32 if the leaf is unsigned,
+// Int_t values into a []int32 otherwise.
+func (leaf *LeafI) read_basket(b *Buffer, n int, data interface{}) (interface{}, error) {
+	if leaf.base.unsigned {
+		v, _ := data.([]uint32)
+		if cap(v) < n {
+			v = make([]uint32, n)
+		}
+		v = v[:n]
+		for i := range v {
+			v[i] = b.ntou4()
+		}
+		return v, b.err
+	}
+	v, _ := data.([]int32)
+	if cap(v) < n {
+		v = make([]int32, n)
+	}
+	v = v[:n]
+	for i := range v {
+		v[i] = b.ntoi4()
+	}
+	return v, b.err
 }
 
 // leaf of ints-64
@@ -148,7 +238,6 @@ type LeafL struct {
 	base baseLeaf
 	min  int64
 	max  int64
-	data []int64
 }
 
 func (leaf *LeafL) toBaseLeaf() *baseLeaf {
@@ -177,15 +266,46 @@ func (leaf *LeafL) ROOTDecode(b *Buffer) (err error) {
 	}
 	leaf.min = b.ntoi8()
 	leaf.max = b.ntoi8()
-	leaf.data = make([]int64, int(leaf.base.length))
-	printf("leafL min=%v max=%v len=%d\n", leaf.min, leaf.max, len(leaf.data))
+	printf("leafL min=%v max=%v len=%d\n", leaf.min, leaf.max, leaf.base.length)
 	b.check_byte_count(pos, bcnt, spos, "LeafL")
 	return
 }
 
 func (leaf *LeafL) ROOTEncode(b *Buffer) (err error) {
-	//FIXME
-	panic("not implemented")
+	pos := b.write_version(1)
+	err = leaf.base.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.i8ton(leaf.min)
+	b.i8ton(leaf.max)
+	b.set_byte_count(pos)
+	return
+}
+
+// read_basket reads ULong64_t values into a []uint64 if the leaf is unsigned,
+// Long64_t values into a []int64 otherwise.
+func (leaf *LeafL) read_basket(b *Buffer, n int, data interface{}) (interface{}, error) {
+	if leaf.base.unsigned {
+		v, _ := data.([]uint64)
+		if cap(v) < n {
+			v = make([]uint64, n)
+		}
+		v = v[:n]
+		for i := range v {
+			v[i] = b.ntou8()
+		}
+		return v, b.err
+	}
+	v, _ := data.([]int64)
+	if cap(v) < n {
+		v = make([]int64, n)
+	}
+	v = v[:n]
+	for i := range v {
+		v[i] = b.ntoi8()
+	}
+	return v, b.err
 }
 
 // leaf of floats
@@ -194,7 +314,6 @@ type LeafF struct {
 	base baseLeaf
 	min  float32
 	max  float32
-	data []float32
 }
 
 func (leaf *LeafF) toBaseLeaf() *baseLeaf {
@@ -223,15 +342,33 @@ func (leaf *LeafF) ROOTDecode(b *Buffer) (err error) {
 	}
 	leaf.min = b.ntof()
 	leaf.max = b.ntof()
-	leaf.data = make([]float32, int(leaf.base.length))
-	printf("leafF min=%v max=%v len=%d\n", leaf.min, leaf.max, len(leaf.data))
+	printf("leafF min=%v max=%v len=%d\n", leaf.min, leaf.max, leaf.base.length)
 	b.check_byte_count(pos, bcnt, spos, "LeafF")
 	return
 }
 
 func (leaf *LeafF) ROOTEncode(b *Buffer) (err error) {
-	//FIXME
-	panic("not implemented")
+	pos := b.write_version(1)
+	err = leaf.base.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.fton(leaf.min)
+	b.fton(leaf.max)
+	b.set_byte_count(pos)
+	return
+}
+
+func (leaf *LeafF) read_basket(b *Buffer, n int, data interface{}) (interface{}, error) {
+	v, _ := data.([]float32)
+	if cap(v) < n {
+		v = make([]float32, n)
+	}
+	v = v[:n]
+	for i := range v {
+		v[i] = b.ntof()
+	}
+	return v, b.err
 }
 
 // leaf of doubles
@@ -240,7 +377,6 @@ type LeafD struct {
 	base baseLeaf
 	min  float64
 	max  float64
-	data []float64
 }
 
 func (leaf *LeafD) toBaseLeaf() *baseLeaf {
@@ -269,15 +405,33 @@ func (leaf *LeafD) ROOTDecode(b *Buffer) (err error) {
 	}
 	leaf.min = b.ntod()
 	leaf.max = b.ntod()
-	leaf.data = make([]float64, int(leaf.base.length))
-	printf("leafD min=%v max=%v len=%d\n", leaf.min, leaf.max, len(leaf.data))
+	printf("leafD min=%v max=%v len=%d\n", leaf.min, leaf.max, leaf.base.length)
 	b.check_byte_count(pos, bcnt, spos, "LeafD")
 	return
 }
 
 func (leaf *LeafD) ROOTEncode(b *Buffer) (err error) {
-	//FIXME
-	panic("not implemented")
+	pos := b.write_version(1)
+	err = leaf.base.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.dton(leaf.min)
+	b.dton(leaf.max)
+	b.set_byte_count(pos)
+	return
+}
+
+func (leaf *LeafD) read_basket(b *Buffer, n int, data interface{}) (interface{}, error) {
+	v, _ := data.([]float64)
+	if cap(v) < n {
+		v = make([]float64, n)
+	}
+	v = v[:n]
+	for i := range v {
+		v[i] = b.ntod()
+	}
+	return v, b.err
 }
 
 // leaf of a string
@@ -286,7 +440,6 @@ type LeafC struct {
 	base baseLeaf
 	min  int32
 	max  int32
-	data string
 }
 
 func (leaf *LeafC) toBaseLeaf() *baseLeaf {
@@ -315,14 +468,30 @@ func (leaf *LeafC) ROOTDecode(b *Buffer) (err error) {
 	}
 	leaf.min = b.ntoi4()
 	leaf.max = b.ntoi4()
-	printf("leafC min=%v max=%v len=%d\n", leaf.min, leaf.max, len(leaf.data))
+	printf("leafC min=%v max=%v len=%d\n", leaf.min, leaf.max, leaf.base.length)
 	b.check_byte_count(pos, bcnt, spos, "LeafC")
 	return
 }
 
 func (leaf *LeafC) ROOTEncode(b *Buffer) (err error) {
-	//FIXME
-	panic("not implemented")
+	pos := b.write_version(1)
+	err = leaf.base.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.i4ton(leaf.min)
+	b.i4ton(leaf.max)
+	b.set_byte_count(pos)
+	return
+}
+
+func (leaf *LeafC) read_basket(b *Buffer, n int, data interface{}) (interface{}, error) {
+	// the string is prefixed by its length (see TLeafC::ReadBasket)
+	sz := int(b.ntobyte())
+	if sz == 255 {
+		sz = int(b.ntoi4())
+	}
+	return string(b.read_nbytes(sz)), b.err
 }
 
 // leaf of bool
@@ -331,7 +500,6 @@ type LeafO struct {
 	base baseLeaf
 	min  bool
 	max  bool
-	data []bool
 }
 
 func (leaf *LeafO) toBaseLeaf() *baseLeaf {
@@ -360,15 +528,33 @@ func (leaf *LeafO) ROOTDecode(b *Buffer) (err error) {
 	}
 	leaf.min = b.read_bool()
 	leaf.max = b.read_bool()
-	leaf.data = make([]bool, int(leaf.base.length))
-	printf("leafO min=%v max=%v len=%d\n", leaf.min, leaf.max, len(leaf.data))
+	printf("leafO min=%v max=%v len=%d\n", leaf.min, leaf.max, leaf.base.length)
 	b.check_byte_count(pos, bcnt, spos, "LeafO")
 	return
 }
 
 func (leaf *LeafO) ROOTEncode(b *Buffer) (err error) {
-	//FIXME
-	panic("not implemented")
+	pos := b.write_version(1)
+	err = leaf.base.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.write_bool(leaf.min)
+	b.write_bool(leaf.max)
+	b.set_byte_count(pos)
+	return
+}
+
+func (leaf *LeafO) read_basket(b *Buffer, n int, data interface{}) (interface{}, error) {
+	v, _ := data.([]bool)
+	if cap(v) < n {
+		v = make([]bool, n)
+	}
+	v = v[:n]
+	for i := range v {
+		v[i] = b.read_bool()
+	}
+	return v, b.err
 }
 
 func init() {
@@ -449,26 +635,34 @@ func init() {
 // check interfaces
 var _ Object = (*LeafO)(nil)
 var _ ROOTStreamer = (*LeafO)(nil)
+var _ ileaf = (*LeafO)(nil)
 
 var _ Object = (*LeafB)(nil)
 var _ ROOTStreamer = (*LeafB)(nil)
+var _ ileaf = (*LeafB)(nil)
 
 var _ Object = (*LeafS)(nil)
 var _ ROOTStreamer = (*LeafS)(nil)
+var _ ileaf = (*LeafS)(nil)
 
 var _ Object = (*LeafI)(nil)
 var _ ROOTStreamer = (*LeafI)(nil)
+var _ ileaf = (*LeafI)(nil)
 
 var _ Object = (*LeafL)(nil)
 var _ ROOTStreamer = (*LeafL)(nil)
+var _ ileaf = (*LeafL)(nil)
 
 var _ Object = (*LeafF)(nil)
 var _ ROOTStreamer = (*LeafF)(nil)
+var _ ileaf = (*LeafF)(nil)
 
 var _ Object = (*LeafD)(nil)
 var _ ROOTStreamer = (*LeafD)(nil)
+var _ ileaf = (*LeafD)(nil)
 
 var _ Object = (*LeafC)(nil)
 var _ ROOTStreamer = (*LeafC)(nil)
+var _ ileaf = (*LeafC)(nil)
 
 // EOF
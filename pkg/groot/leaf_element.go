@@ -1,6 +1,7 @@
 package groot
 
 import (
+	"fmt"
 	"reflect"
 )
 
@@ -49,6 +50,10 @@ func (le *LeafElement) ROOTEncode(b *Buffer) (err error) {
 	return
 }
 
+func (le *LeafElement) read_basket(b *Buffer, n int, data interface{}) (interface{}, error) {
+	return nil, fmt.Errorf("groot: reading TLeafElement [%s] is not supported", le.Name())
+}
+
 func init() {
 	f := func() reflect.Value {
 		o := &LeafElement{}
@@ -61,3 +66,4 @@ func init() {
 // check interfaces
 var _ Object = (*LeafElement)(nil)
 var _ ROOTStreamer = (*LeafElement)(nil)
+var _ ileaf = (*LeafElement)(nil)
@@ -0,0 +1,191 @@
+package groot
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLeafCountName(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		want  string
+	}{
+		{"e", ""},
+		{"e[n]", "n"},
+		{"e[n][3]", "n"},
+		{"e[3]", ""},
+		{"e[3][n]", ""},
+		{"e[n", ""},
+	} {
+		base := baseLeaf{title: tc.title}
+		if got := base.count_name(); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.title, got, tc.want)
+		}
+	}
+}
+
+// create_count_tree creates a file holding the tree "tree", whose branch "e"
+// is read as a variable-length array of "n" values.
+// the tree writer only writes fixed-size arrays: e is written with 4 values
+// per entry, and turned into "e[n]" once read back.
+func create_count_tree(t *testing.T, nevts int) *Tree {
+	fname := filepath.Join(t.TempDir(), "count.root")
+	{
+		f, err := Create(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewTreeWriter(f.Dir(), "tree", "my tree")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			n int32
+			e [4]float32
+		)
+		for _, br := range []struct {
+			name string
+			ptr  interface{}
+		}{{"n", &n}, {"e", &e}} {
+			err = w.Branch(br.name, br.ptr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := 0; i < nevts; i++ {
+			n = int32(i % 5)
+			for j := range e {
+				e[j] = float32(10*i + j)
+			}
+			err = w.Fill()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	obj, err := f.Get("tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := obj.(*Tree)
+	_, leaf := tree.find_leaf("e")
+	base := leaf.toBaseLeaf()
+	base.title = "e[n]"
+	base.length = 1
+	tree.attach_leaf_counts()
+	return tree
+}
+
+func TestLeafCount(t *testing.T) {
+	const nevts = 100
+	tree := create_count_tree(t, nevts)
+	_, leaf := tree.find_leaf("e")
+	if lc := leaf.toBaseLeaf().leaf_count; lc == nil || lc.Name() != "n" {
+		t.Fatalf("got leaf-count %v", lc)
+	}
+
+	// with and without the branch of the leaf-count
+	for _, branches := range [][]string{{"n", "e"}, {"e"}} {
+		r, err := tree.NewReader(branches...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for r.Next() {
+			i := r.Cur()
+			e, ok := r.Value("e").([]float32)
+			if !ok {
+				t.Fatalf("%v: entry %d: got %#v", branches, i, r.Value("e"))
+			}
+			if len(e) != int(i%5) {
+				t.Fatalf("%v: entry %d: got %d values, want %d", branches, i, len(e), i%5)
+			}
+			for j, v := range e {
+				if want := float32(10*int(i) + j); v != want {
+					t.Errorf("%v: entry %d: e[%d]: got %v, want %v", branches, i, j, v, want)
+				}
+			}
+		}
+		if err := r.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLeafEntryOffsets(t *testing.T) {
+	const nevts = 2000
+	str := func(i int) string {
+		return strings.Repeat(fmt.Sprint(i%10), i%37)
+	}
+
+	fname := filepath.Join(t.TempDir(), "strings.root")
+	{
+		f, err := Create(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewTreeWriter(f.Dir(), "tree", "my tree")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.SetBasketSize(1024)
+		var s string
+		err = w.Branch("str", &s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < nevts; i++ {
+			s = str(i)
+			err = w.Fill()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	obj, err := f.Get("tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := obj.(*Tree)
+	if n := len(tree.Branch("str").basketEntry); n < 10 {
+		t.Fatalf("got %d baskets", n)
+	}
+
+	r, err := tree.NewReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// backwards, to look up the offsets of each basket out of order
+	for i := int64(nevts - 1); i >= 0; i-- {
+		err = r.Entry(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := r.Value("str"), str(int(i)); got != want {
+			t.Fatalf("entry %d: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+// EOF
@@ -21,6 +21,16 @@ func (lst *List) Title() string {
 	return "list-title"
 }
 
+// Len returns the number of objects in the list
+func (lst *List) Len() int {
+	return len(lst.elmts)
+}
+
+// At returns the i-th object of the list
+func (lst *List) At(i int) Object {
+	return lst.elmts[i]
+}
+
 func (lst *List) ROOTDecode(b *Buffer) (err error) {
 	spos := b.Pos()
 	lst.elmts = make([]Object, 0)
@@ -32,15 +42,13 @@ func (lst *List) ROOTDecode(b *Buffer) (err error) {
 		err = fmt.Errorf("sorry, too old version of TList (%d)", vers)
 		return
 	}
-	id := b.ntou4()
-	bits := b.ntou4()
-	bits |= kIsOnHeap // by definition de-serialized object is on heap
-	if (bits & kIsReferenced) == 0 {
-		_ = b.read_nbytes(2)
-	}
+	id, bits := b.read_tobject()
 
 	name := b.read_tstring()
 	nobjs := int(b.ntoi4())
+	if !b.check_len(nobjs, 4) {
+		return b.err
+	}
 	lst.elmts = make([]Object, 0, nobjs)
 
 	printf("id=%v bits=%v\n", id, bits)
@@ -64,7 +72,19 @@ func (lst *List) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (lst *List) ROOTEncode(b *Buffer) (err error) {
-	panic("groot.List.ROOTEncode not implemented")
+	pos := b.write_version(5)
+	b.write_tobject(0, 0)
+	b.write_tstring("") // name
+	b.i4ton(int32(len(lst.elmts)))
+	for _, obj := range lst.elmts {
+		err = b.write_object(obj)
+		if err != nil {
+			return err
+		}
+		b.write_tstring("") // option
+	}
+	b.set_byte_count(pos)
+	return err
 }
 
 /*
@@ -133,6 +153,7 @@ func init() {
 
 // check interfaces
 var _ Object = (*List)(nil)
+var _ Collection = (*List)(nil)
 var _ ROOTStreamer = (*List)(nil)
 
 // EOF
@@ -0,0 +1,157 @@
+package groot
+
+import (
+	"reflect"
+)
+
+// Pair is a (key, value) pair of a TMap (TPair)
+type Pair struct {
+	key   Object
+	value Object
+}
+
+func (p *Pair) Class() string {
+	return "TPair"
+}
+
+// Name returns the name of the key of the pair
+func (p *Pair) Name() string {
+	if p.key == nil {
+		return ""
+	}
+	return p.key.Name()
+}
+
+func (p *Pair) Title() string {
+	return "Pair of objects"
+}
+
+// Key returns the key of the pair
+func (p *Pair) Key() Object {
+	return p.key
+}
+
+// Value returns the value of the pair
+func (p *Pair) Value() Object {
+	return p.value
+}
+
+// Map is an associative array of objects (TMap).
+// The pairs are kept in their streaming order.
+type Map struct {
+	name  string
+	pairs []Pair
+}
+
+// NewMap returns a new, empty, map
+func NewMap(name string) *Map {
+	return &Map{name: name}
+}
+
+func (m *Map) Class() string {
+	return "TMap"
+}
+
+func (m *Map) Name() string {
+	return m.name
+}
+
+func (m *Map) Title() string {
+	return "A (key,value) map"
+}
+
+// Len returns the number of pairs in the map
+func (m *Map) Len() int {
+	return len(m.pairs)
+}
+
+// At returns the i-th pair of the map
+func (m *Map) At(i int) Object {
+	return &m.pairs[i]
+}
+
+// Pairs returns the (key, value) pairs of the map
+func (m *Map) Pairs() []Pair {
+	return m.pairs
+}
+
+// Get returns the value whose key is named name, or nil
+func (m *Map) Get(name string) Object {
+	for i := range m.pairs {
+		if m.pairs[i].Name() == name {
+			return m.pairs[i].value
+		}
+	}
+	return nil
+}
+
+// Add adds the (key, value) pair to the map
+func (m *Map) Add(key, value Object) {
+	m.pairs = append(m.pairs, Pair{key: key, value: value})
+}
+
+// TMap does not stream its TPairs: the keys and values are streamed one
+// after the other.
+// see TMap::Streamer
+
+func (m *Map) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[map] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	b.read_tobject()
+	if vers > 1 {
+		m.name = b.read_tstring()
+	}
+
+	nobjs := int(b.ntoi4())
+	if !b.check_len(nobjs, 8) {
+		return b.err
+	}
+	m.pairs = make([]Pair, 0, nobjs)
+	for i := 0; i < nobjs; i++ {
+		key := b.read_object()
+		value := b.read_object()
+		if key != nil {
+			m.pairs = append(m.pairs, Pair{key: key, value: value})
+		}
+	}
+
+	b.check_byte_count(pos, bcnt, spos, "TMap")
+	return
+}
+
+func (m *Map) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(3)
+	b.write_tobject(0, 0)
+	b.write_tstring(m.name)
+	b.i4ton(int32(len(m.pairs)))
+	for _, p := range m.pairs {
+		err = b.write_object(p.key)
+		if err != nil {
+			return err
+		}
+		err = b.write_object(p.value)
+		if err != nil {
+			return err
+		}
+	}
+	b.set_byte_count(pos)
+	return
+}
+
+func init() {
+	f := func() reflect.Value {
+		o := &Map{}
+		return reflect.ValueOf(o)
+	}
+	Factory.db["TMap"] = f
+	Factory.db["*groot.Map"] = f
+}
+
+// check interfaces
+var _ Object = (*Pair)(nil)
+var _ Object = (*Map)(nil)
+var _ Collection = (*Map)(nil)
+var _ ROOTStreamer = (*Map)(nil)
+
+// EOF
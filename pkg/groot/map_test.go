@@ -0,0 +1,45 @@
+package groot
+
+import (
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	want := NewMap("m")
+	val := NewObjString("value")
+	want.Add(NewObjString("k1"), val)
+	want.Add(NewObjString("k2"), nil)
+	want.Add(NewObjString("k3"), NewObjArray("arr", NewObjString("x")))
+	want.Add(NewObjString("k4"), val)
+
+	var got Map
+	write_read(t, want, &got)
+	if got.Name() != "m" || got.Len() != 4 {
+		t.Fatalf("got name=%q len=%d", got.Name(), got.Len())
+	}
+	for i, p := range got.Pairs() {
+		if k := want.pairs[i].key.Name(); p.Name() != k {
+			t.Errorf("pair #%d: got key %q, want %q", i, p.Name(), k)
+		}
+	}
+	if v, ok := got.Get("k1").(*ObjString); !ok || v.String() != "value" {
+		t.Errorf("k1: got %#v", got.Get("k1"))
+	}
+	if v := got.Get("k2"); v != nil {
+		t.Errorf("k2: got %#v, want nil", v)
+	}
+	if v, ok := got.Get("k3").(*ObjArray); !ok || v.Len() != 1 {
+		t.Errorf("k3: got %#v", got.Get("k3"))
+	}
+	if got.Get("k1") != got.Get("k4") {
+		t.Errorf("the same value was read twice")
+	}
+	if v := got.Get("k5"); v != nil {
+		t.Errorf("k5: got %#v, want nil", v)
+	}
+	if p, ok := got.At(0).(*Pair); !ok || p.Key().Name() != "k1" || p.Value() != got.Get("k1") {
+		t.Errorf("pair #0: got %#v", got.At(0))
+	}
+}
+
+// EOF
@@ -0,0 +1,111 @@
+package groot
+
+import (
+	"reflect"
+)
+
+// ObjArray is an array of objects (TObjArray)
+type ObjArray struct {
+	name   string
+	lbound int32 // lower bound of the array
+	elmts  []Object
+}
+
+// NewObjArray returns a new array holding the given objects
+func NewObjArray(name string, elmts ...Object) *ObjArray {
+	return &ObjArray{name: name, elmts: elmts}
+}
+
+func (arr *ObjArray) Class() string {
+	return "TObjArray"
+}
+
+func (arr *ObjArray) Name() string {
+	return arr.name
+}
+
+func (arr *ObjArray) Title() string {
+	return "An array of objects"
+}
+
+// Len returns the number of objects in the array
+func (arr *ObjArray) Len() int {
+	return len(arr.elmts)
+}
+
+// At returns the i-th object of the array (nil for an empty slot)
+func (arr *ObjArray) At(i int) Object {
+	return arr.elmts[i]
+}
+
+// Elements returns the objects of the array
+func (arr *ObjArray) Elements() []Object {
+	return arr.elmts
+}
+
+// LowerBound returns the lower bound of the array
+func (arr *ObjArray) LowerBound() int {
+	return int(arr.lbound)
+}
+
+func (arr *ObjArray) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	if vers > 2 {
+		b.read_tobject()
+	}
+	if vers > 1 {
+		arr.name = b.read_tstring()
+	}
+
+	nobjs := int(b.ntoi4())
+	arr.lbound = b.ntoi4()
+	if !b.check_len(nobjs, 4) {
+		return b.err
+	}
+
+	printf("[obj-array] vers=%v pos=%v bcnt=%v name='%v' nobjs=%v lbound=%v\n",
+		vers, pos, bcnt, arr.name, nobjs, arr.lbound)
+
+	arr.elmts = make([]Object, 0, nobjs)
+	for i := 0; i < nobjs; i++ {
+		printf("[obj-array] %d/%d...\n", i, nobjs)
+		obj := b.read_object()
+		arr.elmts = append(arr.elmts, obj)
+	}
+
+	b.check_byte_count(pos, bcnt, spos, "TObjArray")
+	return
+}
+
+func (arr *ObjArray) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(3)
+	b.write_tobject(0, 0)
+	b.write_tstring(arr.name)
+	b.i4ton(int32(len(arr.elmts)))
+	b.i4ton(arr.lbound)
+	for _, elmt := range arr.elmts {
+		err = b.write_object(elmt)
+		if err != nil {
+			return err
+		}
+	}
+	b.set_byte_count(pos)
+	return
+}
+
+func init() {
+	f := func() reflect.Value {
+		o := &ObjArray{}
+		return reflect.ValueOf(o)
+	}
+	Factory.db["TObjArray"] = f
+	Factory.db["*groot.ObjArray"] = f
+}
+
+// check interfaces
+var _ Object = (*ObjArray)(nil)
+var _ Collection = (*ObjArray)(nil)
+var _ ROOTStreamer = (*ObjArray)(nil)
+
+// EOF
@@ -0,0 +1,37 @@
+package groot
+
+import (
+	"testing"
+)
+
+func TestObjArray(t *testing.T) {
+	str := NewObjString("a")
+	want := NewObjArray("arr", str, nil, NewObjString("b"), str, NewObjArray("sub", NewObjString("c")))
+	want.lbound = 2
+
+	var got ObjArray
+	write_read(t, want, &got)
+	if got.Name() != "arr" || got.Len() != want.Len() || got.LowerBound() != 2 {
+		t.Fatalf("got name=%q len=%d lbound=%d", got.Name(), got.Len(), got.LowerBound())
+	}
+	for i, s := range []string{"a", "", "b", "a"} {
+		if s == "" {
+			if got.At(i) != nil {
+				t.Errorf("elmt #%d: got %v, want nil", i, got.At(i))
+			}
+			continue
+		}
+		if o, ok := got.At(i).(*ObjString); !ok || o.String() != s {
+			t.Errorf("elmt #%d: got %#v, want %q", i, got.At(i), s)
+		}
+	}
+	if got.At(0) != got.At(3) {
+		t.Errorf("the same object was read twice")
+	}
+	sub, ok := got.At(4).(*ObjArray)
+	if !ok || sub.Name() != "sub" || sub.Len() != 1 || sub.At(0).(*ObjString).String() != "c" {
+		t.Errorf("elmt #4: got %#v", got.At(4))
+	}
+}
+
+// EOF
@@ -0,0 +1,66 @@
+package groot
+
+import (
+	"reflect"
+)
+
+// ObjString is a string wrapped into an object (TObjString)
+type ObjString struct {
+	str string
+}
+
+// NewObjString returns a new TObjString holding s
+func NewObjString(s string) *ObjString {
+	return &ObjString{str: s}
+}
+
+func (obj *ObjString) Class() string {
+	return "TObjString"
+}
+
+// Name returns the string held by the object, as ROOT does
+func (obj *ObjString) Name() string {
+	return obj.str
+}
+
+func (obj *ObjString) Title() string {
+	return "Collectable string class"
+}
+
+// String returns the string held by the object
+func (obj *ObjString) String() string {
+	return obj.str
+}
+
+func (obj *ObjString) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+	vers, pos, bcnt := b.read_version()
+	printf("[obj-string] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	b.read_tobject()
+	obj.str = b.read_tstring()
+	b.check_byte_count(pos, bcnt, spos, "TObjString")
+	return
+}
+
+func (obj *ObjString) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(1)
+	b.write_tobject(0, 0)
+	b.write_tstring(obj.str)
+	b.set_byte_count(pos)
+	return
+}
+
+func init() {
+	f := func() reflect.Value {
+		o := &ObjString{}
+		return reflect.ValueOf(o)
+	}
+	Factory.db["TObjString"] = f
+	Factory.db["*groot.ObjString"] = f
+}
+
+// check interfaces
+var _ Object = (*ObjString)(nil)
+var _ ROOTStreamer = (*ObjString)(nil)
+
+// EOF
@@ -0,0 +1,42 @@
+package groot
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// write_read writes obj into a buffer, then reads it back into out
+func write_read(t *testing.T, obj, out ROOTStreamer) {
+	w, err := NewWBuffer(binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = obj.ROOTEncode(w)
+	if err != nil {
+		t.Fatalf("could not write: %v", err)
+	}
+	b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = out.ROOTDecode(b)
+	if err != nil {
+		t.Fatalf("could not read: %v", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("%d bytes left", b.Len())
+	}
+}
+
+func TestObjString(t *testing.T) {
+	for _, s := range []string{"", "hello", strings.Repeat("x", 300)} {
+		var got ObjString
+		write_read(t, NewObjString(s), &got)
+		if got.String() != s || got.Name() != s {
+			t.Errorf("got %q, want %q", got.String(), s)
+		}
+	}
+}
+
+// EOF
@@ -0,0 +1,190 @@
+package groot
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// read_cache holds ranges of data fetched from a remote file, so that
+// read-ahead and prefetched data can be served without another round trip.
+// the oldest data is evicted first when the cache is full.
+type read_cache struct {
+	mu     sync.Mutex
+	max    int          // maximum number of bytes held by the cache
+	nbytes int          // number of bytes held by the cache
+	chunks []read_chunk // cached data, oldest first
+}
+
+// read_chunk is a contiguous range of data of a remote file
+type read_chunk struct {
+	off  int64
+	data []byte
+}
+
+func new_read_cache(max int) *read_cache {
+	return &read_cache{
+		max:    max,
+		chunks: make([]read_chunk, 0),
+	}
+}
+
+// get fills p with the cached data at off, if available
+func (c *read_cache) get(p []byte, off int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copy_chunks(p, off, c.chunks)
+}
+
+// copy_chunks fills p with the data at off, if one of the chunks holds it
+func copy_chunks(p []byte, off int64, chunks []read_chunk) bool {
+	for _, chunk := range chunks {
+		if chunk.off <= off && off+int64(len(p)) <= chunk.off+int64(len(chunk.data)) {
+			copy(p, chunk.data[off-chunk.off:])
+			return true
+		}
+	}
+	return false
+}
+
+// has returns whether the n bytes at off are held by the cache
+func (c *read_cache) has(off, n int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, chunk := range c.chunks {
+		if chunk.off <= off && off+n <= chunk.off+int64(len(chunk.data)) {
+			return true
+		}
+	}
+	return false
+}
+
+// add adds chunks to the cache, evicting the oldest data if needed
+func (c *read_cache) add(chunks ...read_chunk) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, chunk := range chunks {
+		c.chunks = append(c.chunks, chunk)
+		c.nbytes += len(chunk.data)
+	}
+	i := 0
+	for c.nbytes > c.max && i < len(c.chunks)-1 {
+		c.nbytes -= len(c.chunks[i].data)
+		i++
+	}
+	c.chunks = append(c.chunks[:0], c.chunks[i:]...)
+}
+
+// reset drops all the cached data
+func (c *read_cache) reset() {
+	c.mu.Lock()
+	c.chunks = c.chunks[:0]
+	c.nbytes = 0
+	c.mu.Unlock()
+}
+
+// fetch_fct retrieves the given ranges of data from a remote file
+type fetch_fct func(spans []span) ([]read_chunk, error)
+
+// read_at reads len(p) bytes at off, from the cache or with fetch.
+// on a cache miss, at least readahead bytes are fetched and cached.
+func (c *read_cache) read_at(p []byte, off, size, readahead int64, fetch fetch_fct) (n int, err error) {
+	if off < 0 {
+		return 0, fmt.Errorf("groot: negative offset (%d)", off)
+	}
+	if off >= size {
+		return 0, io.EOF
+	}
+	want := int64(len(p))
+	if off+want > size {
+		want = size - off
+	}
+	if !c.get(p[:want], off) {
+		sz := want
+		if sz < readahead {
+			sz = readahead
+		}
+		if off+sz > size {
+			sz = size - off
+		}
+		chunks, err := fetch([]span{{off, sz}})
+		if err != nil {
+			return 0, err
+		}
+		// the fetched data may be evicted by a concurrent add before it is
+		// read back from the cache: fill p from the fetched chunks.
+		if !copy_chunks(p[:want], off, chunks) {
+			return 0, fmt.Errorf("groot: could not fetch range [%d, %d)", off, off+want)
+		}
+		c.add(chunks...)
+	}
+	if int(want) < len(p) {
+		return int(want), io.EOF
+	}
+	return len(p), nil
+}
+
+// prefetch fetches into the cache the spans which are not already there,
+// at most maxn spans at a time.
+func (c *read_cache) prefetch(spans []span, size, gap int64, maxn int, fetch fetch_fct) (err error) {
+	todo := c.missing(spans, size, gap)
+	for len(todo) > 0 {
+		n := len(todo)
+		if n > maxn {
+			n = maxn
+		}
+		chunks, err := fetch(todo[:n])
+		if err != nil {
+			return err
+		}
+		c.add(chunks...)
+		todo = todo[n:]
+	}
+	return err
+}
+
+// missing returns the spans which are not held by the cache, sorted and
+// merged when they are less than gap bytes apart.
+// spans outside of [0, size) are dropped.
+func (c *read_cache) missing(spans []span, size, gap int64) []span {
+	todo := make([]span, 0, len(spans))
+	for _, s := range spans {
+		if s.len <= 0 || s.off < 0 || s.off+s.len > size {
+			continue
+		}
+		if c.has(s.off, s.len) {
+			continue
+		}
+		todo = append(todo, s)
+	}
+	return merge_spans(todo, gap)
+}
+
+// merge_spans sorts the spans and merges the ones less than gap bytes apart
+func merge_spans(spans []span, gap int64) []span {
+	if len(spans) == 0 {
+		return spans
+	}
+	sort.Sort(spans_by_off(spans))
+	out := spans[:1]
+	for _, s := range spans[1:] {
+		last := &out[len(out)-1]
+		if s.off <= last.off+last.len+gap {
+			if end := s.off + s.len; end > last.off+last.len {
+				last.len = end - last.off
+			}
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+type spans_by_off []span
+
+func (p spans_by_off) Len() int           { return len(p) }
+func (p spans_by_off) Less(i, j int) bool { return p[i].off < p[j].off }
+func (p spans_by_off) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// EOF
@@ -3,19 +3,35 @@ package groot
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-type unzip_fct func()
-
+// File is a ROOT file.
+// A File opened for reading may be used concurrently from several
+// goroutines: the raw data is only accessed with positional reads.
+// The objects read from a File (e.g. a Tree and its readers) are not safe
+// for concurrent use.
 type File struct {
+	mu          sync.Mutex           // protects the objects cached by the keys
 	name        string               // path to this file
-	f           *os.File             // handle to the raw file
+	r           io.ReaderAt          // handle to the raw data
+	w           io.WriterAt          // handle to the raw data, for writing
+	c           io.Closer            // closer of the raw data (if any)
+	size        int64                // size of the raw data
 	order       binary.ByteOrder     // file endianness
 	nbytes_read uint64               // number of bytes read from this file
 	root_dir    Directory            // root directory of this file
 	unzipers    map[string]unzip_fct // unziper functions
 	title       string               // title of this file
+	writable    bool                 // whether this file was opened for writing
+	uuid        [16]byte             // universally unique identifier of this file
+	sinfos      []*StreamerInfo      // streamer infos of this file
+	bg          sync.WaitGroup       // reads running in the background (e.g. read-ahead of tree caches)
 
 	// -- record --
 
@@ -30,54 +46,327 @@ type File struct {
 	nbytes_info uint32 // number of bytes for streamerinfos?
 }
 
+// Open opens the named ROOT file for reading.
+// name may be the path to a local file or the URL of a remote file
+// ("http://...", "https://..." or "root://...").
+func Open(name string) (f *File, err error) {
+	var r interface {
+		io.ReaderAt
+		io.Closer
+		Size() int64
+	}
+	switch {
+	case strings.HasPrefix(name, "http://"), strings.HasPrefix(name, "https://"):
+		r, err = NewHTTPReader(name)
+	case strings.HasPrefix(name, "root://"), strings.HasPrefix(name, "xroot://"):
+		r, err = NewXRootDReader(name)
+	default:
+		return NewFileReader(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, err = NewReader(r, r.Size())
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	f.c = r
+	return f, err
+}
+
+// NewFileReader opens the named ROOT file for reading.
 func NewFileReader(name string) (f *File, err error) {
+	r, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := r.Stat()
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	f, err = NewReader(r, fi.Size())
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	f.c = r
+	return f, err
+}
+
+// NewReader returns a ROOT file reading its size bytes from r.
+// r may e.g. be a file, an in-memory buffer or an entry of an archive.
+// Closing the returned File does not close r.
+func NewReader(r io.ReaderAt, size int64) (f *File, err error) {
+	f = &File{
+		r:        r,
+		size:     size,
+		order:    binary.BigEndian,
+		unzipers: new_unzipers(),
+	}
+	if r, ok := r.(interface {
+		Name() string
+	}); ok {
+		f.name = r.Name()
+	}
+	f.root_dir = Directory{file: f, name: f.name}
+
+	err = f.initialize()
+	if err != nil {
+		return nil, err
+	}
+	return f, err
+}
+
+// Create creates the named ROOT file for writing.
+// If the file already exists, it is truncated.
+// Close must be called to write out the file's metadata.
+func Create(name string) (f *File, err error) {
 	f = &File{
 		name:     name,
 		order:    binary.BigEndian,
-		unzipers: make(map[string]unzip_fct),
+		unzipers: new_unzipers(),
+		writable: true,
+		uuid:     new_uuid(),
+		version:  g_ROOT_VERSION,
+		beg:      kBEGIN,
+		end:      kBEGIN,
+		sinfos:   make([]*StreamerInfo, 0),
 	}
 
-	f.f, err = os.Open(name)
+	w, err := os.Create(name)
 	if err != nil {
 		return nil, err
 	}
+	f.r = w
+	f.w = w
+	f.c = w
+
+	now := time.Now()
+	f.root_dir = Directory{
+		file:  f,
+		keys:  make([]Key, 0),
+		name:  name,
+		uuid:  new_uuid(),
+		ctime: now,
+		mtime: now,
+	}
 
-	f.root_dir = Directory{file: f}
+	// the first key of the file holds the name and title of the file,
+	// followed by the record of the top-level directory.
+	key, err := new_key_for(&f.root_dir, f.name, f.title, "TFile", func(b *Buffer) error {
+		f.nbytes_name = b.klen + uint32(tstring_size(f.name)+tstring_size(f.title))
+		f.root_dir.nbytes_name = f.nbytes_name
+		f.root_dir.seek_dir = f.beg
+		b.write_tstring(f.name)
+		b.write_tstring(f.title)
+		return f.root_dir.ROOTEncode(b)
+	})
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	err = key.write_file()
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
 
-	err = f.initialize()
+	err = f.write_header()
 	if err != nil {
+		w.Close()
 		return nil, err
 	}
 	return f, err
 }
 
-func (f *File) seek(offset int64, pos int) (ret int64, err error) {
-	switch pos {
-	case os.SEEK_SET:
-		return f.f.Seek(offset, os.SEEK_SET)
-	case os.SEEK_CUR:
-		return f.f.Seek(offset, os.SEEK_CUR)
-	case os.SEEK_END:
-		return f.f.Seek(offset, os.SEEK_END)
+// Close closes the file.
+// For files opened for writing, the keys of all directories, the streamer
+// infos, the free segments and the file header are written out first.
+func (f *File) Close() (err error) {
+	if f.r == nil {
+		return
+	}
+	f.bg.Wait()
 
-	default:
-		err = fmt.Errorf("groot: unknown seek option (%d)", pos)
-		panic(err)
+	if f.writable {
+		// the trees still being filled register their streamer infos
+		// when they are written out: close the directories first.
+		err = f.root_dir.close()
+		if err != nil {
+			return err
+		}
+		err = f.write_streamer_infos()
+		if err != nil {
+			return err
+		}
+		err = f.write_free_segments()
+		if err != nil {
+			return err
+		}
+		err = f.write_header()
+		if err != nil {
+			return err
+		}
+	}
+
+	if f.c != nil {
+		err = f.c.Close()
 	}
-	panic("unreachable")
+	f.r = nil
+	f.w = nil
+	f.c = nil
+	return err
 }
 
-func (f *File) initialize() (err error) {
-	err = f.read_header()
+// alloc reserves nbytes at the end of the file and returns their location
+func (f *File) alloc(nbytes int64) (pos int64) {
+	pos = f.end
+	f.end += nbytes
+	return pos
+}
+
+// write_header writes the file header at the beginning of the file.
+// see ROOT::TFile::WriteHeader
+func (f *File) write_header() (err error) {
+	b, err := NewWBuffer(f.order, 0)
+	if err != nil {
+		return err
+	}
+	b.write_nbytes([]byte("root"))
+	version := f.version
+	big := f.end > g_START_BIG_FILE
+	if big && version < 1000000 {
+		version += 1000000
+	}
+	b.u4ton(version)
+	b.u4ton(uint32(f.beg))
+	nfree := int32(0)
+	if f.seek_free > 0 {
+		nfree = 1
+	}
+	if big {
+		b.i8ton(f.end)
+		b.i8ton(f.seek_free)
+		b.u4ton(f.nbytes_free)
+		b.i4ton(nfree)
+		b.u4ton(f.nbytes_name)
+		b.byteton(8) // units
+		b.i4ton(0)   // compress
+		b.i8ton(f.seek_info)
+		b.u4ton(f.nbytes_info)
+	} else {
+		b.i4ton(int32(f.end))
+		b.i4ton(int32(f.seek_free))
+		b.u4ton(f.nbytes_free)
+		b.i4ton(nfree)
+		b.u4ton(f.nbytes_name)
+		b.byteton(4) // units
+		b.i4ton(0)   // compress
+		b.i4ton(int32(f.seek_info))
+		b.u4ton(f.nbytes_info)
+	}
+	b.u2ton(1) // TUUID version
+	b.write_nbytes(f.uuid[:])
+
+	buf := make([]byte, int(f.beg))
+	copy(buf, b.Bytes())
+	_, err = f.w.WriteAt(buf, 0)
+	return err
+}
+
+// write_streamer_infos writes the list of streamer infos of this file.
+// see ROOT::TFile::WriteStreamerInfo
+func (f *File) write_streamer_infos() (err error) {
+	lst := List{elmts: make([]Object, 0, len(f.sinfos))}
+	for _, si := range f.sinfos {
+		lst.elmts = append(lst.elmts, si)
+	}
+
+	key, err := new_key_for(&f.root_dir, "StreamerInfo", "Doubly linked list", "TList", lst.ROOTEncode)
+	if err != nil {
+		return err
+	}
+	f.seek_info = key.seek_key
+	f.nbytes_info = key.nbytes
+	return key.write_file()
+}
+
+// write_free_segments writes the list of free segments of this file.
+// the only free segment is the one after the end of the file.
+// see ROOT::TFile::WriteFree
+func (f *File) write_free_segments() (err error) {
+	key, err := new_key_for(&f.root_dir, f.name, f.title, "TFile", func(b *Buffer) error {
+		version := uint16(1)
+		nbytes := int64(2 + 2*sz_int32)
+		if f.end > g_START_BIG_FILE {
+			version += 1000
+			nbytes = 2 + 2*sz_int64
+		}
+		first := f.end + int64(b.klen) + nbytes
+		last := int64(g_START_BIG_FILE)
+		if first >= last {
+			last = first + g_START_BIG_FILE
+		}
+		b.u2ton(version)
+		if version > 1000 {
+			b.i8ton(first)
+			b.i8ton(last)
+		} else {
+			b.i4ton(int32(first))
+			b.i4ton(int32(last))
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
+	f.seek_free = key.seek_key
+	f.nbytes_free = key.nbytes
+	return key.write_file()
+}
+
+// span is a range of bytes in a file
+type span struct {
+	off int64 // offset of the first byte
+	len int64 // number of bytes
+}
+
+// prefetcher is implemented by the readers of raw data which can fetch
+// several ranges of bytes at once, ahead of their actual reading.
+type prefetcher interface {
+	prefetch(spans []span) error
+}
+
+// prefetch hints the reader of the raw data that the given ranges of bytes
+// will be read soon.
+func (f *File) prefetch(spans []span) error {
+	p, ok := f.r.(prefetcher)
+	if !ok {
+		return nil
+	}
+	return p.prefetch(spans)
+}
+
+// read_at reads len(buf) bytes from the raw data, starting at pos
+func (f *File) read_at(buf []byte, pos int64) (err error) {
+	n, err := f.r.ReadAt(buf, pos)
+	atomic.AddUint64(&f.nbytes_read, uint64(n))
+	if err == io.EOF && n == len(buf) {
+		err = nil
+	}
+	return err
+}
 
-	cur, err := f.f.Seek(0, os.SEEK_CUR)
+func (f *File) initialize() (err error) {
+	err = f.read_header()
 	if err != nil {
 		return err
 	}
-	defer f.f.Seek(cur, os.SEEK_SET)
 
 	nbytes := f.nbytes_name + f.root_dir.record_size(f.version)
 	printf("nbytes: %v\n", nbytes)
@@ -85,7 +374,7 @@ func (f *File) initialize() (err error) {
 	buf := make([]byte, int(nbytes))
 
 	// read directory info
-	_, err = f.f.ReadAt(buf, f.beg)
+	err = f.read_at(buf, f.beg)
 	if err != nil {
 		return err
 	}
@@ -127,6 +416,9 @@ func (f *File) initialize() (err error) {
 
 	f.title = b.read_tstring()
 	printf("f-title   [%v]\n", f.title)
+	if b.err != nil {
+		return fmt.Errorf("groot: could not read header of file [%s]: %w", f.name, b.err)
+	}
 
 	if f.root_dir.nbytes_name < 10 || f.root_dir.nbytes_name > 1000 {
 		return fmt.Errorf("groot: can't read directory info.")
@@ -146,9 +438,9 @@ func (f *File) initialize() (err error) {
 
 func (f *File) read_header() (err error) {
 	buf := make([]byte, 64)
-	_, err = f.f.ReadAt(buf, 0)
+	err = f.read_at(buf, 0)
 	if err != nil {
-		return err
+		return fmt.Errorf("groot: could not read header of file [%s]: %w", f.name, err)
 	}
 
 	b, err := NewBuffer(buf, f.order, 0)
@@ -172,6 +464,9 @@ func (f *File) read_header() (err error) {
 		f.seek_free = int64(b.ntou4())
 	}
 	printf("end: %v\n", f.end)
+	if f.end > f.size {
+		return fmt.Errorf("groot: file [%s] is truncated (end=%d, size=%d)", f.name, f.end, f.size)
+	}
 	printf("seek-free: %v\n", f.seek_free)
 	f.nbytes_free = b.ntou4()
 	/*nfree*/ b.ntoi4()
@@ -193,6 +488,45 @@ func (f *File) read_header() (err error) {
 	return f.read_streamer_infos()
 }
 
+// check_range checks that the nbytes bytes starting at pos are part of the
+// file
+func (f *File) check_range(pos, nbytes int64) error {
+	if pos < 0 || nbytes < 0 || pos+nbytes > f.end {
+		return fmt.Errorf("groot: invalid file range [%d, %d) (end=%d)", pos, pos+nbytes, f.end)
+	}
+	return nil
+}
+
+// find_streamer_info returns the StreamerInfo of the given class and class
+// version, or nil if there is none in this file.
+// the most recent version of the class is returned if vers is negative.
+func (f *File) find_streamer_info(name string, vers int) *StreamerInfo {
+	var found *StreamerInfo
+	for _, si := range f.sinfos {
+		if si.name != name {
+			continue
+		}
+		if vers >= 0 && int(si.classvers) == vers {
+			return si
+		}
+		if vers < 0 && (found == nil || si.classvers > found.classvers) {
+			found = si
+		}
+	}
+	return found
+}
+
+// find_streamer_info_by_checksum returns the StreamerInfo of the given class
+// with the given checksum, or nil if there is none in this file.
+func (f *File) find_streamer_info_by_checksum(name string, chksum uint32) *StreamerInfo {
+	for _, si := range f.sinfos {
+		if si.name == name && si.checksum == chksum {
+			return si
+		}
+	}
+	return nil
+}
+
 func (f *File) read_streamer_infos() (err error) {
 
 	//FIXME:
@@ -206,7 +540,7 @@ func (f *File) read_streamer_infos() (err error) {
 	if f.seek_info > 0 && f.seek_info < f.end {
 		buf = make([]byte, int(f.nbytes_info))
 
-		_, err = f.f.ReadAt(buf, f.seek_info)
+		err = f.read_at(buf, f.seek_info)
 		if err != nil {
 			return err
 		}
@@ -215,17 +549,29 @@ func (f *File) read_streamer_infos() (err error) {
 			return err
 		}
 		b, err := NewBuffer(buf, f.order, 0)
+		if err != nil {
+			return err
+		}
 		err = key.init_from_buffer(b)
 		if err != nil {
 			return err
 		}
-		obj, ok := key.Value().(List)
+		v, err := key.Value()
+		if err != nil {
+			return fmt.Errorf("groot: could not read the streamer infos of file [%s]: %w", f.name, err)
+		}
+		obj, ok := v.(*List)
 		if ok {
-			lst = obj
+			lst = *obj
 		}
 
+		f.sinfos = make([]*StreamerInfo, 0, len(lst.elmts))
 		for i, v := range lst.elmts {
 			printf("lst[%d]= %s %s\n", i, v.Name(), v.Title())
+			// the list also holds the schema evolution rules (if any)
+			if si, ok := v.(*StreamerInfo); ok {
+				f.sinfos = append(f.sinfos, si)
+			}
 		}
 	}
 	printf("buf: %v\n", len(buf))
@@ -303,8 +649,34 @@ func (f *File) Dir() *Directory {
 	return &f.root_dir
 }
 
+// Get returns the object registered under the given name in the top-level
+// directory of this file (see Directory.Get).
+func (f *File) Get(name string) (Object, error) {
+	return f.root_dir.Get(name)
+}
+
+// BytesRead returns the number of bytes read from the raw data of this file
+func (f *File) BytesRead() int64 {
+	return int64(atomic.LoadUint64(&f.nbytes_read))
+}
+
 func (f *File) ByteOrder() binary.ByteOrder {
 	return f.order
 }
 
+// StreamerInfos returns the list of StreamerInfos stored in this file.
+func (f *File) StreamerInfos() []*StreamerInfo {
+	return f.sinfos
+}
+
+// StreamerInfo returns the StreamerInfo of the given class and class version.
+// The most recent version of the class is returned if version is negative.
+func (f *File) StreamerInfo(name string, version int) (*StreamerInfo, error) {
+	si := f.find_streamer_info(name, version)
+	if si == nil {
+		return nil, fmt.Errorf("groot: no StreamerInfo for class [%s] (version=%d) in file [%s]", name, version, f.name)
+	}
+	return si, nil
+}
+
 // EOF
@@ -0,0 +1,75 @@
+package groot
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestNewReader(t *testing.T) {
+	tree := create_test_tree(t, 100)
+	data, err := ioutil.ReadFile(tree.file.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a file held in memory, or as a part of a larger blob
+	blob := append([]byte(strings.Repeat("x", 100)), data...)
+	blob = append(blob, "trailer"...)
+	for _, tc := range []struct {
+		name string
+		r    io.ReaderAt
+	}{
+		{"memory", bytes.NewReader(data)},
+		{"section", io.NewSectionReader(bytes.NewReader(blob), 100, int64(len(data)))},
+	} {
+		f, err := NewReader(tc.r, int64(len(data)))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		obj, err := f.Get("tree")
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		r, err := obj.(*Tree).NewReader("i32", "f64")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		n := 0
+		for r.Next() {
+			i32 := r.Value("i32").(int32)
+			f64 := r.Value("f64").(float64)
+			if i32 != int32(n) || f64 != 2*float64(n) {
+				t.Errorf("%s: entry %d: got i32=%v f64=%v", tc.name, n, i32, f64)
+			}
+			n++
+		}
+		if err := r.Err(); err != nil || n != 100 {
+			t.Errorf("%s: read %d entries, err=%v", tc.name, n, err)
+		}
+		if f.BytesRead() == 0 {
+			t.Errorf("%s: no byte read", tc.name)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+
+	// not a ROOT file
+	_, err = NewReader(bytes.NewReader(blob), int64(len(blob)))
+	if err == nil {
+		t.Errorf("expected an error reading a non-ROOT file")
+	}
+	// a size larger than the data
+	_, err = NewReader(bytes.NewReader(data[:200]), int64(len(data)))
+	if err == nil {
+		t.Errorf("expected an error reading a truncated file")
+	}
+}
+
+// EOF
@@ -0,0 +1,523 @@
+package groot
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// kinds of types held by STL containers
+const (
+	stl_basic    = iota // builtin type
+	stl_string          // std::string
+	stl_tstring         // TString
+	stl_seq             // vector, list, deque, set, multiset, ...
+	stl_map             // map, unordered_map
+	stl_multimap        // multimap, unordered_multimap
+	stl_object          // object streamed by value
+	stl_pointer         // pointer to an object
+)
+
+// stl_basic_types maps the names of the builtin types to their type code
+var stl_basic_types = map[string]int{
+	"bool":               KBool,
+	"Bool_t":             KBool,
+	"char":               KChar,
+	"Char_t":             KChar,
+	"signed char":        KChar,
+	"int8_t":             KChar,
+	"unsigned char":      KUChar,
+	"UChar_t":            KUChar,
+	"uint8_t":            KUChar,
+	"short":              KShort,
+	"Short_t":            KShort,
+	"int16_t":            KShort,
+	"unsigned short":     KUShort,
+	"UShort_t":           KUShort,
+	"uint16_t":           KUShort,
+	"int":                KInt,
+	"Int_t":              KInt,
+	"int32_t":            KInt,
+	"unsigned int":       KUInt,
+	"unsigned":           KUInt,
+	"UInt_t":             KUInt,
+	"uint32_t":           KUInt,
+	"long":               KLong,
+	"Long_t":             KLong,
+	"unsigned long":      KULong,
+	"ULong_t":            KULong,
+	"long long":          KLong64,
+	"Long64_t":           KLong64,
+	"int64_t":            KLong64,
+	"unsigned long long": KULong64,
+	"ULong64_t":          KULong64,
+	"uint64_t":           KULong64,
+	"float":              KFloat,
+	"Float_t":            KFloat,
+	"Float16_t":          KFloat16,
+	"double":             KDouble,
+	"Double_t":           KDouble,
+	"Double32_t":         KDouble32,
+}
+
+var (
+	g_iface_type  = reflect.TypeOf((*interface{})(nil)).Elem()
+	g_object_type = reflect.TypeOf((*Object)(nil)).Elem()
+)
+
+// stl_type describes a (possibly nested) type held by an STL container,
+// from its C++ name.
+type stl_type struct {
+	name  string       // C++ name of the type
+	kind  int          // kind of type (stl_basic, stl_string, ...)
+	etype int          // type code of builtin types
+	rtype reflect.Type // Go type of the decoded values
+	elem  *stl_type    // type of the elements (sequences) or keys (maps)
+	value *stl_type    // type of the mapped values (maps)
+}
+
+// stl_pair_type returns the Go type of the key/value pairs of a multimap
+func stl_pair_type(k, v reflect.Type) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{
+		{Name: "Key", Type: k},
+		{Name: "Value", Type: v},
+	})
+}
+
+// new_stl_type parses the C++ type name and returns its description.
+// e.g. "vector<float>", "map<int,string>", "vector<vector<MyStruct> >"
+func new_stl_type(name string) (t *stl_type, err error) {
+	name = strings.TrimSpace(strings.Replace(name, "std::", "", -1))
+	name = strings.TrimSpace(strings.TrimPrefix(name, "const "))
+	t = &stl_type{name: name}
+
+	if strings.HasSuffix(name, "*") {
+		t.name = strings.TrimSpace(strings.TrimRight(name, "*"))
+		t.kind = stl_pointer
+		t.rtype = g_object_type
+		return t, err
+	}
+
+	if etype, ok := stl_basic_types[name]; ok {
+		t.kind = stl_basic
+		t.etype = etype
+		t.rtype = basic_types[etype]
+		return t, err
+	}
+
+	switch name {
+	case "string":
+		t.kind = stl_string
+		t.rtype = reflect.TypeOf("")
+		return t, err
+	case "TString":
+		t.kind = stl_tstring
+		t.rtype = reflect.TypeOf("")
+		return t, err
+	}
+
+	beg := strings.Index(name, "<")
+	end := strings.LastIndex(name, ">")
+	if beg < 0 || end < beg {
+		t.kind = stl_object
+		t.rtype = g_iface_type
+		return t, err
+	}
+	args := split_template_args(name[beg+1 : end])
+
+	switch strings.TrimSpace(name[:beg]) {
+	case "vector", "list", "deque", "forward_list",
+		"set", "multiset", "unordered_set", "unordered_multiset":
+		if len(args) < 1 {
+			return nil, fmt.Errorf("groot: invalid STL type [%s]", name)
+		}
+		t.kind = stl_seq
+		t.elem, err = new_stl_type(args[0])
+		if err != nil {
+			return nil, err
+		}
+		t.rtype = reflect.SliceOf(t.elem.rtype)
+
+	case "map", "multimap", "unordered_map", "unordered_multimap":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("groot: invalid STL type [%s]", name)
+		}
+		t.elem, err = new_stl_type(args[0])
+		if err != nil {
+			return nil, err
+		}
+		t.value, err = new_stl_type(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(name[:beg], "multimap") {
+			// keys may be repeated: keep all the pairs, in streaming order.
+			t.kind = stl_multimap
+			t.rtype = reflect.SliceOf(stl_pair_type(t.elem.rtype, t.value.rtype))
+			break
+		}
+		t.kind = stl_map
+		if !t.elem.rtype.Comparable() {
+			return nil, fmt.Errorf("groot: STL type [%s] has unsupported keys", name)
+		}
+		t.rtype = reflect.MapOf(t.elem.rtype, t.value.rtype)
+
+	default:
+		// e.g. pair<int,float> or a templated user class
+		t.kind = stl_object
+		t.rtype = g_iface_type
+	}
+	return t, err
+}
+
+// new_stl_container returns the description of the STL container name
+func new_stl_container(name string) (t *stl_type, err error) {
+	t, err = new_stl_type(name)
+	if err != nil {
+		return nil, err
+	}
+	if !t.is_container() {
+		return nil, fmt.Errorf("groot: [%s] is not an STL container", name)
+	}
+	return t, err
+}
+
+// is_container returns whether t is an STL container
+func (t *stl_type) is_container() bool {
+	return t.kind == stl_seq || t.kind == stl_map || t.kind == stl_multimap
+}
+
+// is_stl_container returns whether name is the name of an STL container
+func is_stl_container(name string) bool {
+	_, err := new_stl_container(name)
+	return err == nil
+}
+
+// STLType returns the Go type of the values of the STL container name,
+// as read by Buffer.ReadSTL and written by Buffer.WriteSTL.
+// e.g. []float32 for "vector<float>", map[int32]string for "map<int,string>"
+// and []struct{ Key int32; Value string } for "multimap<int,string>"
+func STLType(name string) (reflect.Type, error) {
+	t, err := new_stl_container(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.rtype, err
+}
+
+// split_template_args splits the arguments of a template, at the top-level
+// commas.
+func split_template_args(s string) []string {
+	args := make([]string, 0, 2)
+	depth := 0
+	beg := 0
+	for i, c := range s {
+		switch c {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(s[beg:i]))
+				beg = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(s[beg:]))
+}
+
+// read decodes a value of type t
+func (t *stl_type) read(b *Buffer) (v interface{}, err error) {
+	switch t.kind {
+	case stl_basic:
+		return read_basic(b, "", t.etype)
+	case stl_string:
+		return b.read_std_string(), err
+	case stl_tstring:
+		return b.read_tstring(), err
+	case stl_object:
+		return read_object_value(b, KObject, t.name)
+	case stl_pointer:
+		return b.read_object(), err
+	case stl_seq, stl_map, stl_multimap:
+		return t.read_container(b)
+	}
+	return nil, fmt.Errorf("groot: unknown STL type [%s]", t.name)
+}
+
+// read_container decodes an STL container.
+// the container may be preceded by a byte count and version header, and
+// its content may be streamed member-wise or object-wise.
+// see TStreamerInfoActions::ReadSTL
+func (t *stl_type) read_container(b *Buffer) (v interface{}, err error) {
+	spos := b.Pos()
+	var (
+		vers uint16
+		pos  uint32
+		bcnt uint32
+	)
+	// the number of elements of a container never has the byte count bit
+	// set: use it to detect the header.
+	if bb := b.clone(); bb.ntou4()&kByteCountMask != 0 {
+		vers, pos, bcnt = b.read_version()
+	}
+	printf("[stl] type=%s vers=%v bcnt=%v\n", t.name, vers, bcnt)
+
+	if vers&kStreamedMemberWise != 0 {
+		v, err = t.read_memberwise(b)
+	} else {
+		v, err = t.read_objectwise(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcnt != 0 {
+		b.check_byte_count(pos, bcnt, spos, t.name)
+	}
+	return v, err
+}
+
+// read_objectwise decodes the content of a container, element by element
+func (t *stl_type) read_objectwise(b *Buffer) (v interface{}, err error) {
+	n := int(b.ntoi4())
+	if n < 0 || !b.check_len(n, 1) {
+		return nil, fmt.Errorf("groot: invalid number of elements (%d) for [%s]", n, t.name)
+	}
+
+	switch t.kind {
+	case stl_seq:
+		if t.elem.kind == stl_basic {
+			return read_basic_array(b, "", t.elem.etype, n)
+		}
+		slice := reflect.MakeSlice(t.rtype, n, n)
+		for i := 0; i < n; i++ {
+			v, err := t.elem.read(b)
+			if err != nil {
+				return nil, err
+			}
+			set_value(slice.Index(i), v)
+		}
+		return slice.Interface(), err
+
+	case stl_map, stl_multimap:
+		keys := make([]interface{}, n)
+		values := make([]interface{}, n)
+		for i := 0; i < n; i++ {
+			keys[i], err = t.elem.read(b)
+			if err != nil {
+				return nil, err
+			}
+			values[i], err = t.value.read(b)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return t.make_map(keys, values), err
+	}
+	return nil, fmt.Errorf("groot: [%s] is not an STL container", t.name)
+}
+
+// read_memberwise decodes the content of a container whose elements have
+// been streamed member by member: all the values of the first member,
+// then all the values of the second member, etc...
+// see TStreamerInfo::ReadBufferSTL
+func (t *stl_type) read_memberwise(b *Buffer) (v interface{}, err error) {
+	// version of the class of the elements
+	// see TBufferFile::ReadVersionForMemberWise
+	vers := int16(b.ntou2())
+	var chksum uint32
+	if vers <= 0 {
+		chksum = b.ntou4()
+	}
+	n := int(b.ntoi4())
+	if n < 0 || !b.check_len(n, 1) {
+		return nil, fmt.Errorf("groot: invalid number of elements (%d) for [%s]", n, t.name)
+	}
+
+	switch t.kind {
+	case stl_seq:
+		if t.elem.kind != stl_object {
+			return nil, fmt.Errorf("groot: member-wise streaming of [%s] is not supported", t.name)
+		}
+		var si *StreamerInfo
+		if vers <= 0 && b.file != nil {
+			si = b.file.find_streamer_info_by_checksum(t.elem.name, chksum)
+		} else {
+			si = b.streamer_info(t.elem.name, int(vers))
+		}
+		if si == nil {
+			return nil, fmt.Errorf("groot: no StreamerInfo for class [%s] (version=%d)", t.elem.name, vers)
+		}
+		objs, err := read_memberwise_objects(b, si, n)
+		if err != nil {
+			return nil, err
+		}
+		slice := reflect.MakeSlice(t.rtype, n, n)
+		for i, obj := range objs {
+			slice.Index(i).Set(reflect.ValueOf(obj))
+		}
+		return slice.Interface(), err
+
+	case stl_map, stl_multimap:
+		// the elements are std::pair<K,V>: all the keys, then all the values.
+		keys, err := t.elem.read_column(b, n)
+		if err != nil {
+			return nil, err
+		}
+		values, err := t.value.read_column(b, n)
+		if err != nil {
+			return nil, err
+		}
+		return t.make_map(keys, values), err
+	}
+	return nil, fmt.Errorf("groot: [%s] is not an STL container", t.name)
+}
+
+// read_column reads the n values of a member of member-wise streamed objects
+func (t *stl_type) read_column(b *Buffer, n int) (vs []interface{}, err error) {
+	if t.is_container() {
+		// nested containers are preceded by a header for the whole column
+		if bb := b.clone(); bb.ntou4()&kByteCountMask != 0 {
+			b.read_version()
+		}
+	}
+	vs = make([]interface{}, n)
+	for i := range vs {
+		vs[i], err = t.read(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return vs, err
+}
+
+// write encodes the value v of type t
+func (t *stl_type) write(b *Buffer, v reflect.Value) (err error) {
+	switch t.kind {
+	case stl_basic:
+		return write_basic(b, "", t.etype, v.Interface())
+	case stl_string, stl_tstring:
+		b.write_tstring(v.String())
+		return err
+	case stl_object:
+		o, ok := v.Interface().(ROOTStreamer)
+		if !ok {
+			return fmt.Errorf("groot: element of [%s] does not satisfy the ROOTStreamer interface", t.name)
+		}
+		return o.ROOTEncode(b)
+	case stl_pointer:
+		o, _ := v.Interface().(Object)
+		return b.write_object(o)
+	case stl_seq, stl_map, stl_multimap:
+		// nested containers are streamed without a header
+		return t.write_objectwise(b, v)
+	}
+	return fmt.Errorf("groot: unknown STL type [%s]", t.name)
+}
+
+// write_container encodes an STL container, element by element, preceded
+// by a byte count and version header.
+// see TStreamerInfoActions::WriteSTL
+func (t *stl_type) write_container(b *Buffer, v reflect.Value) (err error) {
+	// the version is the one of the TStreamerInfo class
+	pos := b.write_version(9)
+	err = t.write_objectwise(b, v)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
+	return err
+}
+
+// write_objectwise encodes the content of a container, element by element
+func (t *stl_type) write_objectwise(b *Buffer, v reflect.Value) (err error) {
+	b.i4ton(int32(v.Len()))
+	switch t.kind {
+	case stl_seq:
+		for i := 0; i < v.Len(); i++ {
+			err = t.elem.write(b, v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	case stl_map:
+		// std::map and std::multimap are ordered by key
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return less_value(keys[i], keys[j])
+		})
+		for _, k := range keys {
+			err = t.elem.write(b, k)
+			if err != nil {
+				return err
+			}
+			err = t.value.write(b, v.MapIndex(k))
+			if err != nil {
+				return err
+			}
+		}
+	case stl_multimap:
+		for i := 0; i < v.Len(); i++ {
+			err = t.elem.write(b, v.Index(i).Field(0))
+			if err != nil {
+				return err
+			}
+			err = t.value.write(b, v.Index(i).Field(1))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+// less_value orders the keys of a map
+func less_value(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+	return false
+}
+
+// set_value sets dst to v (a nil v leaves dst to its zero value)
+func set_value(dst reflect.Value, v interface{}) {
+	if v == nil {
+		return
+	}
+	dst.Set(reflect.ValueOf(v))
+}
+
+// make_map returns the map (or the slice of pairs, for multimaps) of type t
+// holding the given keys and values
+func (t *stl_type) make_map(keys, values []interface{}) interface{} {
+	if t.kind == stl_multimap {
+		pairs := reflect.MakeSlice(t.rtype, len(keys), len(keys))
+		for i := range keys {
+			set_value(pairs.Index(i).Field(0), keys[i])
+			set_value(pairs.Index(i).Field(1), values[i])
+		}
+		return pairs.Interface()
+	}
+	m := reflect.MakeMap(t.rtype)
+	for i := range keys {
+		kv := reflect.New(t.elem.rtype).Elem()
+		set_value(kv, keys[i])
+		vv := reflect.New(t.value.rtype).Elem()
+		set_value(vv, values[i])
+		m.SetMapIndex(kv, vv)
+	}
+	return m.Interface()
+}
+
+// EOF
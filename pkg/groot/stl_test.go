@@ -0,0 +1,279 @@
+package groot
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+type stl_pair struct {
+	Key   int32
+	Value string
+}
+
+func TestSTLType(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{"vector<float>", "[]float32"},
+		{"std::vector<std::string>", "[]string"},
+		{"set<unsigned short>", "[]uint16"},
+		{"vector<vector<Double32_t> >", "[][]float64"},
+		{"map<int,string>", "map[int32]string"},
+		{"unordered_map<TString,vector<bool> >", "map[string][]bool"},
+		{"multimap<int,string>", "[]struct { Key int32; Value string }"},
+		{"vector<Track*>", "[]groot.Object"},
+		{"vector<Track>", "[]interface {}"},
+	} {
+		rt, err := STLType(tc.name)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got := rt.String(); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+
+	for _, name := range []string{"int", "string", "pair<int,float>", "map<int>", "map<vector<int>,int>"} {
+		_, err := STLType(name)
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSTLReadWrite(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		v    interface{}
+	}{
+		{"vector<float>", []float32{1, -2.5, 3}},
+		{"vector<string>", []string{"a", "", string(make([]byte, 300))}},
+		{"set<unsigned short>", []uint16{1, 2, 65535}},
+		{"list<TString>", []string{"x", "y"}},
+		{"vector<vector<double> >", [][]float64{{1, 2}, nil, {3}}},
+		{"map<int,string>", map[int32]string{-1: "a", 0: "b", 42: "c"}},
+		{"map<string,vector<int> >", map[string][]int32{"a": {1, 2}, "b": {3}}},
+		{"multimap<int,string>", []struct {
+			Key   int32
+			Value string
+		}{{1, "a"}, {1, "b"}, {2, "c"}, {1, "d"}}},
+	} {
+		w, err := NewWBuffer(binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.WriteSTL(tc.name, tc.v)
+		if err != nil {
+			t.Errorf("%s: could not write: %v", tc.name, err)
+			continue
+		}
+
+		b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := reflect.New(reflect.TypeOf(tc.v))
+		err = b.ReadSTL(tc.name, got.Interface())
+		if err != nil {
+			t.Errorf("%s: could not read: %v", tc.name, err)
+			continue
+		}
+		want := reflect.ValueOf(tc.v)
+		if tc.name == "vector<vector<double> >" {
+			// empty containers are read as empty slices
+			want = reflect.ValueOf([][]float64{{1, 2}, {}, {3}})
+		}
+		if !reflect.DeepEqual(got.Elem().Interface(), want.Interface()) {
+			t.Errorf("%s: got %v, want %v", tc.name, got.Elem().Interface(), want.Interface())
+		}
+		if b.Len() != 0 {
+			t.Errorf("%s: %d bytes left", tc.name, b.Len())
+		}
+	}
+
+	w, err := NewWBuffer(binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.WriteSTL("vector<float>", []float64{1})
+	if err == nil {
+		t.Errorf("expected an error writing a []float64 as a vector<float>")
+	}
+	w.WriteSTL("vector<float>", []float32{1})
+	b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v []float64
+	err = b.ReadSTL("vector<float>", &v)
+	if err == nil {
+		t.Errorf("expected an error reading a vector<float> into a []float64")
+	}
+}
+
+func TestSTLMemberwise(t *testing.T) {
+	w, err := NewWBuffer(binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// vector<Track>
+	pos := w.write_version(9 | kStreamedMemberWise)
+	w.u2ton(2)
+	w.i4ton(2)
+	for i := 0; i < 2; i++ {
+		w.write_tobject(0, 0)
+	}
+	for _, px := range []float64{50, 25} {
+		write_double32(w, px, "[0,100,16] px", false)
+	}
+	for _, py := range []float64{1.5, -1} {
+		write_double32(w, py, "py", false)
+	}
+	for _, q := range []float32{1, -1} {
+		write_double32(w, float64(q), "[0,0,10] charge", true)
+	}
+	for _, m := range []float32{0.5, 0.25} {
+		write_double32(w, float64(m), "mass", true)
+	}
+	w.set_byte_count(pos)
+
+	// map<int,string> and multimap<int,string>: all the keys, then all the
+	// values.
+	for i := 0; i < 2; i++ {
+		pos = w.write_version(9 | kStreamedMemberWise)
+		w.u2ton(1)
+		w.i4ton(3)
+		for _, k := range []int32{3, 1, 3} {
+			w.i4ton(k)
+		}
+		for _, v := range []string{"a", "b", "c"} {
+			w.write_tstring(v)
+		}
+		w.set_byte_count(pos)
+	}
+
+	b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.file = &File{sinfos: generic_sinfos}
+
+	var trks []interface{}
+	err = b.ReadSTL("vector<Track>", &trks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trks) != 2 {
+		t.Fatalf("got %d tracks", len(trks))
+	}
+	for i, want := range [][]interface{}{
+		{50.0, 1.5, float32(1), float32(0.5)},
+		{25.0, -1.0, float32(-1), float32(0.25)},
+	} {
+		trk := trks[i].(*GenericObject)
+		got := []interface{}{trk.Value("fPx"), trk.Value("fPy"), trk.Value("fQ"), trk.Value("fM")}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("track #%d: got %v, want %v", i, got, want)
+		}
+	}
+
+	var m map[int32]string
+	err = b.ReadSTL("map<int,string>", &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int32]string{1: "b", 3: "c"}; !reflect.DeepEqual(m, want) {
+		t.Errorf("map: got %v, want %v", m, want)
+	}
+
+	var mm []struct {
+		Key   int32
+		Value string
+	}
+	err = b.ReadSTL("multimap<int,string>", &mm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(mm), 3; got != want {
+		t.Fatalf("multimap: got %d pairs, want %d", got, want)
+	}
+	for i, want := range []stl_pair{{3, "a"}, {1, "b"}, {3, "c"}} {
+		if got := stl_pair(mm[i]); got != want {
+			t.Errorf("multimap: pair #%d: got %v, want %v", i, got, want)
+		}
+	}
+	if b.Len() != 0 {
+		t.Errorf("%d bytes left", b.Len())
+	}
+}
+
+func TestSTLMembers(t *testing.T) {
+	se_stl := func(name, typename string) *StreamerSTL {
+		return &StreamerSTL{
+			seBase: seBase{name: name, etype: KSTL, typename: typename},
+		}
+	}
+	sinfos := []*StreamerInfo{{
+		name:      "Holder",
+		classvers: 1,
+		elmts: []StreamerElement{
+			se_stl("fFloats", "vector<float>"),
+			se_stl("fNames", "map<int,string>"),
+			se_stl("fStrs", "vector<string>"),
+			se_stl("fPairs", "multimap<int,string>"),
+			&StreamerSTLstring{
+				seBase: seBase{name: "fStr", etype: KSTLstring, typename: "string"},
+			},
+		},
+	}}
+
+	values := []interface{}{
+		[]float32{1, 2},
+		map[int32]string{1: "one", 2: "two"},
+		[]string{"x", "y"},
+		[]struct {
+			Key   int32
+			Value string
+		}{{1, "a"}, {1, "b"}},
+		"hello",
+	}
+
+	w, err := NewWBuffer(binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := w.write_version(1)
+	for i, elmt := range sinfos[0].elmts {
+		if elmt.Type() == KSTLstring {
+			w.WriteStdString(values[i].(string))
+			continue
+		}
+		err = w.WriteSTL(elmt.TypeName(), values[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.set_byte_count(pos)
+
+	b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.file = &File{sinfos: sinfos}
+	obj := new_generic_object("Holder")
+	err = obj.ROOTDecode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, elmt := range sinfos[0].elmts {
+		if got := obj.Value(elmt.Name()); !reflect.DeepEqual(got, values[i]) {
+			t.Errorf("%s: got %#v, want %#v", elmt.Name(), got, values[i])
+		}
+	}
+}
+
+// EOF
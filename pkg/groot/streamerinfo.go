@@ -25,6 +25,22 @@ func (si *StreamerInfo) Title() string {
 	return si.title
 }
 
+// CheckSum returns the checksum of the class layout
+func (si *StreamerInfo) CheckSum() uint32 {
+	return si.checksum
+}
+
+// ClassVersion returns the version of the class described by this StreamerInfo
+func (si *StreamerInfo) ClassVersion() int {
+	return int(si.classvers)
+}
+
+// Elements returns the streamer elements describing the members of the class.
+// An element is nil if its type is not known to groot.
+func (si *StreamerInfo) Elements() []StreamerElement {
+	return si.elmts
+}
+
 func (si *StreamerInfo) ROOTDecode(b *Buffer) (err error) {
 
 	spos := b.Pos()
@@ -55,11 +71,26 @@ func (si *StreamerInfo) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (si *StreamerInfo) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(9)
+	b.write_tnamed(si.name, si.title)
+	b.u4ton(si.checksum)
+	b.u4ton(si.classvers)
+	elmts := make([]Object, 0, len(si.elmts))
+	for _, v := range si.elmts {
+		if v, ok := v.(Object); ok {
+			elmts = append(elmts, v)
+		}
+	}
+	err = b.write_elements(elmts)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
 	return
 }
 
 type StreamerElement interface {
+	Class() string
 	Name() string
 	Title() string
 	Type() int       // element type
@@ -132,7 +163,7 @@ func (se *seBase) ROOTDecode(b *Buffer) (err error) {
 	vers, pos, bcnt := b.read_version()
 	printf("[streamerelmt] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
 	if vers < 2 {
-		panic("groot.StreamerElement.ROOTDecode: version<2 is not supported")
+		return fmt.Errorf("groot: version %d of TStreamerElement is not supported", vers)
 	}
 	se.name, se.title = b.read_tnamed()
 	se.etype = int(b.ntoi4())
@@ -147,7 +178,17 @@ func (se *seBase) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (se *seBase) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(4)
+	b.write_tnamed(se.name, se.title)
+	b.i4ton(int32(se.etype))
+	b.i4ton(int32(se.esize))
+	b.i4ton(int32(se.arrlen))
+	b.i4ton(int32(se.arrdim))
+	maxidx := make([]int32, 5) // FIXME: magic constant
+	copy(maxidx, se.maxidx)
+	b.write_fast_array_I(maxidx)
+	b.write_tstring(se.typename)
+	b.set_byte_count(pos)
 	return
 }
 
@@ -161,13 +202,18 @@ func (se *StreamerBase) Class() string {
 	return "TStreamerBase"
 }
 
+// BaseVersion returns the version number of the base class
+func (se *StreamerBase) BaseVersion() int {
+	return se.version
+}
+
 func (se *StreamerBase) ROOTDecode(b *Buffer) (err error) {
 	spos := b.Pos()
 
 	vers, pos, bcnt := b.read_version()
 	printf("[streamerbase] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
 	if vers < 2 {
-		panic("groot.StreamerBase.ROOTDecode: version<2 is not supported")
+		return fmt.Errorf("groot: version %d of TStreamerBase is not supported", vers)
 	}
 	err = se.seBase.ROOTDecode(b)
 	if err != nil {
@@ -179,7 +225,13 @@ func (se *StreamerBase) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (se *StreamerBase) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(3)
+	err = se.seBase.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.i4ton(int32(se.version))
+	b.set_byte_count(pos)
 	return
 }
 
@@ -198,7 +250,7 @@ func (se *StreamerBasicType) ROOTDecode(b *Buffer) (err error) {
 	vers, pos, bcnt := b.read_version()
 	printf("[streamerbasictype] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
 	if vers < 2 {
-		panic("groot.StreamerBasicType.ROOTDecode: version<2 is not supported")
+		return fmt.Errorf("groot: version %d of TStreamerBasicType is not supported", vers)
 	}
 	err = se.seBase.ROOTDecode(b)
 	if err != nil {
@@ -209,7 +261,12 @@ func (se *StreamerBasicType) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (se *StreamerBasicType) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(2)
+	err = se.seBase.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
 	return
 }
 
@@ -225,13 +282,28 @@ func (se *StreamerBasicPointer) Class() string {
 	return "TStreamerBasicPointer"
 }
 
+// CountVersion returns the version number of the class with the counter
+func (se *StreamerBasicPointer) CountVersion() int {
+	return se.countvers
+}
+
+// CountName returns the name of the data member holding the array count
+func (se *StreamerBasicPointer) CountName() string {
+	return se.countname
+}
+
+// CountClass returns the name of the class with the counter
+func (se *StreamerBasicPointer) CountClass() string {
+	return se.countclass
+}
+
 func (se *StreamerBasicPointer) ROOTDecode(b *Buffer) (err error) {
 	spos := b.Pos()
 
 	vers, pos, bcnt := b.read_version()
 	printf("[streamerbasicptr] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
 	if vers < 2 {
-		panic("groot.StreamerBasicPointer.ROOTDecode: version<2 is not supported")
+		return fmt.Errorf("groot: version %d of TStreamerBasicPointer is not supported", vers)
 	}
 	err = se.seBase.ROOTDecode(b)
 	if err != nil {
@@ -249,7 +321,79 @@ func (se *StreamerBasicPointer) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (se *StreamerBasicPointer) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(2)
+	err = se.seBase.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.i4ton(int32(se.countvers))
+	b.write_tstring(se.countname)
+	b.write_tstring(se.countclass)
+	b.set_byte_count(pos)
+	return
+}
+
+// StreamerLoop is a streamer element for a pointer to an array of objects
+// (or of pointers to objects), of size given by another data member
+type StreamerLoop struct {
+	seBase
+	countvers  int    // version number of the class with the counter
+	countname  string // name of the data member holding the array count
+	countclass string // name of the class with the counter
+}
+
+func (se *StreamerLoop) Class() string {
+	return "TStreamerLoop"
+}
+
+// CountVersion returns the version number of the class with the counter
+func (se *StreamerLoop) CountVersion() int {
+	return se.countvers
+}
+
+// CountName returns the name of the data member holding the array count
+func (se *StreamerLoop) CountName() string {
+	return se.countname
+}
+
+// CountClass returns the name of the class with the counter
+func (se *StreamerLoop) CountClass() string {
+	return se.countclass
+}
+
+func (se *StreamerLoop) ROOTDecode(b *Buffer) (err error) {
+	spos := b.Pos()
+
+	vers, pos, bcnt := b.read_version()
+	printf("[streamerloop] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
+	if vers < 2 {
+		return fmt.Errorf("groot: version %d of TStreamerLoop is not supported", vers)
+	}
+	err = se.seBase.ROOTDecode(b)
+	if err != nil {
+		return err
+	}
+
+	se.countvers = int(b.ntoi4())
+	se.countname = b.read_tstring()
+	se.countclass = b.read_tstring()
+	printf("[streamerloop] cntvers=%v name=%v cls=%v\n",
+		se.countvers, se.countname, se.countclass)
+
+	b.check_byte_count(pos, bcnt, spos, "TStreamerLoop")
+	return
+}
+
+func (se *StreamerLoop) ROOTEncode(b *Buffer) (err error) {
+	pos := b.write_version(2)
+	err = se.seBase.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.i4ton(int32(se.countvers))
+	b.write_tstring(se.countname)
+	b.write_tstring(se.countclass)
+	b.set_byte_count(pos)
 	return
 }
 
@@ -268,7 +412,7 @@ func (se *StreamerString) ROOTDecode(b *Buffer) (err error) {
 	vers, pos, bcnt := b.read_version()
 	printf("[streamerstring] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
 	if vers < 2 {
-		panic("groot.StreamerString.ROOTDecode: version<2 is not supported")
+		return fmt.Errorf("groot: version %d of TStreamerString is not supported", vers)
 	}
 	err = se.seBase.ROOTDecode(b)
 	if err != nil {
@@ -279,7 +423,12 @@ func (se *StreamerString) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (se *StreamerString) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(2)
+	err = se.seBase.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
 	return
 }
 
@@ -298,15 +447,15 @@ func NewStreamerObject(name, title string, offset int, typename string) *Streame
 			name:     name,
 			title:    title,
 			offset:   offset,
-			etype:    kObject,
+			etype:    KObject,
 			typename: typename,
 		},
 	}
 	switch name {
 	case "TObject":
-		o.seBase.etype = kTObject
+		o.seBase.etype = KTObject
 	case "TNamed":
-		o.seBase.etype = kTNamed
+		o.seBase.etype = KTNamed
 	}
 	return o
 }
@@ -317,7 +466,7 @@ func (se *StreamerObject) ROOTDecode(b *Buffer) (err error) {
 	vers, pos, bcnt := b.read_version()
 	printf("[streamerobj] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
 	if vers < 2 {
-		panic("groot.StreamerObject.ROOTDecode: version<2 is not supported")
+		return fmt.Errorf("groot: version %d of TStreamerObject is not supported", vers)
 	}
 	err = se.seBase.ROOTDecode(b)
 	if err != nil {
@@ -328,7 +477,12 @@ func (se *StreamerObject) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (se *StreamerObject) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(2)
+	err = se.seBase.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
 	return
 }
 
@@ -347,7 +501,7 @@ func (se *StreamerObjectPointer) ROOTDecode(b *Buffer) (err error) {
 	vers, pos, bcnt := b.read_version()
 	printf("[streamerobjptr] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
 	if vers < 2 {
-		panic("groot.StreamerObjectPointer.ROOTDecode: version<2 is not supported")
+		return fmt.Errorf("groot: version %d of TStreamerObjectPointer is not supported", vers)
 	}
 	err = se.seBase.ROOTDecode(b)
 	if err != nil {
@@ -358,7 +512,12 @@ func (se *StreamerObjectPointer) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (se *StreamerObjectPointer) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(2)
+	err = se.seBase.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
 	return
 }
 
@@ -377,7 +536,7 @@ func (se *StreamerObjectAny) ROOTDecode(b *Buffer) (err error) {
 	vers, pos, bcnt := b.read_version()
 	printf("[streamerobjany] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
 	if vers < 2 {
-		panic("groot.StreamerObjectAny.ROOTDecode: version<2 is not supported")
+		return fmt.Errorf("groot: version %d of TStreamerObjectAny is not supported", vers)
 	}
 	err = se.seBase.ROOTDecode(b)
 	if err != nil {
@@ -388,7 +547,12 @@ func (se *StreamerObjectAny) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (se *StreamerObjectAny) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(2)
+	err = se.seBase.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
 	return
 }
 
@@ -403,13 +567,23 @@ func (se *StreamerSTL) Class() string {
 	return "TStreamerSTL"
 }
 
+// STLType returns the type of STL container (see ROOT::ESTLType)
+func (se *StreamerSTL) STLType() int {
+	return se.stltype
+}
+
+// ContainedType returns the type of the contained objects
+func (se *StreamerSTL) ContainedType() int {
+	return se.ctype
+}
+
 func (se *StreamerSTL) ROOTDecode(b *Buffer) (err error) {
 	spos := b.Pos()
 
 	vers, pos, bcnt := b.read_version()
 	printf("[streamerstl] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
 	if vers < 2 {
-		panic("groot.StreamerSTL.ROOTDecode: version<2 is not supported")
+		return fmt.Errorf("groot: version %d of TStreamerSTL is not supported", vers)
 	}
 	err = se.seBase.ROOTDecode(b)
 	if err != nil {
@@ -424,7 +598,14 @@ func (se *StreamerSTL) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (se *StreamerSTL) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(3)
+	err = se.seBase.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.i4ton(int32(se.stltype))
+	b.i4ton(int32(se.ctype))
+	b.set_byte_count(pos)
 	return
 }
 
@@ -443,7 +624,7 @@ func (se *StreamerSTLstring) ROOTDecode(b *Buffer) (err error) {
 	vers, pos, bcnt := b.read_version()
 	printf("[streamerstlstr] vers=%v pos=%v bcnt=%v\n", vers, pos, bcnt)
 	if vers < 2 {
-		panic("groot.StreamerSTLstring.ROOTDecode: version<2 is not supported")
+		return fmt.Errorf("groot: version %d of TStreamerSTLstring is not supported", vers)
 	}
 	err = se.StreamerSTL.ROOTDecode(b)
 	if err != nil {
@@ -456,7 +637,12 @@ func (se *StreamerSTLstring) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (se *StreamerSTLstring) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(2)
+	err = se.StreamerSTL.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.set_byte_count(pos)
 	return
 }
 
@@ -509,6 +695,15 @@ func init() {
 		Factory.db["*groot.StreamerBasicPointer"] = f
 	}
 
+	{
+		f := func() reflect.Value {
+			o := &StreamerLoop{}
+			return reflect.ValueOf(o)
+		}
+		Factory.db["TStreamerLoop"] = f
+		Factory.db["*groot.StreamerLoop"] = f
+	}
+
 	{
 		f := func() reflect.Value {
 			o := &StreamerString{}
@@ -584,6 +779,10 @@ var _ Object = (*StreamerBasicPointer)(nil)
 var _ ROOTStreamer = (*StreamerBasicPointer)(nil)
 var _ StreamerElement = (*StreamerBasicPointer)(nil)
 
+var _ Object = (*StreamerLoop)(nil)
+var _ ROOTStreamer = (*StreamerLoop)(nil)
+var _ StreamerElement = (*StreamerLoop)(nil)
+
 var _ Object = (*StreamerString)(nil)
 var _ ROOTStreamer = (*StreamerString)(nil)
 var _ StreamerElement = (*StreamerString)(nil)
@@ -0,0 +1,151 @@
+package groot
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStreamerInfoReadWrite(t *testing.T) {
+	arr := se_basic("fArr", "fixed size array", KFloat, "float")
+	arr.arrlen = 3
+	arr.arrdim = 1
+	arr.maxidx = []int32{3}
+	want := &StreamerInfo{
+		name:      "Event",
+		checksum:  0xcafe,
+		classvers: 3,
+		elmts: append(append([]StreamerElement{}, generic_sinfos[1].elmts...),
+			arr,
+			&StreamerSTL{
+				seBase:  seBase{name: "fVec", etype: KSTL, typename: "vector<float>"},
+				stltype: 1,
+				ctype:   KFloat,
+			},
+			&StreamerSTLstring{StreamerSTL{
+				seBase:  seBase{name: "fStr", etype: KSTLstring, typename: "string"},
+				stltype: 365,
+				ctype:   KSTLstring,
+			}},
+		),
+	}
+
+	var got StreamerInfo
+	write_read(t, want, &got)
+	if got.Name() != want.Name() || got.CheckSum() != want.CheckSum() || got.ClassVersion() != want.ClassVersion() {
+		t.Fatalf("got name=%q checksum=0x%x version=%d", got.Name(), got.CheckSum(), got.ClassVersion())
+	}
+	if len(got.Elements()) != len(want.Elements()) {
+		t.Fatalf("got %d elements, want %d", len(got.Elements()), len(want.Elements()))
+	}
+
+	// the accessors of each kind of element
+	type counter interface {
+		CountVersion() int
+		CountName() string
+		CountClass() string
+	}
+	desc := func(se StreamerElement) []interface{} {
+		d := []interface{}{
+			se.Class(), se.Name(), se.Title(), se.Type(), se.TypeName(),
+			se.Size(), se.ArrLen(), se.ArrDim(),
+		}
+		if se.ArrDim() > 0 {
+			d = append(d, se.MaxIdx()[:se.ArrDim()])
+		}
+		switch se := se.(type) {
+		case *StreamerBase:
+			d = append(d, se.BaseVersion())
+		case counter:
+			d = append(d, se.CountVersion(), se.CountName(), se.CountClass())
+		case *StreamerSTL:
+			d = append(d, se.STLType(), se.ContainedType())
+		case *StreamerSTLstring:
+			d = append(d, se.STLType(), se.ContainedType())
+		}
+		return d
+	}
+	for i, se := range got.Elements() {
+		if se == nil {
+			t.Errorf("element #%d: unknown element", i)
+			continue
+		}
+		if got, want := desc(se), desc(want.elmts[i]); !reflect.DeepEqual(got, want) {
+			t.Errorf("element #%d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestFileStreamerInfos(t *testing.T) {
+	f := create_test_tree(t, 1).file
+
+	if len(f.StreamerInfos()) == 0 {
+		t.Fatalf("no streamer infos")
+	}
+	for _, si := range f.StreamerInfos() {
+		if got, err := f.StreamerInfo(si.Name(), si.ClassVersion()); err != nil || got != si {
+			t.Errorf("%s: got %v, err=%v", si.Name(), got, err)
+		}
+	}
+
+	si, err := f.StreamerInfo("TTree", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if si.ClassVersion() != 20 {
+		t.Errorf("TTree: got version %d", si.ClassVersion())
+	}
+	var names []string
+	for _, se := range si.Elements() {
+		if se, ok := se.(*StreamerBase); ok {
+			names = append(names, se.Name())
+		}
+	}
+	if want := []string{"TNamed", "TAttLine", "TAttFill", "TAttMarker"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("TTree: got bases %v, want %v", names, want)
+	}
+
+	for _, tc := range []struct {
+		name string
+		vers int
+	}{
+		{"TTree", 3},
+		{"Event", -1},
+	} {
+		_, err := f.StreamerInfo(tc.name, tc.vers)
+		if err == nil || !strings.Contains(err.Error(), "no StreamerInfo for class ["+tc.name+"]") {
+			t.Errorf("%s: got err=%v", tc.name, err)
+		}
+	}
+}
+
+func TestStreamerElementOldVersion(t *testing.T) {
+	for _, se := range []StreamerElement{
+		&StreamerBase{},
+		&StreamerBasicType{},
+		&StreamerBasicPointer{},
+		&StreamerString{},
+		&StreamerObject{},
+		&StreamerObjectPointer{},
+	} {
+		w, err := NewWBuffer(binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pos := w.write_version(1)
+		w.set_byte_count(pos)
+		b, err := NewBuffer(w.Bytes(), binary.BigEndian, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		class := "T" + reflect.TypeOf(se).Elem().Name()
+		want := "groot: version 1 of " + class + " is not supported"
+		err = se.(ROOTStreamer).ROOTDecode(b)
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: got err=%v, want %q", class, err, want)
+		}
+	}
+}
+
+// EOF
@@ -0,0 +1,495 @@
+package groot
+
+import (
+	"strings"
+)
+
+// builtin_sinfos holds the StreamerInfos of the classes groot writes, as
+// ROOT would generate them from its dictionaries.
+// they are stored into the files created by groot, so that ROOT (and groot)
+// can read back the objects without a dictionary.
+var builtin_sinfos = make(map[string]*StreamerInfo)
+
+// add_builtin_sinfo registers the StreamerInfo of a class.
+// the StreamerInfos of the base classes must have been registered first.
+func add_builtin_sinfo(name string, vers int, elmts ...StreamerElement) {
+	si := &StreamerInfo{
+		name:      name,
+		classvers: uint32(vers),
+		elmts:     elmts,
+	}
+	si.checksum = sinfo_checksum(si)
+	builtin_sinfos[name] = si
+}
+
+// sinfo_checksum computes the checksum of the class layout described by si.
+// see TStreamerInfo::GetCheckSum
+func sinfo_checksum(si *StreamerInfo) uint32 {
+	id := uint32(0)
+	hash := func(s string) {
+		for i := 0; i < len(s); i++ {
+			id = id*3 + uint32(s[i])
+		}
+	}
+
+	hash(si.name)
+	for _, se := range si.elmts {
+		base, ok := se.(*StreamerBase)
+		if !ok {
+			continue
+		}
+		hash(base.name)
+		if bsi, ok := builtin_sinfos[base.name]; ok {
+			id = id*3 + bsi.checksum
+		}
+	}
+
+	for _, se := range si.elmts {
+		if _, ok := se.(*StreamerBase); ok {
+			continue
+		}
+		if se.Type() == KInt && !is_builtin_type(se.TypeName()) {
+			// an enum
+			id = id*3 + 1
+		}
+		hash(se.Name())
+		hash(se.TypeName())
+		for i := 0; i < se.ArrDim(); i++ {
+			id = id*3 + uint32(se.MaxIdx()[i])
+		}
+		// the counter of a variable-size array
+		title := strings.TrimLeft(se.Title(), " \t*")
+		if strings.HasPrefix(title, "[") {
+			if end := strings.Index(title, "]"); end > 0 {
+				hash(title[1:end])
+			}
+		}
+	}
+	return id
+}
+
+func is_builtin_type(name string) bool {
+	switch name {
+	case "char", "unsigned char", "short", "unsigned short",
+		"int", "unsigned int", "long", "unsigned long",
+		"Long64_t", "ULong64_t", "float", "double", "bool":
+		return true
+	}
+	return false
+}
+
+// size of the builtin types, by streamer element type
+var builtin_sizes = map[int]int{
+	KChar:    1,
+	KShort:   2,
+	KInt:     4,
+	KLong:    8,
+	KFloat:   4,
+	KCounter: 4,
+	KDouble:  8,
+	KUChar:   1,
+	KUShort:  2,
+	KUInt:    4,
+	KULong:   8,
+	KBits:    4,
+	KLong64:  8,
+	KULong64: 8,
+	KBool:    1,
+}
+
+// se_base returns the element of the base class name, of the given version
+func se_base(name, title string, vers int) *StreamerBase {
+	etype := KBase
+	switch name {
+	case "TObject":
+		etype = KTObject
+	case "TNamed":
+		etype = KTNamed
+	}
+	return &StreamerBase{
+		seBase: seBase{
+			name:     name,
+			title:    title,
+			etype:    etype,
+			typename: "BASE",
+		},
+		version: vers,
+	}
+}
+
+// se_basic returns the element of a member of a builtin type
+func se_basic(name, title string, etype int, typename string) *StreamerBasicType {
+	return &StreamerBasicType{
+		seBase: seBase{
+			name:     name,
+			title:    title,
+			etype:    etype,
+			esize:    builtin_sizes[etype],
+			typename: typename,
+		},
+	}
+}
+
+// se_basic_ptr returns the element of a pointer to an array of a builtin
+// type, of size the member count of the class cclass (version cvers)
+func se_basic_ptr(name, title string, etype int, typename, count, cclass string, cvers int) *StreamerBasicPointer {
+	return &StreamerBasicPointer{
+		seBase: seBase{
+			name:     name,
+			title:    title,
+			etype:    KOffsetP + etype,
+			esize:    8,
+			typename: typename + "*",
+		},
+		countvers:  cvers,
+		countname:  count,
+		countclass: cclass,
+	}
+}
+
+// se_tstring returns the element of a TString member
+func se_tstring(name, title string) *StreamerString {
+	return &StreamerString{
+		seBase: seBase{
+			name:     name,
+			title:    title,
+			etype:    KTString,
+			esize:    24,
+			typename: "TString",
+		},
+	}
+}
+
+// se_object returns the element of a member of a class deriving from TObject
+func se_object(name, title, typename string) *StreamerObject {
+	return NewStreamerObject(name, title, 0, typename)
+}
+
+// se_any returns the element of a member of a class not deriving from TObject
+func se_any(name, title, typename string) *StreamerObjectAny {
+	return &StreamerObjectAny{
+		seBase: seBase{
+			name:     name,
+			title:    title,
+			etype:    KAny,
+			typename: typename,
+		},
+	}
+}
+
+// se_object_ptr returns the element of a pointer to an object.
+// a title starting with "->" denotes a pointer which is never null.
+func se_object_ptr(name, title, typename string) *StreamerObjectPointer {
+	etype := KObjectP
+	if strings.HasPrefix(title, "->") {
+		etype = KObjectp
+	}
+	return &StreamerObjectPointer{
+		seBase: seBase{
+			name:     name,
+			title:    title,
+			etype:    etype,
+			esize:    8,
+			typename: typename + "*",
+		},
+	}
+}
+
+// add_streamer_info registers the StreamerInfo of the named class, and of the
+// classes it depends on, into the streamer infos written to the file.
+// classes groot does not know the StreamerInfo of are ignored.
+func (f *File) add_streamer_info(class string) {
+	si, ok := builtin_sinfos[class]
+	if !ok || f.find_streamer_info(class, si.ClassVersion()) != nil {
+		return
+	}
+	f.sinfos = append(f.sinfos, si)
+
+	for _, se := range si.elmts {
+		switch se := se.(type) {
+		case *StreamerBase:
+			f.add_streamer_info(se.Name())
+		case *StreamerObject, *StreamerObjectAny, *StreamerObjectPointer:
+			f.add_streamer_info(strings.TrimSuffix(se.TypeName(), "*"))
+		}
+	}
+}
+
+func init() {
+	add_builtin_sinfo("TObject", 1,
+		se_basic("fUniqueID", "object unique identifier", KUInt, "unsigned int"),
+		se_basic("fBits", "bit field status word", KBits, "unsigned int"),
+	)
+	add_builtin_sinfo("TNamed", 1,
+		se_base("TObject", "Basic ROOT object", 1),
+		se_tstring("fName", "object identifier"),
+		se_tstring("fTitle", "object title"),
+	)
+	add_builtin_sinfo("TObjString", 1,
+		se_base("TObject", "Basic ROOT object", 1),
+		se_tstring("fString", "wrapped TString"),
+	)
+
+	// collections
+	add_builtin_sinfo("TCollection", 3,
+		se_base("TObject", "Basic ROOT object", 1),
+		se_tstring("fName", "name of the collection"),
+		se_basic("fSize", "number of elements in collection", KInt, "int"),
+	)
+	add_builtin_sinfo("TSeqCollection", 0,
+		se_base("TCollection", "Collection abstract base class", 3),
+		// not streamed (version 0), but part of the checksum of the class
+		se_basic("fSorted", "true if collection has been sorted", KBool, "bool"),
+	)
+	add_builtin_sinfo("TList", 5,
+		se_base("TSeqCollection", "Sequenceable collection ABC", 0),
+	)
+	add_builtin_sinfo("THashList", 0,
+		se_base("TList", "Doubly linked list", 5),
+	)
+	add_builtin_sinfo("TObjArray", 3,
+		se_base("TSeqCollection", "Sequenceable collection ABC", 0),
+		se_basic("fLowerBound", "Lower bound of the array", KInt, "int"),
+		se_basic("fLast", "Last element in array containing an object", KInt, "int"),
+	)
+
+	// arrays
+	add_builtin_sinfo("TArray", 1,
+		se_basic("fN", "Number of array elements", KCounter, "int"),
+	)
+	add_builtin_sinfo("TArrayD", 1,
+		se_base("TArray", "Abstract array base class", 1),
+		se_basic_ptr("fArray", "[fN] Array of fN doubles", KDouble, "double", "fN", "TArray", 1),
+	)
+	add_builtin_sinfo("TArrayF", 1,
+		se_base("TArray", "Abstract array base class", 1),
+		se_basic_ptr("fArray", "[fN] Array of fN floats", KFloat, "float", "fN", "TArray", 1),
+	)
+	add_builtin_sinfo("TArrayI", 1,
+		se_base("TArray", "Abstract array base class", 1),
+		se_basic_ptr("fArray", "[fN] Array of fN 32 bit integers", KInt, "int", "fN", "TArray", 1),
+	)
+
+	// attributes
+	add_builtin_sinfo("TAttLine", 2,
+		se_basic("fLineColor", "Line color", KShort, "short"),
+		se_basic("fLineStyle", "Line style", KShort, "short"),
+		se_basic("fLineWidth", "Line width", KShort, "short"),
+	)
+	add_builtin_sinfo("TAttFill", 2,
+		se_basic("fFillColor", "Fill area color", KShort, "short"),
+		se_basic("fFillStyle", "Fill area style", KShort, "short"),
+	)
+	add_builtin_sinfo("TAttMarker", 2,
+		se_basic("fMarkerColor", "Marker color", KShort, "short"),
+		se_basic("fMarkerStyle", "Marker style", KShort, "short"),
+		se_basic("fMarkerSize", "Marker size", KFloat, "float"),
+	)
+	add_builtin_sinfo("TAttAxis", 4,
+		se_basic("fNdivisions", "Number of divisions(10000*n3 + 100*n2 + n1)", KInt, "int"),
+		se_basic("fAxisColor", "Color of the line axis", KShort, "short"),
+		se_basic("fLabelColor", "Color of labels", KShort, "short"),
+		se_basic("fLabelFont", "Font for labels", KShort, "short"),
+		se_basic("fLabelOffset", "Offset of labels", KFloat, "float"),
+		se_basic("fLabelSize", "Size of labels", KFloat, "float"),
+		se_basic("fTickLength", "Length of tick marks", KFloat, "float"),
+		se_basic("fTitleOffset", "Offset of axis title", KFloat, "float"),
+		se_basic("fTitleSize", "Size of axis title", KFloat, "float"),
+		se_basic("fTitleColor", "Color of axis title", KShort, "short"),
+		se_basic("fTitleFont", "Font for axis title", KShort, "short"),
+	)
+
+	// histograms
+	add_builtin_sinfo("TAxis", 10,
+		se_base("TNamed", "The basis for a named object (name, title)", 1),
+		se_base("TAttAxis", "Axis attributes", 4),
+		se_basic("fNbins", "Number of bins", KInt, "int"),
+		se_basic("fXmin", "low edge of first bin", KDouble, "double"),
+		se_basic("fXmax", "upper edge of last bin", KDouble, "double"),
+		se_any("fXbins", "Bin edges array in X", "TArrayD"),
+		se_basic("fFirst", "first bin to display", KInt, "int"),
+		se_basic("fLast", "last bin to display", KInt, "int"),
+		se_basic("fBits2", "second bit status word", KUShort, "unsigned short"),
+		se_basic("fTimeDisplay", "on/off displaying time values instead of numerics", KBool, "bool"),
+		se_tstring("fTimeFormat", "Date&time format, ex: 09/12/99 12:34:00"),
+		se_object_ptr("fLabels", "List of labels", "THashList"),
+		se_object_ptr("fModLabs", "List of modified labels", "TList"),
+	)
+	add_builtin_sinfo("TH1", 8,
+		se_base("TNamed", "The basis for a named object (name, title)", 1),
+		se_base("TAttLine", "Line attributes", 2),
+		se_base("TAttFill", "Fill area attributes", 2),
+		se_base("TAttMarker", "Marker attributes", 2),
+		se_basic("fNcells", "number of bins(1D), cells (2D) +U/Overflows", KInt, "int"),
+		se_object("fXaxis", "X axis descriptor", "TAxis"),
+		se_object("fYaxis", "Y axis descriptor", "TAxis"),
+		se_object("fZaxis", "Z axis descriptor", "TAxis"),
+		se_basic("fBarOffset", "(1000*offset) for bar charts or legos", KShort, "short"),
+		se_basic("fBarWidth", "(1000*width) for bar charts or legos", KShort, "short"),
+		se_basic("fEntries", "Number of entries", KDouble, "double"),
+		se_basic("fTsumw", "Total Sum of weights", KDouble, "double"),
+		se_basic("fTsumw2", "Total Sum of squares of weights", KDouble, "double"),
+		se_basic("fTsumwx", "Total Sum of weight*X", KDouble, "double"),
+		se_basic("fTsumwx2", "Total Sum of weight*X*X", KDouble, "double"),
+		se_basic("fMaximum", "Maximum value for plotting", KDouble, "double"),
+		se_basic("fMinimum", "Minimum value for plotting", KDouble, "double"),
+		se_basic("fNormFactor", "Normalization factor", KDouble, "double"),
+		se_any("fContour", "Array to display contour levels", "TArrayD"),
+		se_any("fSumw2", "Array of sum of squares of weights", "TArrayD"),
+		se_tstring("fOption", "histogram options"),
+		se_object_ptr("fFunctions", "->Pointer to list of functions (fits and user)", "TList"),
+		se_basic("fBufferSize", "fBuffer size", KCounter, "int"),
+		se_basic_ptr("fBuffer", "[fBufferSize] entry buffer", KDouble, "double", "fBufferSize", "TH1", 8),
+		se_basic("fBinStatErrOpt", "option for bin statistical errors", KInt, "TH1::EBinErrorOpt"),
+		se_basic("fStatOverflows", "per object flag to use under/overflows in statistics", KInt, "TH1::EStatOverflows"),
+	)
+	add_builtin_sinfo("TH1F", 3,
+		se_base("TH1", "1-Dim histogram base class", 8),
+		se_base("TArrayF", "Array of floats", 1),
+	)
+	add_builtin_sinfo("TH1D", 3,
+		se_base("TH1", "1-Dim histogram base class", 8),
+		se_base("TArrayD", "Array of doubles", 1),
+	)
+	add_builtin_sinfo("TH1I", 3,
+		se_base("TH1", "1-Dim histogram base class", 8),
+		se_base("TArrayI", "Array of ints", 1),
+	)
+	add_builtin_sinfo("TH2", 5,
+		se_base("TH1", "1-Dim histogram base class", 8),
+		se_basic("fScalefactor", "Scale factor", KDouble, "double"),
+		se_basic("fTsumwy", "Total Sum of weight*Y", KDouble, "double"),
+		se_basic("fTsumwy2", "Total Sum of weight*Y*Y", KDouble, "double"),
+		se_basic("fTsumwxy", "Total Sum of weight*X*Y", KDouble, "double"),
+	)
+	add_builtin_sinfo("TH2F", 4,
+		se_base("TH2", "2-Dim histogram base class", 5),
+		se_base("TArrayF", "Array of floats", 1),
+	)
+	add_builtin_sinfo("TH2D", 4,
+		se_base("TH2", "2-Dim histogram base class", 5),
+		se_base("TArrayD", "Array of doubles", 1),
+	)
+
+	// graphs
+	add_builtin_sinfo("TGraph", 4,
+		se_base("TNamed", "The basis for a named object (name, title)", 1),
+		se_base("TAttLine", "Line attributes", 2),
+		se_base("TAttFill", "Fill area attributes", 2),
+		se_base("TAttMarker", "Marker attributes", 2),
+		se_basic("fNpoints", "Number of points <= fMaxSize", KCounter, "int"),
+		se_basic_ptr("fX", "[fNpoints] array of X points", KDouble, "double", "fNpoints", "TGraph", 4),
+		se_basic_ptr("fY", "[fNpoints] array of Y points", KDouble, "double", "fNpoints", "TGraph", 4),
+		se_object_ptr("fFunctions", "Pointer to list of functions (fits and user)", "TList"),
+		se_object_ptr("fHistogram", "Pointer to histogram used for drawing axis", "TH1F"),
+		se_basic("fMinimum", "Minimum value for plotting along y", KDouble, "double"),
+		se_basic("fMaximum", "Maximum value for plotting along y", KDouble, "double"),
+	)
+	add_builtin_sinfo("TGraphErrors", 3,
+		se_base("TGraph", "Graph graphics class", 4),
+		se_basic_ptr("fEX", "[fNpoints] array of X errors", KDouble, "double", "fNpoints", "TGraph", 4),
+		se_basic_ptr("fEY", "[fNpoints] array of Y errors", KDouble, "double", "fNpoints", "TGraph", 4),
+	)
+	add_builtin_sinfo("TGraphAsymmErrors", 3,
+		se_base("TGraph", "Graph graphics class", 4),
+		se_basic_ptr("fEXlow", "[fNpoints] array of X low errors", KDouble, "double", "fNpoints", "TGraph", 4),
+		se_basic_ptr("fEXhigh", "[fNpoints] array of X high errors", KDouble, "double", "fNpoints", "TGraph", 4),
+		se_basic_ptr("fEYlow", "[fNpoints] array of Y low errors", KDouble, "double", "fNpoints", "TGraph", 4),
+		se_basic_ptr("fEYhigh", "[fNpoints] array of Y high errors", KDouble, "double", "fNpoints", "TGraph", 4),
+	)
+
+	// trees
+	add_builtin_sinfo("ROOT::TIOFeatures", 1,
+		se_basic("fIOBits", "", KUChar, "unsigned char"),
+	)
+	add_builtin_sinfo("TTree", 20,
+		se_base("TNamed", "The basis for a named object (name, title)", 1),
+		se_base("TAttLine", "Line attributes", 2),
+		se_base("TAttFill", "Fill area attributes", 2),
+		se_base("TAttMarker", "Marker attributes", 2),
+		se_basic("fEntries", "Number of entries", KLong64, "Long64_t"),
+		se_basic("fTotBytes", "Total number of bytes in all branches before compression", KLong64, "Long64_t"),
+		se_basic("fZipBytes", "Total number of bytes in all branches after compression", KLong64, "Long64_t"),
+		se_basic("fSavedBytes", "Number of autosaved bytes", KLong64, "Long64_t"),
+		se_basic("fFlushedBytes", "Number of auto-flushed bytes", KLong64, "Long64_t"),
+		se_basic("fWeight", "Tree weight (see TTree::SetWeight)", KDouble, "double"),
+		se_basic("fTimerInterval", "Timer interval in milliseconds", KInt, "int"),
+		se_basic("fScanField", "Number of runs before prompting in Scan", KInt, "int"),
+		se_basic("fUpdate", "Update frequency for EntryLoop", KInt, "int"),
+		se_basic("fDefaultEntryOffsetLen", "Initial Length of fEntryOffset table in the basket buffers", KInt, "int"),
+		se_basic("fNClusterRange", "Number of Cluster range in addition to the one defined by 'AutoFlush'", KCounter, "int"),
+		se_basic("fMaxEntries", "Maximum number of entries in case of circular buffers", KLong64, "Long64_t"),
+		se_basic("fMaxEntryLoop", "Maximum number of entries to process", KLong64, "Long64_t"),
+		se_basic("fMaxVirtualSize", "Maximum total size of buffers kept in memory", KLong64, "Long64_t"),
+		se_basic("fAutoSave", "Autosave tree when fAutoSave entries have been written or -fAutoSave (compressed) bytes produced", KLong64, "Long64_t"),
+		se_basic("fAutoFlush", "Auto-flush tree when fAutoFlush entries have been written or -fAutoFlush (compressed) bytes produced", KLong64, "Long64_t"),
+		se_basic("fEstimate", "Number of entries to estimate histogram limits", KLong64, "Long64_t"),
+		se_basic_ptr("fClusterRangeEnd", "[fNClusterRange] Last entry of a cluster range.", KLong64, "Long64_t", "fNClusterRange", "TTree", 20),
+		se_basic_ptr("fClusterSize", "[fNClusterRange] Number of entries in each cluster for a given range.", KLong64, "Long64_t", "fNClusterRange", "TTree", 20),
+		se_any("fIOFeatures", "IO features to define for newly-written baskets and branches.", "ROOT::TIOFeatures"),
+		se_object("fBranches", "List of Branches", "TObjArray"),
+		se_object("fLeaves", "Direct pointers to individual branch leaves", "TObjArray"),
+		se_object_ptr("fAliases", "List of aliases for expressions based on the tree branches.", "TList"),
+		se_any("fIndexValues", "Sorted index values", "TArrayD"),
+		se_any("fIndex", "Index of sorted values", "TArrayI"),
+		se_object_ptr("fTreeIndex", "Pointer to the tree Index (if any)", "TVirtualIndex"),
+		se_object_ptr("fFriends", "pointer to list of friend elements", "TList"),
+		se_object_ptr("fUserInfo", "pointer to a list of user objects associated to this Tree", "TList"),
+		se_object_ptr("fBranchRef", "Branch supporting the TRefTable (if any)", "TBranchRef"),
+	)
+	add_builtin_sinfo("TBranch", 13,
+		se_base("TNamed", "The basis for a named object (name, title)", 1),
+		se_base("TAttFill", "Fill area attributes", 2),
+		se_basic("fCompress", "Compression level and algorithm", KInt, "int"),
+		se_basic("fBasketSize", "Initial Size of  Basket Buffer", KInt, "int"),
+		se_basic("fEntryOffsetLen", "Initial Length of fEntryOffset table in the basket buffers", KInt, "int"),
+		se_basic("fWriteBasket", "Last basket number written", KInt, "int"),
+		se_basic("fEntryNumber", "Current entry number (last one filled in this branch)", KLong64, "Long64_t"),
+		se_any("fIOFeatures", "IO features for newly-created baskets.", "ROOT::TIOFeatures"),
+		se_basic("fOffset", "Offset of this branch", KInt, "int"),
+		se_basic("fMaxBaskets", "Maximum number of Baskets so far", KCounter, "int"),
+		se_basic("fSplitLevel", "Branch split level", KInt, "int"),
+		se_basic("fEntries", "Number of entries", KLong64, "Long64_t"),
+		se_basic("fFirstEntry", "Number of the first entry in this branch", KLong64, "Long64_t"),
+		se_basic("fTotBytes", "Total number of bytes in all leaves before compression", KLong64, "Long64_t"),
+		se_basic("fZipBytes", "Total number of bytes in all leaves after compression", KLong64, "Long64_t"),
+		se_object("fBranches", "-> List of Branches of this branch", "TObjArray"),
+		se_object("fLeaves", "-> List of leaves of this branch", "TObjArray"),
+		se_object("fBaskets", "-> List of baskets of this branch", "TObjArray"),
+		se_basic_ptr("fBasketBytes", "[fMaxBaskets] Length of baskets on file", KInt, "int", "fMaxBaskets", "TBranch", 13),
+		se_basic_ptr("fBasketEntry", "[fMaxBaskets] Table of first entry in each basket", KLong64, "Long64_t", "fMaxBaskets", "TBranch", 13),
+		se_basic_ptr("fBasketSeek", "[fMaxBaskets] Addresses of baskets on file", KLong64, "Long64_t", "fMaxBaskets", "TBranch", 13),
+		se_tstring("fFileName", "Name of file where buffers are stored (\"\" if in same file as Tree header)"),
+	)
+	add_builtin_sinfo("TLeaf", 2,
+		se_base("TNamed", "The basis for a named object (name, title)", 1),
+		se_basic("fLen", "Number of fixed length elements in the leaf's data.", KInt, "int"),
+		se_basic("fLenType", "Number of bytes for this data type", KInt, "int"),
+		se_basic("fOffset", "Offset in ClonesArray object (if one)", KInt, "int"),
+		se_basic("fIsRange", "(=kTRUE if leaf has a range, kFALSE otherwise).", KBool, "bool"),
+		se_basic("fIsUnsigned", "(=kTRUE if unsigned, kFALSE otherwise)", KBool, "bool"),
+		se_object_ptr("fLeafCount", "Pointer to Leaf count if variable length (we do not own the counter)", "TLeaf"),
+	)
+	for _, leaf := range []struct {
+		class    string
+		etype    int
+		typename string
+	}{
+		{"TLeafO", KBool, "bool"},
+		{"TLeafB", KChar, "char"},
+		{"TLeafS", KShort, "short"},
+		{"TLeafI", KInt, "int"},
+		{"TLeafL", KLong64, "Long64_t"},
+		{"TLeafF", KFloat, "float"},
+		{"TLeafD", KDouble, "double"},
+		{"TLeafC", KInt, "int"},
+	} {
+		add_builtin_sinfo(leaf.class, 1,
+			se_base("TLeaf", "Leaf: description of a Branch data type", 2),
+			se_basic("fMinimum", "Minimum value if leaf range is specified", leaf.etype, leaf.typename),
+			se_basic("fMaximum", "Maximum value if leaf range is specified", leaf.etype, leaf.typename),
+		)
+	}
+}
+
+// EOF
@@ -32,6 +32,9 @@ func (tree *Tree) SetFile(f *File) (err error) {
 		return
 	}
 	tree.file = f
+	for i := range tree.branches {
+		tree.branches[i].set_file(f)
+	}
 	return
 }
 
@@ -55,6 +58,101 @@ func (tree *Tree) Branches() []Branch {
 	return tree.branches
 }
 
+// Branch returns the branch whose name is given, or nil if no such branch
+// exists in this tree.
+func (tree *Tree) Branch(name string) *Branch {
+	return find_branch(tree.branches, name)
+}
+
+// find_leaf returns the leaf whose name is given, together with its branch.
+// A branch with a single leaf can also be looked up by its own name.
+func (tree *Tree) find_leaf(name string) (*Branch, ileaf) {
+	return find_leaf(tree.branches, name)
+}
+
+func find_leaf(branches []Branch, name string) (*Branch, ileaf) {
+	for i := range branches {
+		br := &branches[i]
+		for _, leaf := range br.leaves {
+			if leaf.Name() == name {
+				return br, leaf
+			}
+		}
+		if br.name == name && len(br.leaves) == 1 {
+			return br, br.leaves[0]
+		}
+		if br, leaf := find_leaf(br.branches, name); leaf != nil {
+			return br, leaf
+		}
+	}
+	return nil, nil
+}
+
+// attach_leaf_counts connects the variable-length leaves to their leaf-count.
+// the leaf-count is looked up by name, from the title of the leaf.
+// it also connects the branches holding the members of the objects of a
+// split collection to the branch holding the collection, as given by their
+// fBranchCount (or to their parent branch, for files which do not store it.)
+func (tree *Tree) attach_leaf_counts() {
+	// the branches are copied out of their TBranchElement when decoded:
+	// resolve the references to the branches of this tree.
+	resolve := func(ref *BranchElement, parent *Branch) *Branch {
+		if ref == nil {
+			return nil
+		}
+		if parent != nil && parent.name == ref.branch.name {
+			return parent
+		}
+		return tree.Branch(ref.branch.name)
+	}
+
+	var attach func(branches []Branch, parent *Branch)
+	attach = func(branches []Branch, parent *Branch) {
+		for i := range branches {
+			br := &branches[i]
+			if be := br.element; be != nil {
+				be.count = resolve(be.bcount, parent)
+				be.count2 = resolve(be.bcount2, parent)
+				switch be.btype {
+				case kBranchElemClonesNode, kBranchElemSTLNode:
+					if be.count == nil {
+						be.count = parent
+					}
+				default:
+					// only the members of the objects of a collection are
+					// read column-wise.
+					be.count = nil
+				}
+			}
+			for _, leaf := range br.leaves {
+				base := leaf.toBaseLeaf()
+				name := base.count_name()
+				if name == "" {
+					continue
+				}
+				if _, lc := tree.find_leaf(name); lc != nil {
+					base.leaf_count = lc
+				}
+			}
+			attach(br.branches, br)
+		}
+	}
+	attach(tree.branches, nil)
+}
+
+func find_branch(branches []Branch, name string) *Branch {
+	for i := range branches {
+		br := &branches[i]
+		if br.name == name {
+			return br
+		}
+		if br := find_branch(br.branches, name); br != nil {
+			return br
+		}
+	}
+	return nil
+}
+
 func (tree *Tree) ROOTDecode(b *Buffer) (err error) {
 	spos := b.Pos()
 	vers, pos, bcnt := b.read_version()
@@ -114,6 +212,10 @@ func (tree *Tree) ROOTDecode(b *Buffer) (err error) {
 		if vers >= 18 {
 			b.ntoi4() //fDefaultEntryOffsetLen
 		}
+		nclus := 0
+		if vers >= 19 {
+			nclus = int(b.ntoi4()) //fNClusterRange
+		}
 		b.ntoi8() //fMaxEntries
 		b.ntoi8() //fMaxEntryLoop
 		b.ntou8() //fMaxVirtualSize
@@ -122,6 +224,15 @@ func (tree *Tree) ROOTDecode(b *Buffer) (err error) {
 			b.ntoi8() //fAutoFlush
 		}
 		b.ntoi8() //fEstimate
+		if vers >= 19 {
+			b.ntobyte()
+			b.read_fast_array_L(nclus) //fClusterRangeEnd
+			b.ntobyte()
+			b.read_fast_array_L(nclus) //fClusterSize
+		}
+		if vers >= 20 {
+			b.read_iofeatures() //fIOFeatures
+		}
 	}
 
 	printf("=> (%s) entries=%v tot_bytes=%v zip_bytes=%v\n",
@@ -129,10 +240,19 @@ func (tree *Tree) ROOTDecode(b *Buffer) (err error) {
 
 	branches := b.read_obj_array()
 	printf("-- #nbranches: %v\n", len(branches))
+	if b.err != nil {
+		return b.err
+	}
 	tree.branches = make([]Branch, len(branches))
 	for i, v := range branches {
-		tree.branches[i] = *(v.(ibranch).toBranch())
+		br, ok := v.(ibranch)
+		if !ok {
+			return fmt.Errorf("groot: tree [%s]: invalid branch #%d (%T)", tree.name, i, v)
+		}
+		tree.branches[i] = *br.toBranch()
+		tree.branches[i].set_file(tree.file)
 	}
+	tree.attach_leaf_counts()
 	leaves := b.read_obj_array()
 	printf("-- #nleaves: %v\n", len(leaves))
 
@@ -159,7 +279,67 @@ func (tree *Tree) ROOTDecode(b *Buffer) (err error) {
 }
 
 func (tree *Tree) ROOTEncode(b *Buffer) (err error) {
-	panic("not implemented")
+	pos := b.write_version(20)
+	b.write_tnamed(tree.name, tree.title)
+	b.write_attline(1, 1, 1)
+	b.write_attfill(0, 1001)
+	b.write_attmarker(1, 1, 1)
+
+	b.u8ton(tree.entries)
+	b.u8ton(tree.tot_bytes)
+	b.u8ton(tree.zip_bytes)
+	b.u8ton(tree.zip_bytes) // fSavedBytes
+	b.i8ton(0)              // fFlushedBytes
+	b.dton(1)               // fWeight
+	b.i4ton(0)              // fTimerInterval
+	b.i4ton(25)             // fScanField
+	b.i4ton(0)              // fUpdate
+	b.i4ton(1000)           // fDefaultEntryOffsetLen
+	b.i4ton(0)              // fNClusterRange
+	b.i8ton(1000000000000)  // fMaxEntries
+	b.i8ton(1000000000000)  // fMaxEntryLoop
+	b.u8ton(0)              // fMaxVirtualSize
+	b.u8ton(0)              // fAutoSave
+	b.i8ton(0)              // fAutoFlush
+	b.i8ton(1000000)        // fEstimate
+	b.byteton(0)            // fClusterRangeEnd
+	b.byteton(0)            // fClusterSize
+	b.write_iofeatures()
+
+	branches := make([]Object, len(tree.branches))
+	leaves := make([]Object, 0, len(tree.branches))
+	var collect func(br *Branch)
+	collect = func(br *Branch) {
+		for _, leaf := range br.leaves {
+			leaves = append(leaves, leaf)
+		}
+		for i := range br.branches {
+			collect(&br.branches[i])
+		}
+	}
+	for i := range tree.branches {
+		branches[i] = &tree.branches[i]
+		collect(&tree.branches[i])
+	}
+	err = b.write_obj_array("", branches)
+	if err != nil {
+		return err
+	}
+	// the leaves have already been streamed with their branch:
+	// only references to them are written here.
+	err = b.write_obj_array("", leaves)
+	if err != nil {
+		return err
+	}
+
+	b.write_object(nil)  // fAliases
+	b.write_array_D(nil) // fIndexValues
+	b.write_array_I(nil) // fIndex
+	b.write_object(nil)  // fTreeIndex
+	b.write_object(nil)  // fFriends
+	b.write_object(nil)  // fUserInfo
+	b.write_object(nil)  // fBranchRef
+	b.set_byte_count(pos)
 	return
 }
 
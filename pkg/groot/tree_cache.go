@@ -0,0 +1,221 @@
+package groot
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	tree_cache_size = 16 * 1024 * 1024 // default number of bytes of baskets read at once
+	tree_cache_gap  = 16 * 1024        // baskets closer than this are read together
+)
+
+// CacheStats holds the statistics of the basket cache of a TreeReader
+type CacheStats struct {
+	Hits      int64 // number of baskets found in the cache
+	Misses    int64 // number of baskets read directly from the file
+	Reads     int64 // number of (coalesced) reads issued by the cache
+	BytesRead int64 // number of bytes read by the cache
+}
+
+// tree_cache reads the baskets of the branches read by a TreeReader, one
+// cluster of entries at a time: the baskets of a cluster are sorted by
+// location and read with a few large reads, while the baskets of the next
+// cluster are read ahead in the background.
+// see ROOT's TTreeCache
+type tree_cache struct {
+	file     *File
+	branches []*Branch // branches whose baskets are cached
+	size     int       // maximum number of bytes of baskets of a cluster
+
+	mu   sync.Mutex
+	cur  *tree_cluster // cluster holding the entries being read
+	next *tree_cluster // cluster following cur, read ahead
+
+	hits   int64
+	misses int64
+	nreads int64
+	nbytes int64
+}
+
+// tree_cluster holds the baskets of a range of entries
+type tree_cluster struct {
+	beg  int64       // first entry of the cluster
+	end  int64       // last entry (excluded) of the cluster
+	data *read_cache // baskets of the cluster
+	done chan struct{}
+}
+
+func new_tree_cache(f *File, branches []*Branch, size int) *tree_cache {
+	return &tree_cache{
+		file:     f,
+		branches: branches,
+		size:     size,
+	}
+}
+
+// stats returns the statistics of the cache
+func (tc *tree_cache) stats() CacheStats {
+	return CacheStats{
+		Hits:      atomic.LoadInt64(&tc.hits),
+		Misses:    atomic.LoadInt64(&tc.misses),
+		Reads:     atomic.LoadInt64(&tc.nreads),
+		BytesRead: atomic.LoadInt64(&tc.nbytes),
+	}
+}
+
+// load makes sure the baskets holding the given entry are cached, and starts
+// reading the baskets of the following cluster.
+func (tc *tree_cache) load(entry int64) {
+	tc.mu.Lock()
+	cur, next := tc.cur, tc.next
+	tc.mu.Unlock()
+	if cur != nil && cur.beg <= entry && entry < cur.end {
+		return
+	}
+
+	if next != nil && next.beg <= entry && entry < next.end {
+		cur = next
+	} else {
+		cur = tc.fill(entry, cur)
+	}
+	<-cur.done
+	next = tc.fill(cur.end, cur)
+
+	tc.mu.Lock()
+	tc.cur, tc.next = cur, next
+	tc.mu.Unlock()
+}
+
+// fill starts reading the baskets of the cluster starting at entry beg.
+// the cluster holds as many entries as possible, within the size of the
+// cache (but at least a basket of each branch.)
+// the baskets already held by the (fully read) cluster prev are not read
+// again.
+func (tc *tree_cache) fill(beg int64, prev *tree_cluster) *tree_cluster {
+	type candidate struct {
+		br    *Branch
+		j     int   // index of the basket in its branch
+		first int64 // first entry of the basket
+	}
+
+	cands := make([]candidate, 0, len(tc.branches))
+	for _, br := range tc.branches {
+		if beg >= br.entries {
+			continue
+		}
+		j0 := br.find_basket(beg)
+		if j0 < 0 {
+			continue
+		}
+		n := br.nbaskets()
+		if n > len(br.basketSeek) {
+			n = len(br.basketSeek)
+		}
+		// the baskets of a branch beyond the size of the cache can not be
+		// part of the cluster.
+		nbytes := 0
+		for j := j0; j < n && br.basketSeek[j] != 0 && nbytes <= tc.size; j++ {
+			first := br.basketEntry[j]
+			if j == j0 {
+				// the baskets holding the first entry come first
+				first = beg
+			}
+			cands = append(cands, candidate{br, j, first})
+			nbytes += int(br.basketBytes[j])
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].first < cands[j].first
+	})
+
+	c := &tree_cluster{
+		beg:  beg,
+		data: new_read_cache(int(^uint(0) >> 1)),
+		done: make(chan struct{}),
+	}
+
+	// take the baskets in the order of their entries, until the cache is full
+	last := make(map[*Branch]int) // last basket taken, by branch
+	spans := make([]span, 0, len(cands))
+	nbytes := 0
+	for _, cand := range cands {
+		pos := cand.br.basketSeek[cand.j]
+		sz := int(cand.br.basketBytes[cand.j])
+		if cand.first > beg && nbytes+sz > tc.size {
+			break
+		}
+		nbytes += sz
+		last[cand.br] = cand.j
+		if prev != nil && prev.data.has(pos, int64(sz)) {
+			buf := make([]byte, sz)
+			prev.data.get(buf, pos)
+			c.data.add(read_chunk{off: pos, data: buf})
+			continue
+		}
+		spans = append(spans, span{pos, int64(sz)})
+	}
+
+	// the cluster ends with the first entry missing in one of the branches
+	end := int64(-1)
+	for br, j := range last {
+		e := br.entries
+		if j+1 < br.nbaskets() {
+			e = br.basketEntry[j+1]
+		}
+		if end < 0 || e < end {
+			end = e
+		}
+	}
+	if end <= beg {
+		end = beg + 1
+	}
+	c.end = end
+
+	spans = merge_spans(spans, tree_cache_gap)
+	if len(spans) == 0 {
+		close(c.done)
+		return c
+	}
+	tc.file.bg.Add(1)
+	go tc.read(c, spans)
+	return c
+}
+
+// read reads the spans of the cluster c.
+// errors are ignored: the baskets which could not be read are read again,
+// directly from the file, when they are loaded.
+func (tc *tree_cache) read(c *tree_cluster, spans []span) {
+	defer tc.file.bg.Done()
+	defer close(c.done)
+	tc.file.prefetch(spans)
+	for _, s := range spans {
+		buf := make([]byte, int(s.len))
+		err := tc.file.read_at(buf, s.off)
+		if err != nil {
+			return
+		}
+		c.data.add(read_chunk{off: s.off, data: buf})
+		atomic.AddInt64(&tc.nreads, 1)
+		atomic.AddInt64(&tc.nbytes, s.len)
+	}
+}
+
+// get fills p with the cached data at pos, if available.
+// get may be called concurrently.
+func (tc *tree_cache) get(p []byte, pos int64) bool {
+	tc.mu.Lock()
+	cur, next := tc.cur, tc.next
+	tc.mu.Unlock()
+	for _, c := range []*tree_cluster{cur, next} {
+		if c != nil && c.data.get(p, pos) {
+			atomic.AddInt64(&tc.hits, 1)
+			return true
+		}
+	}
+	atomic.AddInt64(&tc.misses, 1)
+	return false
+}
+
+// EOF
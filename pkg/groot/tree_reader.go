@@ -0,0 +1,543 @@
+package groot
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TreeReader reads the content of a Tree, entry by entry.
+//
+//	r, err := tree.NewReader("px", "py")
+//	for r.Next() {
+//	    px := r.Value("px").(float64)
+//	    ...
+//	}
+//	err = r.Err()
+//
+// Several TreeReaders may read the same tree concurrently, each one from its
+// own goroutine: a TreeReader is not safe for concurrent use.
+type TreeReader struct {
+	tree     *Tree
+	branches []*Branch              // branches to read
+	leaves   map[string]ileaf       // leaves of the branches to read, by name
+	values   map[string]interface{} // values of the TBranchElements to read, by name
+	entry    int64                  // current entry
+	err      error                  // first error encountered while reading
+	binds    []leaf_binding         // struct fields filled by this reader
+
+	sem   chan struct{}           // limits the number of baskets decoded concurrently
+	jobs  map[*Branch]*basket_job // baskets being decoded, by branch
+	bkbrs []*Branch               // branches whose baskets are read
+	state *read_state             // current baskets and values of the branches being read
+}
+
+// basket_job is a basket being decoded in the background
+type basket_job struct {
+	j      int     // index of the basket in its branch
+	basket *Basket // decoded basket
+	err    error
+	done   chan struct{}
+}
+
+// read_state holds the state of the reading of a tree by a TreeReader: the
+// current basket and entry of each branch, and the values of the leaves for
+// the current entry.
+// the branches and leaves of a tree are shared by all its readers (and by
+// all the users of the Tree): they are not modified when reading.
+type read_state struct {
+	tc       *tree_cache               // cache of the baskets of the branches being read (if any)
+	branches map[*Branch]*branch_state // state of the branches, by branch
+	leaves   map[ileaf]interface{}     // values of the leaves for the current entry (see ileaf.read_basket)
+}
+
+// branch_state is the state of the reading of a branch
+type branch_state struct {
+	basket     *Basket // current basket
+	ibasket    int     // index of the current basket in the branch
+	entry      int64   // current entry (-1 if none)
+	prefetched [2]int  // range of baskets whose prefetching was requested
+}
+
+func new_read_state() *read_state {
+	return &read_state{
+		branches: make(map[*Branch]*branch_state),
+		leaves:   make(map[ileaf]interface{}),
+	}
+}
+
+// branch returns the state of branch br
+func (s *read_state) branch(br *Branch) *branch_state {
+	bs, ok := s.branches[br]
+	if !ok {
+		bs = &branch_state{ibasket: -1, entry: -1}
+		s.branches[br] = bs
+	}
+	return bs
+}
+
+// set_basket makes basket, the j-th basket of its branch, the current one
+func (bs *branch_state) set_basket(j int, basket *Basket) {
+	bs.basket = basket
+	bs.ibasket = j
+}
+
+// NewReader creates a new reader for the given branches of this tree.
+// All the top-level branches are read if no branch name is given.
+func (tree *Tree) NewReader(names ...string) (r *TreeReader, err error) {
+	branches := make([]*Branch, 0, len(names))
+	if len(names) == 0 {
+		for i := range tree.branches {
+			br := &tree.branches[i]
+			if !br.is_readable() {
+				printf("groot.TreeReader: skipping branch [%s]\n", br.name)
+				continue
+			}
+			branches = append(branches, br)
+		}
+	}
+
+	for _, name := range names {
+		br := tree.Branch(name)
+		if br == nil {
+			return nil, fmt.Errorf("groot: no branch [%s] in tree [%s]", name, tree.name)
+		}
+		branches = append(branches, br)
+	}
+	return tree.new_reader(branches)
+}
+
+func (tree *Tree) new_reader(branches []*Branch) (r *TreeReader, err error) {
+	if tree.file == nil {
+		return nil, fmt.Errorf("groot: tree [%s] is not attached to a file", tree.name)
+	}
+
+	r = &TreeReader{
+		tree:     tree,
+		branches: make([]*Branch, 0, len(branches)),
+		leaves:   make(map[string]ileaf),
+		values:   make(map[string]interface{}),
+		entry:    -1,
+		state:    new_read_state(),
+	}
+
+	for _, br := range branches {
+		if !br.is_readable() {
+			return nil, fmt.Errorf("groot: branch [%s] can not be read", br.name)
+		}
+		r.add_branch(br)
+	}
+	r.bkbrs = r.basket_branches()
+	r.SetCacheSize(tree_cache_size)
+	return r, err
+}
+
+// basket_branches returns the branches whose baskets are read by this
+// reader: the branches being read, their sub-branches and the branches
+// holding their counts.
+func (r *TreeReader) basket_branches() []*Branch {
+	brs := make([]*Branch, 0, len(r.branches))
+	seen := make(map[*Branch]bool)
+	var add func(br *Branch)
+	add = func(br *Branch) {
+		if br == nil || seen[br] {
+			return
+		}
+		seen[br] = true
+		brs = append(brs, br)
+		if br.element != nil {
+			add(br.element.count)
+			add(br.element.count2)
+		}
+		for _, leaf := range br.leaves {
+			if count := leaf.toBaseLeaf().leaf_count; count != nil {
+				add(count.toBaseLeaf().branch)
+			}
+		}
+		for i := range br.branches {
+			add(&br.branches[i])
+		}
+	}
+	for _, br := range r.branches {
+		add(br)
+	}
+	return brs
+}
+
+// SetCacheSize sets the maximum number of bytes of baskets read at once by
+// this reader.
+// The baskets of the branches being read are read ahead, for a range of
+// entries at a time, with a few large reads.
+// A size of zero disables the cache: each basket is then read on demand.
+func (r *TreeReader) SetCacheSize(n int) {
+	r.state.tc = nil
+	if n > 0 {
+		r.state.tc = new_tree_cache(r.tree.file, r.bkbrs, n)
+	}
+}
+
+// CacheStats returns the statistics of the basket cache of this reader
+func (r *TreeReader) CacheStats() CacheStats {
+	if r.state.tc == nil {
+		return CacheStats{}
+	}
+	return r.state.tc.stats()
+}
+
+func (r *TreeReader) add_branch(br *Branch) {
+	r.branches = append(r.branches, br)
+	if br.element != nil {
+		// the value of a TBranchElement is an object (or a data member),
+		// rebuilt from its StreamerInfo.
+		r.values[br.name] = nil
+		return
+	}
+	for _, leaf := range br.leaves {
+		r.leaves[leaf.Name()] = leaf
+	}
+	if len(br.leaves) == 1 {
+		r.leaves[br.name] = br.leaves[0]
+	}
+}
+
+// NewStructReader creates a new reader for this tree, which fills the struct
+// pointed at by ptr, entry by entry.
+//
+// Each exported field of the struct is bound to the leaf named after the
+// field's "groot" tag (or after the field's name, if there is no such tag.)
+// Fields tagged with `groot:"-"` are ignored.
+// The type of each field is checked against the type of its leaf: e.g. an
+// Int_t leaf needs an int32 field, a UInt_t leaf a uint32 one.
+// Fixed-size array leaves (e.g. "px[3]/F") need an array or a slice field,
+// variable-length leaves (e.g. "px[n]/F") need a slice field.
+//
+//	type Event struct {
+//	    Px float64 `groot:"px"`
+//	    N  int32   `groot:"n"`
+//	}
+//	var evt Event
+//	r, err := tree.NewStructReader(&evt)
+//	for r.Next() {
+//	    fmt.Printf("px=%v n=%v\n", evt.Px, evt.N)
+//	}
+func (tree *Tree) NewStructReader(ptr interface{}) (r *TreeReader, err error) {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("groot: expected a pointer to a struct (got %T)", ptr)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	binds := make([]leaf_binding, 0, rt.NumField())
+	branches := make([]*Branch, 0, rt.NumField())
+	seen := make(map[*Branch]bool)
+	for i := 0; i < rt.NumField(); i++ {
+		ft := rt.Field(i)
+		if ft.PkgPath != "" {
+			// unexported field
+			continue
+		}
+		name := ft.Tag.Get("groot")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = ft.Name
+		}
+		br, leaf := tree.find_leaf(name)
+		if leaf == nil {
+			return nil, fmt.Errorf("groot: no leaf [%s] in tree [%s] (field %s.%s)",
+				name, tree.name, rt.Name(), ft.Name)
+		}
+		err = check_leaf_type(leaf, ft.Type)
+		if err != nil {
+			return nil, fmt.Errorf("groot: field %s.%s: %v", rt.Name(), ft.Name, err)
+		}
+		binds = append(binds, leaf_binding{leaf: leaf, field: rv.Field(i)})
+		if !seen[br] {
+			seen[br] = true
+			branches = append(branches, br)
+		}
+	}
+
+	if len(branches) == 0 {
+		return nil, fmt.Errorf("groot: no field of %s is bound to a leaf", rt.Name())
+	}
+
+	r, err = tree.new_reader(branches)
+	if err != nil {
+		return nil, err
+	}
+	r.binds = binds
+	return r, err
+}
+
+// SetConcurrency sets the number of goroutines decompressing and decoding
+// the baskets of the branches being read.
+// With n > 1, the baskets of the different branches are decoded in parallel,
+// and the next basket of each branch is decoded while the current one is
+// being read.
+// The values of the entries are still loaded by the goroutine calling Next
+// or Entry.
+func (r *TreeReader) SetConcurrency(n int) {
+	if n <= 1 {
+		r.sem = nil
+		r.jobs = nil
+		return
+	}
+	r.sem = make(chan struct{}, n)
+	r.jobs = make(map[*Branch]*basket_job)
+}
+
+// start_job starts decoding the j-th basket of branch br in the background
+func (r *TreeReader) start_job(br *Branch, j int) {
+	if j < len(br.basketSeek) && br.basketSeek[j] != 0 {
+		br.prefetch_baskets(j, r.state)
+	}
+	job := &basket_job{j: j, done: make(chan struct{})}
+	r.jobs[br] = job
+	sem, tc := r.sem, r.state.tc
+	go func() {
+		sem <- struct{}{}
+		job.basket, job.err = br.decode_basket(j, tc)
+		<-sem
+		close(job.done)
+	}()
+}
+
+// load_baskets loads the baskets holding the i-th entry, decoding them
+// concurrently, and starts decoding the baskets following them.
+func (r *TreeReader) load_baskets(i int64) (err error) {
+	wait := make([]*Branch, 0, len(r.bkbrs))
+	for _, br := range r.bkbrs {
+		if i >= br.entries {
+			continue
+		}
+		j := br.find_basket(i)
+		if bs := r.state.branch(br); j < 0 || (bs.basket != nil && bs.ibasket == j) {
+			continue
+		}
+		if job := r.jobs[br]; job == nil || job.j != j {
+			r.start_job(br, j)
+		}
+		wait = append(wait, br)
+	}
+
+	for _, br := range wait {
+		job := r.jobs[br]
+		<-job.done
+		delete(r.jobs, br)
+		if job.err != nil {
+			if err == nil {
+				err = job.err
+			}
+			continue
+		}
+		r.state.branch(br).set_basket(job.j, job.basket)
+		if next := job.j + 1; next < br.nbaskets() {
+			r.start_job(br, next)
+		}
+	}
+	return err
+}
+
+// Tree returns the tree this reader is reading from
+func (r *TreeReader) Tree() *Tree {
+	return r.tree
+}
+
+// Entries returns the number of entries of the underlying tree
+func (r *TreeReader) Entries() int64 {
+	return int64(r.tree.entries)
+}
+
+// Cur returns the index of the current entry (-1 if no entry was loaded)
+func (r *TreeReader) Cur() int64 {
+	return r.entry
+}
+
+// Next loads the next entry.
+// Next returns false when no entries are left or if an error occurred.
+func (r *TreeReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if r.entry+1 >= r.Entries() {
+		return false
+	}
+	r.err = r.Entry(r.entry + 1)
+	return r.err == nil
+}
+
+// Entry loads the i-th entry of the tree
+func (r *TreeReader) Entry(i int64) (err error) {
+	if i < 0 || i >= r.Entries() {
+		return fmt.Errorf("groot: entry %d out of range (entries=%d)", i, r.Entries())
+	}
+	if r.state.tc != nil {
+		r.state.tc.load(i)
+	}
+	if r.jobs != nil {
+		err = r.load_baskets(i)
+		if err != nil {
+			return fmt.Errorf("groot: tree [%s]: %w", r.tree.name, err)
+		}
+	}
+	for _, br := range r.branches {
+		if br.element != nil {
+			v, err := br.read_value(i, r.state)
+			if err != nil {
+				return fmt.Errorf("groot: tree [%s]: %w", r.tree.name, err)
+			}
+			r.values[br.name] = v
+			continue
+		}
+		err = br.load_entry(i, r.state)
+		if err != nil {
+			return fmt.Errorf("groot: tree [%s]: %w", r.tree.name, err)
+		}
+	}
+	for _, bind := range r.binds {
+		bind.set(r.state)
+	}
+	r.entry = i
+	return err
+}
+
+// Err returns the first error encountered by Next
+func (r *TreeReader) Err() error {
+	return r.err
+}
+
+// Value returns the value of the named leaf for the current entry.
+// The Go type of the value follows the ROOT type of the leaf: e.g. int8 for
+// Char_t, uint8 for UChar_t, uint32 for UInt_t, and a slice of those for
+// array leaves.
+// The value of a TBranchElement is the object it holds (a *GenericObject for
+// split branches), or the value of its data member for sub-branches.
+// Value returns nil if no such leaf is being read.
+func (r *TreeReader) Value(name string) interface{} {
+	if v, ok := r.values[name]; ok {
+		return v
+	}
+	leaf, ok := r.leaves[name]
+	if !ok {
+		return nil
+	}
+	return leaf.toBaseLeaf().value(r.state.leaves[leaf])
+}
+
+// leaf_binding binds a leaf to the field of a struct
+type leaf_binding struct {
+	leaf  ileaf
+	field reflect.Value
+}
+
+// set copies the value of the leaf for the current entry of the reader state
+// s into the field
+func (bind *leaf_binding) set(s *read_state) {
+	v := reflect.ValueOf(bind.leaf.toBaseLeaf().value(s.leaves[bind.leaf]))
+	field := bind.field
+	switch field.Kind() {
+	case reflect.Array, reflect.Slice:
+		if v.Kind() != reflect.Slice {
+			// single value
+			s := reflect.MakeSlice(reflect.SliceOf(v.Type()), 1, 1)
+			s.Index(0).Set(v)
+			v = s
+		}
+		n := v.Len()
+		if field.Kind() == reflect.Slice {
+			if field.Cap() < n {
+				field.Set(reflect.MakeSlice(field.Type(), n, n))
+			} else {
+				field.SetLen(n)
+			}
+		}
+		et := field.Type().Elem()
+		if v.Type().Elem() == et {
+			reflect.Copy(field, v)
+			return
+		}
+		for i := 0; i < n; i++ {
+			field.Index(i).Set(v.Index(i).Convert(et))
+		}
+	default:
+		field.Set(v.Convert(field.Type()))
+	}
+}
+
+// check_leaf_type checks whether values of leaf can be stored into
+// values of type rt.
+// the kind of rt must match the type of the leaf, signedness included.
+func check_leaf_type(leaf ileaf, rt reflect.Type) error {
+	var kind reflect.Kind
+	unsigned := leaf.toBaseLeaf().unsigned
+	switch leaf.(type) {
+	case *LeafO:
+		kind = reflect.Bool
+	case *LeafB:
+		kind = reflect.Int8
+		if unsigned {
+			kind = reflect.Uint8
+		}
+	case *LeafS:
+		kind = reflect.Int16
+		if unsigned {
+			kind = reflect.Uint16
+		}
+	case *LeafI:
+		kind = reflect.Int32
+		if unsigned {
+			kind = reflect.Uint32
+		}
+	case *LeafL:
+		kind = reflect.Int64
+		if unsigned {
+			kind = reflect.Uint64
+		}
+	case *LeafF:
+		kind = reflect.Float32
+	case *LeafD:
+		kind = reflect.Float64
+	case *LeafC:
+		kind = reflect.String
+	default:
+		return fmt.Errorf("leaf [%s] of type %s can not be bound", leaf.Name(), leaf.Class())
+	}
+
+	match := func(k reflect.Kind) bool {
+		return k == kind
+	}
+
+	length := int(leaf.toBaseLeaf().length)
+	_, isstr := leaf.(*LeafC)
+	if leaf.toBaseLeaf().leaf_count != nil && rt.Kind() != reflect.Slice {
+		return fmt.Errorf("leaf [%s] is variable-length (need a slice)", leaf.Name())
+	}
+	switch {
+	case rt.Kind() == reflect.Slice && !isstr:
+		if match(rt.Elem().Kind()) {
+			return nil
+		}
+	case rt.Kind() == reflect.Array && !isstr:
+		if rt.Len() != length {
+			return fmt.Errorf("leaf [%s] holds %d values (array holds %d)",
+				leaf.Name(), length, rt.Len())
+		}
+		if match(rt.Elem().Kind()) {
+			return nil
+		}
+	default:
+		if length > 1 && !isstr {
+			return fmt.Errorf("leaf [%s] holds %d values (need an array or a slice)",
+				leaf.Name(), length)
+		}
+		if match(rt.Kind()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("leaf [%s] of type %s (%v) can not be stored into a %v",
+		leaf.Name(), leaf.Class(), kind, rt)
+}
+
+// EOF
@@ -0,0 +1,374 @@
+package groot
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// create_test_tree creates a file holding the tree "tree", with nevts entries
+// of the branches "i32" (i) and "f64" (2*i).
+func create_test_tree(t *testing.T, nevts int) *Tree {
+	fname := filepath.Join(t.TempDir(), "tree.root")
+	{
+		f, err := Create(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewTreeWriter(f.Dir(), "tree", "my tree")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			i32 int32
+			f64 float64
+		)
+		err = w.Branch("i32", &i32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.Branch("f64", &f64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < nevts; i++ {
+			i32 = int32(i)
+			f64 = float64(i) * 2
+			err = w.Fill()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	obj, err := f.Get("tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj.(*Tree)
+}
+
+// check_test_entry checks the values of the current entry of a reader of
+// the tree created by create_test_tree
+func check_test_entry(r *TreeReader, i int64) error {
+	if got := r.Value("i32").(int32); got != int32(i) {
+		return fmt.Errorf("entry %d: got i32=%d", i, got)
+	}
+	if got := r.Value("f64").(float64); got != float64(i)*2 {
+		return fmt.Errorf("entry %d: got f64=%v", i, got)
+	}
+	return nil
+}
+
+func TestTreeReaderCache(t *testing.T) {
+	const nevts = 10000
+	tree := create_test_tree(t, nevts)
+
+	for _, conc := range []int{1, 4} {
+		t.Run(fmt.Sprintf("concurrency=%d", conc), func(t *testing.T) {
+			// two readers of the same branches, each with its own cache,
+			// read alternately.
+			readers := make([]*TreeReader, 2)
+			for i := range readers {
+				var err error
+				readers[i], err = tree.NewReader("i32", "f64")
+				if err != nil {
+					t.Fatal(err)
+				}
+				readers[i].SetConcurrency(conc)
+			}
+
+			// the second reader starts later and reads at half the pace
+			for i := int64(0); i < nevts; i++ {
+				for j, r := range readers {
+					if j == 1 && (i < 100 || i%2 == 0) {
+						continue
+					}
+					if !r.Next() {
+						t.Fatalf("reader #%d: entry %d: %v", j, r.Cur()+1, r.Err())
+					}
+					if err := check_test_entry(r, r.Cur()); err != nil {
+						t.Fatalf("reader #%d: %v", j, err)
+					}
+				}
+			}
+			if got, want := readers[1].Cur(), int64((nevts-100)/2-1); got != want {
+				t.Fatalf("reader #1: got entry %d, want %d", got, want)
+			}
+
+			for i, r := range readers {
+				stats := r.CacheStats()
+				if stats.Hits == 0 || stats.Misses != 0 {
+					t.Errorf("reader #%d: invalid cache stats %+v", i, stats)
+				}
+			}
+		})
+	}
+}
+
+func TestTreeReaderConcurrent(t *testing.T) {
+	const nevts = 20000
+	tree := create_test_tree(t, nevts)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		r, err := tree.NewReader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch i % 4 {
+		case 1:
+			r.SetConcurrency(4)
+		case 2:
+			r.SetCacheSize(0)
+		case 3:
+			r.SetCacheSize(0)
+			r.SetConcurrency(2)
+		}
+		wg.Add(1)
+		go func(i int, r *TreeReader) {
+			defer wg.Done()
+			// each reader goes through the entries in its own order
+			for j := int64(0); j < nevts; j += int64(i + 1) {
+				err := r.Entry(j)
+				if err == nil {
+					err = check_test_entry(r, j)
+				}
+				if err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("reader #%d: %v", i, err)
+		}
+	}
+}
+
+// values_event holds a value of each of the types of leaves
+type values_event struct {
+	B   bool      `groot:"b"`
+	I8  int8      `groot:"i8"`
+	U8  uint8     `groot:"u8"`
+	I16 int16     `groot:"i16"`
+	U16 uint16    `groot:"u16"`
+	I32 int32     `groot:"i32"`
+	U32 uint32    `groot:"u32"`
+	I64 int64     `groot:"i64"`
+	U64 uint64    `groot:"u64"`
+	F32 float32   `groot:"f32"`
+	F64 float64   `groot:"f64"`
+	Str string    `groot:"str"`
+	Arr [2]uint16 `groot:"arr"`
+}
+
+var values_evts = []values_event{
+	{true, -1, 255, -2, 65535, -3, 4294967295, -4, 18446744073709551615, 1.5, 2.5, "hello", [2]uint16{1, 65535}},
+	{false, 127, 1, 32767, 2, 2147483647, 3, 9223372036854775807, 4, -1.5, -2.5, "", [2]uint16{3, 4}},
+}
+
+// create_values_tree creates a file holding the tree "tree", with a branch
+// for each field of values_event, filled with values_evts.
+func create_values_tree(t *testing.T) *Tree {
+	fname := filepath.Join(t.TempDir(), "values.root")
+	{
+		f, err := Create(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewTreeWriter(f.Dir(), "tree", "my tree")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var evt values_event
+		rv := reflect.ValueOf(&evt).Elem()
+		for i := 0; i < rv.NumField(); i++ {
+			err = w.Branch(rv.Type().Field(i).Tag.Get("groot"), rv.Field(i).Addr().Interface())
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, e := range values_evts {
+			evt = e
+			err = w.Fill()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	obj, err := f.Get("tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj.(*Tree)
+}
+
+func TestTreeReaderValues(t *testing.T) {
+	tree := create_values_tree(t)
+	r, err := tree.NewReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r.Next() {
+		want := reflect.ValueOf(values_evts[r.Cur()])
+		for i := 0; i < want.NumField(); i++ {
+			name := want.Type().Field(i).Tag.Get("groot")
+			v := want.Field(i)
+			if v.Kind() == reflect.Array {
+				// arrays are read as slices
+				s := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), v.Len(), v.Len())
+				reflect.Copy(s, v)
+				v = s
+			}
+			got := r.Value(name)
+			if !reflect.DeepEqual(got, v.Interface()) {
+				t.Errorf("entry %d: %s: got %#v (%T), want %#v (%T)",
+					r.Cur(), name, got, got, v.Interface(), v.Interface())
+			}
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStructReader(t *testing.T) {
+	tree := create_values_tree(t)
+
+	var evt values_event
+	r, err := tree.NewStructReader(&evt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r.Next() {
+		if want := values_evts[r.Cur()]; evt != want {
+			t.Errorf("entry %d: got %+v, want %+v", r.Cur(), evt, want)
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// slices for arrays, ignored fields
+	var evt2 struct {
+		Arr []uint16 `groot:"arr"`
+		I32 int32    `groot:"i32"`
+		Foo float64  `groot:"-"`
+		bar float64
+	}
+	r, err = tree.NewStructReader(&evt2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r.Next() {
+		want := values_evts[r.Cur()]
+		if !reflect.DeepEqual(evt2.Arr, want.Arr[:]) || evt2.I32 != want.I32 {
+			t.Errorf("entry %d: got %+v", r.Cur(), evt2)
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStructReaderTypes(t *testing.T) {
+	tree := create_values_tree(t)
+
+	for _, tc := range []struct {
+		ptr interface{}
+		err string
+	}{
+		{
+			ptr: &struct {
+				I8 uint8 `groot:"i8"`
+			}{},
+			err: "leaf [i8] of type TLeafB (int8) can not be stored into a uint8",
+		},
+		{
+			ptr: &struct {
+				U8 int8 `groot:"u8"`
+			}{},
+			err: "leaf [u8] of type TLeafB (uint8) can not be stored into a int8",
+		},
+		{
+			ptr: &struct {
+				I32 uint32 `groot:"i32"`
+			}{},
+			err: "leaf [i32] of type TLeafI (int32) can not be stored into a uint32",
+		},
+		{
+			ptr: &struct {
+				U32 int32 `groot:"u32"`
+			}{},
+			err: "leaf [u32] of type TLeafI (uint32) can not be stored into a int32",
+		},
+		{
+			ptr: &struct {
+				U64 int64 `groot:"u64"`
+			}{},
+			err: "leaf [u64] of type TLeafL (uint64) can not be stored into a int64",
+		},
+		{
+			ptr: &struct {
+				F32 float64 `groot:"f32"`
+			}{},
+			err: "leaf [f32] of type TLeafF (float32) can not be stored into a float64",
+		},
+		{
+			ptr: &struct {
+				Arr [3]uint16 `groot:"arr"`
+			}{},
+			err: "leaf [arr] holds 2 values (array holds 3)",
+		},
+		{
+			ptr: &struct {
+				Arr uint16 `groot:"arr"`
+			}{},
+			err: "leaf [arr] holds 2 values (need an array or a slice)",
+		},
+		{
+			ptr: &struct{ Foo int32 }{},
+			err: "no leaf [Foo] in tree [tree]",
+		},
+		{
+			ptr: struct{ I32 int32 }{},
+			err: "expected a pointer to a struct",
+		},
+	} {
+		_, err := tree.NewStructReader(tc.ptr)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%T: got err=%v, want %q", tc.ptr, err, tc.err)
+		}
+	}
+}
+
+// EOF
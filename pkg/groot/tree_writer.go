@@ -0,0 +1,400 @@
+package groot
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+const (
+	kDefaultBasketSize  = 32000 // default size of the baskets (in bytes)
+	kDefaultCompression = 1     // default compression level
+)
+
+// TreeWriter writes a flat Tree, entry by entry.
+//
+//	w, err := groot.NewTreeWriter(f.Dir(), "tree", "my tree")
+//	var px float64
+//	var n int32
+//	err = w.Branch("px", &px)
+//	err = w.Branch("n", &n)
+//	for i := 0; i < 10; i++ {
+//	    px = float64(i)
+//	    n = int32(i)
+//	    err = w.Fill()
+//	}
+//	err = w.Close()
+type TreeWriter struct {
+	dir      *Directory
+	tree     *Tree
+	branches []*branch_writer
+	bufsize  int  // size of the baskets
+	compress int  // compression level (0: no compression)
+	closed   bool // whether the tree has been written out
+}
+
+// NewTreeWriter creates a new tree in directory dir.
+// The tree is written to file when the TreeWriter (or the file) is closed.
+func NewTreeWriter(dir *Directory, name, title string) (w *TreeWriter, err error) {
+	if !dir.file.writable {
+		return nil, fmt.Errorf("groot: file [%s] is not writable", dir.file.name)
+	}
+	tree, err := NewTree(dir.file, name, title)
+	if err != nil {
+		return nil, err
+	}
+	w = &TreeWriter{
+		dir:      dir,
+		tree:     tree,
+		branches: make([]*branch_writer, 0),
+		bufsize:  kDefaultBasketSize,
+		compress: kDefaultCompression,
+	}
+	dir.trees = append(dir.trees, w)
+	return w, err
+}
+
+// Tree returns the tree being written
+func (w *TreeWriter) Tree() *Tree {
+	return w.tree
+}
+
+// SetBasketSize sets the size (in bytes) above which baskets are flushed
+// to file.
+func (w *TreeWriter) SetBasketSize(n int) {
+	if n > 0 {
+		w.bufsize = n
+	}
+}
+
+// SetCompression sets the (zlib) compression level of the baskets,
+// from 0 (no compression) to 9.
+func (w *TreeWriter) SetCompression(lvl int) {
+	if lvl < 0 {
+		lvl = 0
+	}
+	if lvl > 9 {
+		lvl = 9
+	}
+	w.compress = lvl
+}
+
+// Branch creates a new branch, filled from the variable pointed at by ptr.
+// Supported types are bool, (u)int8, (u)int16, (u)int32, (u)int64,
+// float32, float64, string and fixed-size arrays of those (except string.)
+// Branches must be created before the first call to Fill.
+func (w *TreeWriter) Branch(name string, ptr interface{}) (err error) {
+	if w.closed {
+		return fmt.Errorf("groot: tree [%s] already written", w.tree.name)
+	}
+	if w.tree.entries > 0 {
+		return fmt.Errorf("groot: can not add branch [%s] to tree [%s] after Fill", name, w.tree.name)
+	}
+	if w.tree.Branch(name) != nil {
+		return fmt.Errorf("groot: branch [%s] already exists in tree [%s]", name, w.tree.name)
+	}
+
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("groot: expected a pointer (got %T)", ptr)
+	}
+
+	leaf, code, err := new_leaf(name, rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	br := Branch{
+		name:        name,
+		title:       leaf.Title() + "/" + code,
+		file:        w.dir.file,
+		leaves:      []ileaf{leaf},
+		branches:    make([]Branch, 0),
+		basketBytes: make([]int32, 0),
+		basketEntry: make([]int64, 0),
+		basketSeek:  make([]int64, 0),
+	}
+	bw := &branch_writer{
+		w:     w,
+		idx:   len(w.tree.branches),
+		value: rv.Elem(),
+	}
+	bw.buf, _ = NewWBuffer(w.dir.file.order, 0)
+	if _, ok := leaf.(*LeafC); ok {
+		// entries are of variable size
+		br.entryOffsetLen = 1000
+		bw.offsets = make([]int32, 0)
+	}
+	w.tree.branches = append(w.tree.branches, br)
+	w.branches = append(w.branches, bw)
+
+	// branches and leaves are written as elements of a TObjArray:
+	// their streamer infos are not reachable from the one of the tree.
+	w.dir.file.add_streamer_info("TBranch")
+	w.dir.file.add_streamer_info(leaf.Class())
+	return
+}
+
+// Fill writes the current values of the branches' variables as a new entry.
+func (w *TreeWriter) Fill() (err error) {
+	if w.closed {
+		return fmt.Errorf("groot: tree [%s] already written", w.tree.name)
+	}
+	for _, bw := range w.branches {
+		err = bw.fill()
+		if err != nil {
+			return err
+		}
+	}
+	w.tree.entries++
+	return
+}
+
+// Close flushes the pending baskets and writes the tree to its directory.
+func (w *TreeWriter) Close() (err error) {
+	if w.closed {
+		return
+	}
+	w.closed = true
+
+	for _, bw := range w.branches {
+		err = bw.flush()
+		if err != nil {
+			return err
+		}
+		br := bw.branch()
+		br.compress = 0
+		if w.compress > 0 {
+			br.compress = int32(100 + w.compress) // zlib
+		}
+		br.basketSize = int32(w.bufsize)
+
+		// the first entry of the next (not yet written) basket
+		br.basketBytes = append(br.basketBytes, 0)
+		br.basketEntry = append(br.basketEntry, br.entries)
+		br.basketSeek = append(br.basketSeek, 0)
+	}
+
+	for i := range w.tree.branches {
+		w.tree.branches[i].set_file(w.dir.file)
+	}
+	return w.dir.Put(w.tree.name, w.tree)
+}
+
+// branch_writer fills the baskets of a branch
+type branch_writer struct {
+	w       *TreeWriter
+	idx     int           // index of the branch in the tree
+	value   reflect.Value // variable holding the value of the next entry
+	buf     *Buffer       // content of the current basket
+	offsets []int32       // entry offsets in the current basket (variable-size entries only)
+	nev     int           // number of entries in the current basket
+	first   int64         // first entry of the current basket
+}
+
+func (bw *branch_writer) branch() *Branch {
+	return &bw.w.tree.branches[bw.idx]
+}
+
+// fill adds the current value to the basket, flushing it when full
+func (bw *branch_writer) fill() (err error) {
+	if bw.offsets != nil {
+		bw.offsets = append(bw.offsets, int32(bw.buf.Pos()))
+	}
+	write_value(bw.buf, bw.value)
+	bw.nev++
+
+	br := bw.branch()
+	br.entries++
+	br.entryNumber++
+
+	if bw.buf.Len() >= bw.w.bufsize {
+		err = bw.flush()
+	}
+	return
+}
+
+// flush writes the current basket to file.
+// see ROOT::TBranch::WriteBasket
+func (bw *branch_writer) flush() (err error) {
+	if bw.nev == 0 {
+		return
+	}
+	w := bw.w
+	f := w.dir.file
+	br := bw.branch()
+	data := bw.buf.Bytes()
+
+	basket := &Basket{
+		key: Key{
+			file:            f,
+			version:         4,
+			date:            time.Now(),
+			cycle:           1,
+			seek_parent_dir: w.dir.seek_dir,
+			class:           "TBasket",
+			name:            br.name,
+			title:           w.tree.name,
+		},
+		bufsz: uint32(w.bufsize),
+		nev:   uint32(bw.nev),
+	}
+	if f.end > g_START_BIG_FILE {
+		basket.key.version += 1000
+	}
+	keysz := basket.key.key_size() + basket_header_size
+	basket.key.keysz = uint16(keysz)
+	basket.last = uint32(keysz + len(data))
+
+	payload := data
+	if bw.offsets != nil {
+		basket.nev_bufsz = br.entryOffsetLen
+		b, err := NewWBuffer(f.order, 0)
+		if err != nil {
+			return err
+		}
+		b.write_nbytes(data)
+		b.i4ton(int32(len(bw.offsets)))
+		for _, offset := range bw.offsets {
+			b.i4ton(offset + int32(keysz))
+		}
+		payload = b.Bytes()
+	} else {
+		basket.nev_bufsz = uint32(len(data) / bw.nev)
+	}
+
+	zipped, err := zip_root_buffer(payload, w.compress)
+	if err != nil {
+		return err
+	}
+	basket.key.objsz = uint32(len(payload))
+	basket.key.nbytes = uint32(keysz + len(zipped))
+	basket.key.seek_key = f.alloc(int64(basket.key.nbytes))
+
+	b, err := NewWBuffer(f.order, 0)
+	if err != nil {
+		return err
+	}
+	err = basket.ROOTEncode(b)
+	if err != nil {
+		return err
+	}
+	b.write_nbytes(zipped)
+	basket.key.buffer = b.Bytes()
+	err = basket.key.write_file()
+	if err != nil {
+		return err
+	}
+
+	br.basketBytes = append(br.basketBytes, int32(basket.key.nbytes))
+	br.basketEntry = append(br.basketEntry, bw.first)
+	br.basketSeek = append(br.basketSeek, basket.key.seek_key)
+	br.writeBasket++
+	br.totBytes += int64(keysz + len(payload))
+	br.zipBytes += int64(basket.key.nbytes)
+	w.tree.tot_bytes += uint64(keysz + len(payload))
+	w.tree.zip_bytes += uint64(basket.key.nbytes)
+
+	bw.first += int64(bw.nev)
+	bw.nev = 0
+	bw.buf, err = NewWBuffer(f.order, 0)
+	if bw.offsets != nil {
+		bw.offsets = bw.offsets[:0]
+	}
+	return err
+}
+
+// new_leaf creates the leaf holding values of type rt.
+// it also returns the type code of the leaf (e.g. "F" for float32.)
+func new_leaf(name string, rt reflect.Type) (leaf ileaf, code string, err error) {
+	title := name
+	length := 1
+	et := rt
+	if rt.Kind() == reflect.Array {
+		length = rt.Len()
+		et = rt.Elem()
+		title = fmt.Sprintf("%s[%d]", name, length)
+	}
+
+	base := baseLeaf{
+		name:   name,
+		title:  title,
+		length: uint32(length),
+	}
+	switch et.Kind() {
+	case reflect.Bool:
+		base.etype = 1
+		leaf, code = &LeafO{base: base}, "O"
+	case reflect.Int8, reflect.Uint8:
+		base.etype = 1
+		base.unsigned = et.Kind() == reflect.Uint8
+		leaf, code = &LeafB{base: base}, "B"
+	case reflect.Int16, reflect.Uint16:
+		base.etype = 2
+		base.unsigned = et.Kind() == reflect.Uint16
+		leaf, code = &LeafS{base: base}, "S"
+	case reflect.Int32, reflect.Uint32:
+		base.etype = 4
+		base.unsigned = et.Kind() == reflect.Uint32
+		leaf, code = &LeafI{base: base}, "I"
+	case reflect.Int64, reflect.Uint64:
+		base.etype = 8
+		base.unsigned = et.Kind() == reflect.Uint64
+		leaf, code = &LeafL{base: base}, "L"
+	case reflect.Float32:
+		base.etype = 4
+		leaf, code = &LeafF{base: base}, "F"
+	case reflect.Float64:
+		base.etype = 8
+		leaf, code = &LeafD{base: base}, "D"
+	case reflect.String:
+		if et != rt {
+			return nil, "", fmt.Errorf("groot: arrays of strings are not supported (branch [%s])", name)
+		}
+		base.etype = 1
+		leaf, code = &LeafC{base: base}, "C"
+	default:
+		return nil, "", fmt.Errorf("groot: type %v is not supported (branch [%s])", rt, name)
+	}
+	if base.unsigned {
+		code = string(code[0] + 'a' - 'A')
+	}
+	return leaf, code, err
+}
+
+// write_value writes the value v into the basket buffer b
+func write_value(b *Buffer, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			write_value(b, v.Index(i))
+		}
+	case reflect.Bool:
+		b.write_bool(v.Bool())
+	case reflect.Int8:
+		b.byteton(byte(v.Int()))
+	case reflect.Uint8:
+		b.byteton(byte(v.Uint()))
+	case reflect.Int16:
+		b.i2ton(int16(v.Int()))
+	case reflect.Uint16:
+		b.u2ton(uint16(v.Uint()))
+	case reflect.Int32:
+		b.i4ton(int32(v.Int()))
+	case reflect.Uint32:
+		b.u4ton(uint32(v.Uint()))
+	case reflect.Int64:
+		b.i8ton(v.Int())
+	case reflect.Uint64:
+		b.u8ton(v.Uint())
+	case reflect.Float32:
+		b.fton(float32(v.Float()))
+	case reflect.Float64:
+		b.dton(v.Float())
+	case reflect.String:
+		// see TLeafC::FillBasket
+		b.write_tstring(v.String())
+	}
+}
+
+// EOF
@@ -0,0 +1,260 @@
+package groot
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// unzip_fct decompresses the payload of a compressed block
+// (i.e. the block w/o its 9-bytes header) into dst.
+// dst is sized to the uncompressed size of the block, as read from the
+// block header: it must be exactly filled.
+type unzip_fct func(dst, src []byte) error
+
+// new_unzipers returns the decompressors for all the compression algorithms
+// ROOT knows about, indexed by the 2-letters tag of their block header.
+func new_unzipers() map[string]unzip_fct {
+	return map[string]unzip_fct{
+		"ZL": unzip_zlib,
+		"XZ": unzip_xz,
+		"L4": unzip_lz4,
+		"ZS": unzip_zstd,
+	}
+}
+
+// unzip_read fills dst from r and makes sure r holds no more data.
+// r is read until EOF, so that the checksums of the stream are verified.
+func unzip_read(dst []byte, r io.Reader) (err error) {
+	n, err := io.ReadFull(r, dst)
+	if err != nil {
+		return fmt.Errorf("decompressed %d bytes (want %d): %v", n, len(dst), err)
+	}
+	var extra [1]byte
+	n, err = io.ReadFull(r, extra[:])
+	switch {
+	case n != 0:
+		return fmt.Errorf("decompressed more than %d bytes", len(dst))
+	case err != io.EOF:
+		return err
+	}
+	return nil
+}
+
+// unzip_zlib decompresses a block compressed with zlib (deflate)
+func unzip_zlib(dst, src []byte) (err error) {
+	dec, err := zlib.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return err
+	}
+	err = unzip_read(dst, dec)
+	if err != nil {
+		return err
+	}
+	return dec.Close()
+}
+
+// unzip_xz decompresses a block compressed with LZMA (xz container)
+func unzip_xz(dst, src []byte) (err error) {
+	dec, err := xz.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return err
+	}
+	return unzip_read(dst, dec)
+}
+
+var g_zstd struct {
+	once sync.Once
+	dec  *zstd.Decoder
+	err  error
+}
+
+// unzip_zstd decompresses a block compressed with ZSTD
+func unzip_zstd(dst, src []byte) (err error) {
+	g_zstd.once.Do(func() {
+		g_zstd.dec, g_zstd.err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+	if g_zstd.err != nil {
+		return g_zstd.err
+	}
+	out, err := g_zstd.dec.DecodeAll(src, dst[:0])
+	if err != nil {
+		return err
+	}
+	if len(out) != len(dst) {
+		return fmt.Errorf("decompressed %d bytes (want %d)", len(out), len(dst))
+	}
+	return err
+}
+
+// unzip_lz4 decompresses a block compressed with LZ4.
+// ROOT prepends the LZ4 block with the (big-endian) xxhash64 checksum of
+// the compressed data.
+func unzip_lz4(dst, src []byte) (err error) {
+	const CHKSUMSIZE = 8
+	if len(src) < CHKSUMSIZE {
+		return fmt.Errorf("too small source (%d bytes)", len(src))
+	}
+	chksum := binary.BigEndian.Uint64(src[:CHKSUMSIZE])
+	src = src[CHKSUMSIZE:]
+	if sum := xxhash64(src); sum != chksum {
+		return fmt.Errorf("checksum mismatch (got=0x%x, want=0x%x)", sum, chksum)
+	}
+	n, err := lz4_decode_block(dst, src)
+	if err != nil {
+		return err
+	}
+	if n != len(dst) {
+		return fmt.Errorf("decompressed %d bytes (want %d)", n, len(dst))
+	}
+	return err
+}
+
+// lz4_decode_block decodes a raw LZ4 block into dst.
+// it returns the number of decoded bytes.
+// see https://github.com/lz4/lz4/blob/dev/doc/lz4_Block_format.md
+func lz4_decode_block(dst, src []byte) (int, error) {
+	// length reads the (optional) extra bytes of a literals/match length
+	length := func(i, n int) (int, int, error) {
+		if n != 15 {
+			return i, n, nil
+		}
+		for {
+			if i >= len(src) {
+				return i, n, fmt.Errorf("lz4: corrupted input (length)")
+			}
+			v := src[i]
+			i++
+			n += int(v)
+			if v != 255 {
+				return i, n, nil
+			}
+		}
+	}
+
+	var err error
+	j := 0 // position in dst
+	for i, n := 0, 0; i < len(src); {
+		token := src[i]
+		i++
+
+		// literals
+		i, n, err = length(i, int(token>>4))
+		if err != nil {
+			return j, err
+		}
+		if i+n > len(src) {
+			return j, fmt.Errorf("lz4: corrupted input (literals)")
+		}
+		if j+n > len(dst) {
+			return j, fmt.Errorf("lz4: output overflow (literals)")
+		}
+		j += copy(dst[j:], src[i:i+n])
+		i += n
+		if i == len(src) {
+			// last sequence: no match
+			break
+		}
+
+		// match
+		if i+2 > len(src) {
+			return j, fmt.Errorf("lz4: corrupted input (offset)")
+		}
+		offset := int(src[i]) | int(src[i+1])<<8
+		i += 2
+		if offset == 0 || offset > j {
+			return j, fmt.Errorf("lz4: corrupted input (invalid offset=%d)", offset)
+		}
+		i, n, err = length(i, int(token&0xf))
+		if err != nil {
+			return j, err
+		}
+		n += 4 // minimum match length
+		if j+n > len(dst) {
+			return j, fmt.Errorf("lz4: output overflow (match)")
+		}
+		// matches may overlap with the bytes being copied
+		for pos := j - offset; n > 0; n-- {
+			dst[j] = dst[pos]
+			j++
+			pos++
+		}
+	}
+	return j, err
+}
+
+const (
+	xxh_prime1 uint64 = 11400714785074694791
+	xxh_prime2 uint64 = 14029467366897019727
+	xxh_prime3 uint64 = 1609587929392839161
+	xxh_prime4 uint64 = 9650029242287828579
+	xxh_prime5 uint64 = 2870177450012600261
+)
+
+// xxhash64 computes the XXH64 hash (with a zero seed) of b
+// see https://github.com/Cyan4973/xxHash/blob/dev/doc/xxhash_spec.md
+func xxhash64(b []byte) uint64 {
+	round := func(acc, v uint64) uint64 {
+		acc += v * xxh_prime2
+		return bits.RotateLeft64(acc, 31) * xxh_prime1
+	}
+	merge := func(acc, v uint64) uint64 {
+		acc ^= round(0, v)
+		return acc*xxh_prime1 + xxh_prime4
+	}
+
+	n := len(b)
+	var h uint64
+	if n >= 32 {
+		p1, p2 := xxh_prime1, xxh_prime2
+		v1 := p1 + p2
+		v2 := p2
+		v3 := uint64(0)
+		v4 := -p1
+		for ; len(b) >= 32; b = b[32:] {
+			v1 = round(v1, binary.LittleEndian.Uint64(b[0:]))
+			v2 = round(v2, binary.LittleEndian.Uint64(b[8:]))
+			v3 = round(v3, binary.LittleEndian.Uint64(b[16:]))
+			v4 = round(v4, binary.LittleEndian.Uint64(b[24:]))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) +
+			bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = merge(h, v1)
+		h = merge(h, v2)
+		h = merge(h, v3)
+		h = merge(h, v4)
+	} else {
+		h = xxh_prime5
+	}
+	h += uint64(n)
+
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= round(0, binary.LittleEndian.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*xxh_prime1 + xxh_prime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * xxh_prime1
+		h = bits.RotateLeft64(h, 23)*xxh_prime2 + xxh_prime3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * xxh_prime5
+		h = bits.RotateLeft64(h, 11) * xxh_prime1
+	}
+
+	h ^= h >> 33
+	h *= xxh_prime2
+	h ^= h >> 29
+	h *= xxh_prime3
+	h ^= h >> 32
+	return h
+}
+
+// EOF
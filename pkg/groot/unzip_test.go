@@ -0,0 +1,178 @@
+package groot
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// unzip_data is the content of the compressed blocks of the tests
+var unzip_data = []byte(strings.Repeat("hello groot, ", 8))
+
+// unhex decodes a hexadecimal string, ignoring spaces
+func unhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(strings.Replace(s, " ", "", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestXXHash64(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		{"Nobody inspects the spammish repetition", 0xfbcea83c8a378bf1},
+		{string(unzip_data), 0x6e67bea067ade0ba},
+	} {
+		if got := xxhash64([]byte(tc.in)); got != tc.want {
+			t.Errorf("xxhash64(%q): got 0x%x, want 0x%x", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestLZ4DecodeBlock(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		block string
+		n     int // size of the output buffer
+		want  string
+		err   string
+	}{
+		{
+			name:  "overlapping match",
+			block: "35 616263 0300 30 78797a",
+			n:     15,
+			want:  "abcabcabcabcxyz",
+		},
+		{
+			name:  "long lengths",
+			block: "ff 05 30313233343536373839 30313233343536373839 0a00 ff01 10 78",
+			n:     296,
+			want:  strings.Repeat("0123456789", 29) + "01234x",
+		},
+		{
+			name:  "literals only",
+			block: "50 68656c6c6f",
+			n:     5,
+			want:  "hello",
+		},
+		{
+			name:  "truncated literals",
+			block: "50 68656c6c",
+			n:     5,
+			err:   "lz4: corrupted input (literals)",
+		},
+		{
+			name:  "truncated length",
+			block: "f0 ff",
+			n:     300,
+			err:   "lz4: corrupted input (length)",
+		},
+		{
+			name:  "truncated offset",
+			block: "35 616263 03",
+			n:     15,
+			err:   "lz4: corrupted input (offset)",
+		},
+		{
+			name:  "null offset",
+			block: "35 616263 0000 30 78797a",
+			n:     15,
+			err:   "lz4: corrupted input (invalid offset=0)",
+		},
+		{
+			name:  "offset before the output",
+			block: "35 616263 0400 30 78797a",
+			n:     15,
+			err:   "lz4: corrupted input (invalid offset=4)",
+		},
+		{
+			name:  "literals overflow",
+			block: "50 68656c6c6f",
+			n:     4,
+			err:   "lz4: output overflow (literals)",
+		},
+		{
+			name:  "match overflow",
+			block: "35 616263 0300 30 78797a",
+			n:     10,
+			err:   "lz4: output overflow (match)",
+		},
+	} {
+		dst := make([]byte, tc.n)
+		n, err := lz4_decode_block(dst, unhex(t, tc.block))
+		switch {
+		case tc.err != "":
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s: got err=%v, want %q", tc.name, err, tc.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+		case string(dst[:n]) != tc.want:
+			t.Errorf("%s: got %q, want %q", tc.name, dst[:n], tc.want)
+		}
+	}
+}
+
+// unzip_blocks holds known compressed blocks (w/o their ROOT header) of
+// unzip_data, for each compression algorithm
+var unzip_blocks = map[string]string{
+	"ZL": "789ccb48cdc9c957482fcacf2fd151c8a01d0700bbed2559",
+	"XZ": "fd377a585a0000016922de360200210116000000742fe5a3e0006700135d00341949ee8de9" +
+		"132bf01cb93afd95628d9080000000d8e3bd5400012b6828a19f159042990d010000000001595a",
+	// xxhash64 of the LZ4 block, then the LZ4 block
+	"L4": "ab43a44a76f4ffa3 df 68656c6c6f2067726f6f742c20 0d00 3b d0 68656c6c6f2067726f6f742c20",
+	"ZS": "28b52ffd0400ad00006868656c6c6f2067726f6f742c2001540d04290801bae0ad67",
+}
+
+func TestUnzip(t *testing.T) {
+	unzipers := new_unzipers()
+	for _, tag := range []string{"ZL", "XZ", "L4", "ZS"} {
+		src := unhex(t, unzip_blocks[tag])
+		dst := make([]byte, len(unzip_data))
+		err := unzipers[tag](dst, src)
+		if err != nil {
+			t.Errorf("%s: %v", tag, err)
+			continue
+		}
+		if !bytes.Equal(dst, unzip_data) {
+			t.Errorf("%s: got %q, want %q", tag, dst, unzip_data)
+		}
+
+		// too small and too large output buffers
+		for _, n := range []int{len(unzip_data) - 1, len(unzip_data) + 1} {
+			err = unzipers[tag](make([]byte, n), src)
+			if err == nil {
+				t.Errorf("%s: expected an error decompressing into %d bytes", tag, n)
+			}
+		}
+
+		// corrupted checksum of the compressed (L4) or uncompressed data
+		bad := append([]byte(nil), src...)
+		switch tag {
+		case "ZL":
+			bad[len(bad)-1] ^= 1 // adler32
+		case "XZ":
+			bad[52] ^= 1 // crc32 of the block
+		case "L4":
+			bad[0] ^= 1
+		case "ZS":
+			bad[len(bad)-1] ^= 1 // xxhash64 of the frame
+		}
+		err = unzipers[tag](make([]byte, len(unzip_data)), bad)
+		if err == nil {
+			t.Errorf("%s: expected an error for a corrupted checksum", tag)
+		}
+		if tag == "L4" && (err == nil || !strings.Contains(err.Error(), "checksum mismatch")) {
+			t.Errorf("%s: got err=%v, want a checksum mismatch", tag, err)
+		}
+	}
+}
+
+// EOF
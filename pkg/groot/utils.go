@@ -3,8 +3,8 @@ package groot
 import (
 	"bytes"
 	"compress/zlib"
+	"crypto/rand"
 	"fmt"
-	"io/ioutil"
 	"time"
 )
 
@@ -23,38 +23,177 @@ func datime2time(d uint32) time.Time {
 		int(hour), int(min), int(sec), nsec, time.UTC)
 }
 
-// unzip_root_buffer implements the ROOT unzip algorithm
-func unzip_root_buffer(src []byte) (buf []byte, err error) {
-	const HDRSIZE = 9
-	const DEFLATE = 8
+// time2datime converts a time.Time into a uint32 holding a ROOT's TDatime
+func time2datime(t time.Time) uint32 {
+	var year uint32 = uint32(t.Year())
+	if year < 1995 {
+		year = 1995
+	}
+	return (year-1995)<<26 |
+		uint32(t.Month())<<22 |
+		uint32(t.Day())<<17 |
+		uint32(t.Hour())<<12 |
+		uint32(t.Minute())<<6 |
+		uint32(t.Second())
+}
+
+// new_uuid returns a new (random) UUID
+func new_uuid() (uuid [16]byte) {
+	_, err := rand.Read(uuid[:])
+	if err != nil {
+		panic(err)
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40 // version 4
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // variant
+	return
+}
 
-	buf = make([]byte, 0)
+// unzip_root_buffer implements the ROOT unzip algorithm.
+// the compressed payload is made of one or more blocks (of at most 0xffffff
+// uncompressed bytes), each block starting with a 9-bytes header.
+// the decompressor of a block is selected from the 2-letters tag of its
+// header (e.g. "ZL" for zlib, "XZ" for LZMA, "L4" for LZ4, "ZS" for ZSTD.)
+// blocks are decompressed into dst, until it is filled.
+func unzip_root_buffer(dst, src []byte, unzipers map[string]unzip_fct) (err error) {
+	objsz := len(dst)
+	beg := 0 // offset of the current block in src
+	for iblock, n := 0, 0; n < objsz; iblock++ {
+		hdr, err := parse_zip_header(src[beg:])
+		if err != nil {
+			return fmt.Errorf("groot.utils.unzip: block #%d (offset=%d): %v", iblock, beg, err)
+		}
+		if n+hdr.tgtsz > objsz {
+			return fmt.Errorf(
+				"groot.utils.unzip: block #%d (offset=%d): too many bytes (got %d, want %d)",
+				iblock, beg, n+hdr.tgtsz, objsz)
+		}
 
-	// check header
+		unzip, ok := unzipers[hdr.tag]
+		if !ok {
+			return fmt.Errorf(
+				"groot.utils.unzip: block #%d (offset=%d): unknown compression algorithm [%s]",
+				iblock, beg, hdr.tag)
+		}
 
-	if len(src) < HDRSIZE {
-		return buf, fmt.Errorf("groot.utils.unzip: too small source")
+		end := beg + zip_header_size + hdr.srcsz
+		err = unzip(dst[n:n+hdr.tgtsz], src[beg+zip_header_size:end])
+		if err != nil {
+			return fmt.Errorf("groot.utils.unzip: block #%d (offset=%d, algorithm=%s): %v",
+				iblock, beg, hdr.tag, err)
+		}
+		n += hdr.tgtsz
+		beg = end
 	}
+	return err
+}
 
-	if src[0] != byte('Z') || src[1] != byte('L') || src[2] != DEFLATE {
-		return buf, fmt.Errorf("groot.utils.unzip: error in header: %v",
-			src[:3])
+// check_unzip_size checks that the headers of the compressed blocks of src
+// announce exactly objsz uncompressed bytes.
+// it is used to validate the size of an object before allocating its buffer.
+func check_unzip_size(src []byte, objsz int) (err error) {
+	beg := 0
+	n := 0
+	for iblock := 0; n < objsz; iblock++ {
+		hdr, err := parse_zip_header(src[beg:])
+		if err != nil {
+			return fmt.Errorf("groot.utils.unzip: block #%d (offset=%d): %v", iblock, beg, err)
+		}
+		n += hdr.tgtsz
+		beg += zip_header_size + hdr.srcsz
+	}
+	if n != objsz {
+		return fmt.Errorf("groot.utils.unzip: too many bytes (got %d, want %d)", n, objsz)
 	}
+	return err
+}
 
-	rbuf := src[HDRSIZE:]
-	dec, err := zlib.NewReader(bytes.NewBuffer(rbuf))
-	if err != nil {
-		return []byte{}, err
+// zip_header_size is the size of the header of a compressed block
+const zip_header_size = 9
+
+// zip_header is the header of a compressed block:
+//   - the 2-letters tag of the compression algorithm,
+//   - the method (or version) of the compression algorithm,
+//   - the compressed size of the block (3 bytes, little-endian),
+//   - the uncompressed size of the block (3 bytes, little-endian).
+type zip_header struct {
+	tag   string
+	srcsz int // compressed size
+	tgtsz int // uncompressed size
+}
+
+// parse_zip_header decodes and validates the header of a compressed block
+func parse_zip_header(src []byte) (hdr zip_header, err error) {
+	if len(src) < zip_header_size {
+		return hdr, fmt.Errorf("truncated header (%d bytes)", len(src))
 	}
-	buf, err = ioutil.ReadAll(dec)
-	if err != nil {
-		return []byte{}, err
+	hdr.tag = string(src[:2])
+	hdr.srcsz = int(src[3]) | int(src[4])<<8 | int(src[5])<<16
+	hdr.tgtsz = int(src[6]) | int(src[7])<<8 | int(src[8])<<16
+	if hdr.srcsz == 0 || hdr.tgtsz == 0 {
+		return hdr, fmt.Errorf("invalid header %v (compressed=%d, uncompressed=%d)",
+			src[:zip_header_size], hdr.srcsz, hdr.tgtsz)
 	}
-	err = dec.Close()
-	if err != nil {
-		return []byte{}, err
+	if zip_header_size+hdr.srcsz > len(src) {
+		return hdr, fmt.Errorf("truncated block (compressed=%d, available=%d)",
+			hdr.srcsz, len(src)-zip_header_size)
+	}
+	return hdr, err
+}
+
+// zip_root_buffer implements the ROOT zip algorithm, with the ZL (zlib)
+// method and the given compression level.
+// the data is compressed in blocks of at most 0xffffff bytes, each block
+// being preceded by its 9-bytes header.
+// src is returned as is if lvl is 0 or if compressing a block does not pay
+// off.
+func zip_root_buffer(src []byte, lvl int) (buf []byte, err error) {
+	const DEFLATE = 8
+	const MAXBLOCK = 0xffffff
+
+	if lvl <= 0 || len(src) == 0 {
+		return src, err
+	}
+
+	out := new(bytes.Buffer)
+	for beg := 0; beg < len(src); beg += MAXBLOCK {
+		end := beg + MAXBLOCK
+		if end > len(src) {
+			end = len(src)
+		}
+		block := new(bytes.Buffer)
+		enc, err := zlib.NewWriterLevel(block, lvl)
+		if err != nil {
+			return nil, err
+		}
+		_, err = enc.Write(src[beg:end])
+		if err != nil {
+			return nil, err
+		}
+		err = enc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		srcsz := end - beg
+		dstsz := block.Len()
+		if dstsz > MAXBLOCK || dstsz >= srcsz {
+			// the compressed size would not fit into the block header,
+			// or compressing does not pay off: store the data as is.
+			return src, nil
+		}
+		hdr := [zip_header_size]byte{
+			'Z', 'L', DEFLATE,
+			byte(dstsz), byte(dstsz >> 8), byte(dstsz >> 16),
+			byte(srcsz), byte(srcsz >> 8), byte(srcsz >> 16),
+		}
+		out.Write(hdr[:])
+		out.Write(block.Bytes())
+	}
+
+	if out.Len() >= len(src) {
+		return src, err
 	}
-	return buf, err
+	return out.Bytes(), err
 }
 
 // EOF
@@ -0,0 +1,216 @@
+package groot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// zip_block returns the compressed block src, preceded by its header
+// announcing tgtsz uncompressed bytes
+func zip_block(tag string, src []byte, tgtsz int) []byte {
+	srcsz := len(src)
+	hdr := []byte{
+		tag[0], tag[1], 0,
+		byte(srcsz), byte(srcsz >> 8), byte(srcsz >> 16),
+		byte(tgtsz), byte(tgtsz >> 8), byte(tgtsz >> 16),
+	}
+	return append(hdr, src...)
+}
+
+func TestUnzipRootBuffer(t *testing.T) {
+	unzipers := new_unzipers()
+	n := len(unzip_data)
+
+	// a payload made of a block of each algorithm
+	var (
+		src  []byte
+		want []byte
+	)
+	for _, tag := range []string{"ZL", "XZ", "L4", "ZS"} {
+		src = append(src, zip_block(tag, unhex(t, unzip_blocks[tag]), n)...)
+		want = append(want, unzip_data...)
+	}
+	err := check_unzip_size(src, len(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := make([]byte, len(want))
+	err = unzip_root_buffer(dst, src, unzipers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst, want) {
+		t.Fatalf("got %q, want %q", dst, want)
+	}
+
+	zl := unhex(t, unzip_blocks["ZL"])
+	blk := zip_block("ZL", zl, n)
+	for _, tc := range []struct {
+		name  string
+		src   []byte
+		objsz int
+		err   string // error of unzip_root_buffer
+		chk   string // error of check_unzip_size (if different)
+	}{
+		{
+			name:  "uncompressed size larger than the inflated size",
+			src:   append(append([]byte{}, blk...), zip_block("ZL", zl, n+1)...),
+			objsz: 2*n + 1,
+			err:   "block #1 (offset=33, algorithm=ZL): decompressed 104 bytes (want 105)",
+			chk:   "-",
+		},
+		{
+			name:  "uncompressed size smaller than the inflated size",
+			src:   append(append([]byte{}, blk...), zip_block("ZL", zl, n-1)...),
+			objsz: 2*n - 1,
+			err:   "block #1 (offset=33, algorithm=ZL): decompressed more than 103 bytes",
+			chk:   "-",
+		},
+		{
+			name:  "truncated header",
+			src:   append(append([]byte{}, blk...), blk[:5]...),
+			objsz: 2 * n,
+			err:   "block #1 (offset=33): truncated header (5 bytes)",
+		},
+		{
+			name:  "missing block",
+			src:   blk,
+			objsz: 2 * n,
+			err:   "block #1 (offset=33): truncated header (0 bytes)",
+		},
+		{
+			name:  "truncated block",
+			src:   blk[:len(blk)-1],
+			objsz: n,
+			err:   "block #0 (offset=0): truncated block (compressed=24, available=23)",
+		},
+		{
+			name:  "uncompressed size larger than objsz",
+			src:   append(append([]byte{}, blk...), blk...),
+			objsz: 2*n - 1,
+			err:   "block #1 (offset=33): too many bytes (got 208, want 207)",
+			chk:   "too many bytes (got 208, want 207)",
+		},
+		{
+			name:  "null sizes",
+			src:   zip_block("ZL", nil, 0),
+			objsz: n,
+			err:   "block #0 (offset=0): invalid header",
+		},
+		{
+			name:  "unknown algorithm",
+			src:   zip_block("QQ", zl, n),
+			objsz: n,
+			err:   "block #0 (offset=0): unknown compression algorithm [QQ]",
+			chk:   "-",
+		},
+	} {
+		err := unzip_root_buffer(make([]byte, tc.objsz), tc.src, unzipers)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got err=%v, want %q", tc.name, err, tc.err)
+		}
+
+		chk := tc.chk
+		if chk == "" {
+			chk = tc.err
+		}
+		err = check_unzip_size(tc.src, tc.objsz)
+		switch {
+		case chk == "-":
+			if err != nil {
+				t.Errorf("%s: check_unzip_size: %v", tc.name, err)
+			}
+		case err == nil || !strings.Contains(err.Error(), chk):
+			t.Errorf("%s: check_unzip_size: got err=%v, want %q", tc.name, err, chk)
+		}
+	}
+}
+
+func TestZipRootBuffer(t *testing.T) {
+	// more than a block
+	src := make([]byte, 0xffffff+1000)
+	for i := range src {
+		src[i] = byte(i / 1000)
+	}
+	buf, err := zip_root_buffer(src, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr, err := parse_zip_header(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.tag != "ZL" || hdr.tgtsz != 0xffffff {
+		t.Fatalf("invalid first block: %+v", hdr)
+	}
+	hdr, err = parse_zip_header(buf[zip_header_size+hdr.srcsz:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.tgtsz != 1000 {
+		t.Fatalf("invalid second block: %+v", hdr)
+	}
+
+	err = check_unzip_size(buf, len(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := make([]byte, len(src))
+	err = unzip_root_buffer(dst, buf, new_unzipers())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst, src) {
+		t.Fatalf("round trip failed")
+	}
+}
+
+func TestCorruptedBasket(t *testing.T) {
+	tree := create_test_tree(t, 1000)
+	br := tree.Branch("i32")
+	fname := tree.file.name
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// announce one more uncompressed byte in the header of the first block
+	// of the first basket
+	pos := br.basketSeek[0]
+	i := bytes.Index(data[pos:], []byte("ZL"))
+	if i < 0 {
+		t.Fatalf("no compressed block")
+	}
+	data[int(pos)+i+6]++
+
+	fname = filepath.Join(t.TempDir(), "corrupted.root")
+	err = ioutil.WriteFile(fname, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	obj, err := f.Get("tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := obj.(*Tree).NewReader("i32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Next() {
+		t.Fatalf("read a corrupted basket")
+	}
+	want := "invalid compressed payload for basket [i32] of tree [tree]"
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("got err=%v, want %q", err, want)
+	}
+}
+
+// EOF
@@ -0,0 +1,451 @@
+package groot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	xrd_default_port  = 1094             // default port of XRootD servers
+	xrd_readahead     = 256 * 1024       // minimum number of bytes fetched by a read
+	xrd_cache_size    = 64 * 1024 * 1024 // maximum number of bytes held by the cache
+	xrd_max_gap       = 4 * 1024         // ranges closer than this are merged
+	xrd_max_readv     = 1024             // maximum number of elements of a kXR_readv request
+	xrd_max_readv_len = 2097136          // maximum length of an element of a kXR_readv request
+	xrd_max_redirects = 16               // maximum number of redirections when opening a file
+	xrd_max_waits     = 16               // maximum number of kXR_wait responses for a request
+)
+
+// XRootD request identifiers
+// see XProtocol.hh
+const (
+	kXR_close = 3003
+	kXR_login = 3007
+	kXR_open  = 3010
+	kXR_read  = 3013
+	kXR_stat  = 3017
+	kXR_readv = 3025
+)
+
+// XRootD response status
+const (
+	kXR_ok       = 0
+	kXR_oksofar  = 4000
+	kXR_attn     = 4001
+	kXR_authmore = 4002
+	kXR_error    = 4003
+	kXR_redirect = 4004
+	kXR_wait     = 4005
+	kXR_waitresp = 4006
+)
+
+const (
+	kXR_open_read = 0x0010 // open option: read-only access
+	kXR_ver004    = 4      // client protocol capabilities sent at login
+)
+
+// XRootDReader reads the content of a remote file from an XRootD server.
+// Fetched data is held in a read-ahead cache, and the ranges of data about
+// to be read (e.g. the baskets of a branch) are fetched with kXR_readv
+// requests.
+// Requests are sent one at a time over a single connection.
+type XRootDReader struct {
+	url  string
+	host string // address (host:port) of the data server
+	path string // path of the file on the server
+	user string
+	size int64
+
+	mu     sync.Mutex // serializes the requests on the connection
+	conn   net.Conn
+	sid    uint16  // stream id of the last request
+	handle [4]byte // handle of the opened file
+
+	cache *read_cache
+}
+
+// xrd_redirect is the error returned when the server redirects the client
+// to another server.
+type xrd_redirect struct {
+	host   string
+	opaque string
+}
+
+func (r xrd_redirect) Error() string {
+	return fmt.Sprintf("groot: xrootd redirection to [%s]", r.host)
+}
+
+// NewXRootDReader connects to the XRootD server of the given URL
+// (e.g. "root://server//path/file.root") and opens the file for reading.
+func NewXRootDReader(name string) (r *XRootDReader, err error) {
+	u, err := url.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "root" && u.Scheme != "xroot" {
+		return nil, fmt.Errorf("groot: invalid xrootd URL [%s]", name)
+	}
+
+	r = &XRootDReader{
+		url:   name,
+		host:  u.Host,
+		path:  strings.TrimPrefix(u.Path, "/"),
+		cache: new_read_cache(xrd_cache_size),
+	}
+	if u.Port() == "" {
+		r.host = net.JoinHostPort(u.Hostname(), strconv.Itoa(xrd_default_port))
+	}
+	if u.RawQuery != "" {
+		r.path += "?" + u.RawQuery
+	}
+	switch {
+	case u.User != nil && u.User.Username() != "":
+		r.user = u.User.Username()
+	case os.Getenv("USER") != "":
+		r.user = os.Getenv("USER")
+	default:
+		r.user = "groot"
+	}
+
+	for i := 0; ; i++ {
+		err = r.connect()
+		if err == nil {
+			err = r.open()
+		}
+		redir, ok := err.(xrd_redirect)
+		if !ok {
+			break
+		}
+		if i >= xrd_max_redirects {
+			err = fmt.Errorf("groot: too many xrootd redirections for [%s]", name)
+			break
+		}
+		r.conn.Close()
+		r.host = redir.host
+		if redir.opaque != "" {
+			r.path = strings.SplitN(r.path, "?", 2)[0] + "?" + redir.opaque
+		}
+	}
+	if err != nil {
+		if r.conn != nil {
+			r.conn.Close()
+		}
+		return nil, fmt.Errorf("groot: could not open [%s]: %w", name, err)
+	}
+
+	r.size, err = r.stat()
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("groot: could not stat [%s]: %w", name, err)
+	}
+	return r, err
+}
+
+// Name returns the URL of the remote file
+func (r *XRootDReader) Name() string {
+	return r.url
+}
+
+// Size returns the size of the remote file
+func (r *XRootDReader) Size() int64 {
+	return r.size
+}
+
+// Close closes the remote file and the connection to the server
+func (r *XRootDReader) Close() (err error) {
+	r.cache.reset()
+	if r.conn == nil {
+		return err
+	}
+	var params [16]byte
+	copy(params[:4], r.handle[:])
+	_, err = r.request(kXR_close, params, nil)
+	if e := r.conn.Close(); err == nil {
+		err = e
+	}
+	r.conn = nil
+	return err
+}
+
+// ReadAt implements io.ReaderAt.
+// Reads which are not served by the cache fetch at least xrd_readahead bytes.
+func (r *XRootDReader) ReadAt(p []byte, off int64) (n int, err error) {
+	return r.cache.read_at(p, off, r.size, xrd_readahead, r.fetch)
+}
+
+// prefetch fetches the given ranges into the cache, with kXR_readv requests
+func (r *XRootDReader) prefetch(spans []span) error {
+	return r.cache.prefetch(spans, r.size, xrd_max_gap, xrd_max_readv, r.fetch)
+}
+
+// connect connects to the server, performs the handshake and logs in
+func (r *XRootDReader) connect() (err error) {
+	r.conn, err = net.Dial("tcp", r.host)
+	if err != nil {
+		return err
+	}
+
+	// handshake: 5 int32 (0, 0, 0, 4, 2012), answered by the protocol
+	// version and the type of the server.
+	hs := make([]byte, 20)
+	binary.BigEndian.PutUint32(hs[12:], 4)
+	binary.BigEndian.PutUint32(hs[16:], 2012)
+	_, err = r.conn.Write(hs)
+	if err != nil {
+		return err
+	}
+	_, _, err = r.response(0)
+	if err != nil {
+		return fmt.Errorf("xrootd handshake with [%s] failed: %w", r.host, err)
+	}
+
+	// login: pid, user name (8 chars), reserved, ability, capver, role.
+	// the session id (16 bytes) is returned, followed by the security
+	// requirements of the server, if it requires the client to authenticate.
+	var params [16]byte
+	binary.BigEndian.PutUint32(params[:4], uint32(os.Getpid()))
+	copy(params[4:12], r.user)
+	params[14] = kXR_ver004
+	body, err := r.request(kXR_login, params, nil)
+	if err != nil {
+		return fmt.Errorf("xrootd login on [%s] failed: %w", r.host, err)
+	}
+	if len(body) > 16 {
+		if sec := strings.TrimRight(string(body[16:]), "\x00 \n"); sec != "" {
+			return fmt.Errorf("xrootd server [%s] requires authentication (%s): authentication not supported", r.host, sec)
+		}
+	}
+	return err
+}
+
+// open opens the file in read-only mode
+func (r *XRootDReader) open() (err error) {
+	var params [16]byte
+	binary.BigEndian.PutUint16(params[2:4], kXR_open_read)
+	body, err := r.request(kXR_open, params, []byte(r.path))
+	if err != nil {
+		return err
+	}
+	if len(body) < 4 {
+		return fmt.Errorf("invalid kXR_open response (%d bytes)", len(body))
+	}
+	copy(r.handle[:], body[:4])
+	return err
+}
+
+// stat returns the size of the file.
+// the server answers with "id size flags modtime".
+func (r *XRootDReader) stat() (size int64, err error) {
+	var params [16]byte
+	body, err := r.request(kXR_stat, params, []byte(r.path))
+	if err != nil {
+		return 0, err
+	}
+	var id string
+	_, err = fmt.Sscan(string(bytes.TrimRight(body, "\x00")), &id, &size)
+	if err != nil {
+		return 0, fmt.Errorf("invalid kXR_stat response [%s]: %w", body, err)
+	}
+	return size, nil
+}
+
+// fetch retrieves the given ranges from the server, with a kXR_read request
+// for a single range and kXR_readv requests otherwise.
+func (r *XRootDReader) fetch(spans []span) (chunks []read_chunk, err error) {
+	if len(spans) == 1 {
+		var params [16]byte
+		copy(params[:4], r.handle[:])
+		binary.BigEndian.PutUint64(params[4:12], uint64(spans[0].off))
+		binary.BigEndian.PutUint32(params[12:16], uint32(spans[0].len))
+		data, err := r.request(kXR_read, params, nil)
+		if err != nil {
+			return nil, err
+		}
+		return []read_chunk{{off: spans[0].off, data: data}}, nil
+	}
+
+	// split the ranges into elements of at most xrd_max_readv_len bytes
+	elmts := make([]span, 0, len(spans))
+	for _, s := range spans {
+		for s.len > xrd_max_readv_len {
+			elmts = append(elmts, span{s.off, xrd_max_readv_len})
+			s.off += xrd_max_readv_len
+			s.len -= xrd_max_readv_len
+		}
+		elmts = append(elmts, s)
+	}
+
+	for len(elmts) > 0 {
+		n := len(elmts)
+		if n > xrd_max_readv {
+			n = xrd_max_readv
+		}
+		vs, err := r.readv(elmts[:n])
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, vs...)
+		elmts = elmts[n:]
+	}
+
+	// glue back the elements of the ranges which have been split
+	out := chunks[:0]
+	for _, c := range chunks {
+		if n := len(out); n > 0 && out[n-1].off+int64(len(out[n-1].data)) == c.off {
+			out[n-1].data = append(out[n-1].data, c.data...)
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// readv sends a kXR_readv request for the given elements.
+// each element of the request and of the response is described by the file
+// handle, the length and the offset of the data.
+func (r *XRootDReader) readv(elmts []span) (chunks []read_chunk, err error) {
+	data := make([]byte, 16*len(elmts))
+	for i, e := range elmts {
+		buf := data[16*i:]
+		copy(buf[:4], r.handle[:])
+		binary.BigEndian.PutUint32(buf[4:8], uint32(e.len))
+		binary.BigEndian.PutUint64(buf[8:16], uint64(e.off))
+	}
+
+	var params [16]byte
+	body, err := r.request(kXR_readv, params, data)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks = make([]read_chunk, 0, len(elmts))
+	for len(body) > 0 {
+		if len(body) < 16 {
+			return nil, fmt.Errorf("truncated kXR_readv response")
+		}
+		n := int(int32(binary.BigEndian.Uint32(body[4:8])))
+		off := int64(binary.BigEndian.Uint64(body[8:16]))
+		body = body[16:]
+		if n < 0 || n > len(body) {
+			return nil, fmt.Errorf("invalid kXR_readv element (offset=%d, len=%d)", off, n)
+		}
+		chunks = append(chunks, read_chunk{off: off, data: body[:n:n]})
+		body = body[n:]
+	}
+	return chunks, nil
+}
+
+// request sends a request and returns the body of its response.
+// the request is sent again when the server asks to wait.
+func (r *XRootDReader) request(id uint16, params [16]byte, data []byte) (body []byte, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := 0; ; i++ {
+		r.sid++
+		hdr := make([]byte, 24, 24+len(data))
+		binary.BigEndian.PutUint16(hdr[0:2], r.sid)
+		binary.BigEndian.PutUint16(hdr[2:4], id)
+		copy(hdr[4:20], params[:])
+		binary.BigEndian.PutUint32(hdr[20:24], uint32(len(data)))
+		_, err = r.conn.Write(append(hdr, data...))
+		if err != nil {
+			return nil, err
+		}
+
+		status, body, err := r.response(r.sid)
+		if err != nil {
+			return nil, err
+		}
+		switch status {
+		case kXR_ok:
+			return body, nil
+		case kXR_wait:
+			if i >= xrd_max_waits || len(body) < 4 {
+				return nil, fmt.Errorf("xrootd server [%s] is not ready", r.host)
+			}
+			time.Sleep(time.Duration(binary.BigEndian.Uint32(body[:4])) * time.Second)
+		default:
+			return nil, fmt.Errorf("xrootd response with status %d is not supported", status)
+		}
+	}
+}
+
+// response reads the response to the request with stream id sid.
+// a response is made of a header (stream id, status, length of the body)
+// followed by its body.
+// partial responses (kXR_oksofar) are concatenated, errors and redirections
+// are returned as errors.
+func (r *XRootDReader) response(sid uint16) (status uint16, body []byte, err error) {
+	hdr := make([]byte, 8)
+	for {
+		_, err = io.ReadFull(r.conn, hdr)
+		if err != nil {
+			return 0, nil, err
+		}
+		rsid := binary.BigEndian.Uint16(hdr[0:2])
+		status = binary.BigEndian.Uint16(hdr[2:4])
+		n := int(int32(binary.BigEndian.Uint32(hdr[4:8])))
+		if n < 0 {
+			return 0, nil, fmt.Errorf("invalid xrootd response length (%d)", n)
+		}
+		data := make([]byte, n)
+		_, err = io.ReadFull(r.conn, data)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch {
+		case status == kXR_attn:
+			// asynchronous message from the server: ignored.
+			continue
+		case rsid != sid:
+			return 0, nil, fmt.Errorf("unexpected xrootd stream id (got %d, want %d)", rsid, sid)
+		}
+
+		switch status {
+		case kXR_oksofar:
+			body = append(body, data...)
+			continue
+		case kXR_ok:
+			body = append(body, data...)
+			return status, body, nil
+		case kXR_error:
+			if len(data) < 4 {
+				return status, nil, fmt.Errorf("xrootd error")
+			}
+			errnum := int32(binary.BigEndian.Uint32(data[:4]))
+			msg := string(bytes.TrimRight(data[4:], "\x00"))
+			return status, nil, fmt.Errorf("xrootd error %d: %s", errnum, msg)
+		case kXR_redirect:
+			if len(data) < 4 {
+				return status, nil, fmt.Errorf("invalid xrootd redirection")
+			}
+			port := int(int32(binary.BigEndian.Uint32(data[:4])))
+			host := string(data[4:])
+			redir := xrd_redirect{}
+			if i := strings.Index(host, "?"); i >= 0 {
+				host, redir.opaque = host[:i], host[i+1:]
+			}
+			redir.host = net.JoinHostPort(host, strconv.Itoa(port))
+			return status, nil, redir
+		}
+		return status, data, nil
+	}
+}
+
+// check interfaces
+var _ io.ReaderAt = (*XRootDReader)(nil)
+var _ io.Closer = (*XRootDReader)(nil)
+var _ prefetcher = (*XRootDReader)(nil)
+
+// EOF
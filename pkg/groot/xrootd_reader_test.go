@@ -0,0 +1,347 @@
+package groot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// xrd_server is a minimal XRootD server, serving a single file
+type xrd_server struct {
+	ln   net.Listener
+	data []byte
+
+	redirect string // address of the server kXR_open requests are redirected to (if any)
+	sec      string // security requirements sent at login (if any)
+
+	mu     sync.Mutex
+	reqs   []uint16 // identifiers of the requests received
+	paths  []string // paths of the kXR_open requests received
+	waited bool     // whether a kXR_wait response has been sent
+}
+
+func new_xrd_server(t *testing.T, data []byte) *xrd_server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &xrd_server{ln: ln, data: data}
+	go srv.serve()
+	return srv
+}
+
+func (srv *xrd_server) addr() string {
+	return srv.ln.Addr().String()
+}
+
+func (srv *xrd_server) close() {
+	srv.ln.Close()
+}
+
+func (srv *xrd_server) requests() []uint16 {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return append([]uint16(nil), srv.reqs...)
+}
+
+func (srv *xrd_server) serve() {
+	for {
+		conn, err := srv.ln.Accept()
+		if err != nil {
+			return
+		}
+		go srv.handle(conn)
+	}
+}
+
+func (srv *xrd_server) reply(conn net.Conn, sid []byte, status uint16, body []byte) error {
+	hdr := make([]byte, 8, 8+len(body))
+	copy(hdr[:2], sid)
+	binary.BigEndian.PutUint16(hdr[2:4], status)
+	binary.BigEndian.PutUint32(hdr[4:8], uint32(len(body)))
+	_, err := conn.Write(append(hdr, body...))
+	return err
+}
+
+// reply_parts sends body as a series of kXR_oksofar responses, followed by
+// a kXR_ok one.
+func (srv *xrd_server) reply_parts(conn net.Conn, sid []byte, body []byte) error {
+	for len(body) > 3 {
+		n := len(body) / 3
+		err := srv.reply(conn, sid, kXR_oksofar, body[:n])
+		if err != nil {
+			return err
+		}
+		body = body[n:]
+	}
+	return srv.reply(conn, sid, kXR_ok, body)
+}
+
+func (srv *xrd_server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	hs := make([]byte, 20)
+	if _, err := io.ReadFull(conn, hs); err != nil {
+		return
+	}
+	// protocol version and server type (data server)
+	if err := srv.reply(conn, []byte{0, 0}, kXR_ok, []byte{0, 0, 0x05, 0x10, 0, 0, 0, 1}); err != nil {
+		return
+	}
+
+	handle := []byte{1, 2, 3, 4}
+	hdr := make([]byte, 24)
+	for {
+		if _, err := io.ReadFull(conn, hdr); err != nil {
+			return
+		}
+		sid := hdr[0:2]
+		id := binary.BigEndian.Uint16(hdr[2:4])
+		params := hdr[4:20]
+		data := make([]byte, binary.BigEndian.Uint32(hdr[20:24]))
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return
+		}
+		srv.mu.Lock()
+		srv.reqs = append(srv.reqs, id)
+		srv.mu.Unlock()
+
+		var err error
+		switch id {
+		case kXR_login:
+			body := []byte("0123456789abcdef")
+			if srv.sec != "" {
+				body = append(body, srv.sec+"\x00"...)
+			}
+			err = srv.reply(conn, sid, kXR_ok, body)
+
+		case kXR_open:
+			path := string(data)
+			srv.mu.Lock()
+			srv.paths = append(srv.paths, path)
+			srv.mu.Unlock()
+			switch {
+			case srv.redirect != "":
+				host, port, _ := net.SplitHostPort(srv.redirect)
+				body := make([]byte, 4)
+				p, _ := strconv.Atoi(port)
+				binary.BigEndian.PutUint32(body, uint32(p))
+				body = append(body, host+"?tried=here"...)
+				err = srv.reply(conn, sid, kXR_redirect, body)
+			case strings.HasPrefix(path, "/missing"):
+				body := make([]byte, 4)
+				binary.BigEndian.PutUint32(body, 3011)
+				body = append(body, "no such file\x00"...)
+				err = srv.reply(conn, sid, kXR_error, body)
+			default:
+				err = srv.reply(conn, sid, kXR_ok, handle)
+			}
+
+		case kXR_stat:
+			body := fmt.Sprintf("42 %d 16 1600000000\x00", len(srv.data))
+			err = srv.reply(conn, sid, kXR_ok, []byte(body))
+
+		case kXR_read:
+			srv.mu.Lock()
+			waited := srv.waited
+			srv.waited = true
+			srv.mu.Unlock()
+			if !waited {
+				// ask the client to send the request again (in 0s)
+				err = srv.reply(conn, sid, kXR_wait, []byte{0, 0, 0, 0})
+				break
+			}
+			off := int64(binary.BigEndian.Uint64(params[4:12]))
+			n := int64(binary.BigEndian.Uint32(params[12:16]))
+			err = srv.reply_parts(conn, sid, srv.read(off, n))
+
+		case kXR_readv:
+			var body []byte
+			for ; len(data) >= 16; data = data[16:] {
+				n := int64(binary.BigEndian.Uint32(data[4:8]))
+				off := int64(binary.BigEndian.Uint64(data[8:16]))
+				v := srv.read(off, n)
+				elmt := make([]byte, 16)
+				copy(elmt[:4], data[:4])
+				binary.BigEndian.PutUint32(elmt[4:8], uint32(len(v)))
+				binary.BigEndian.PutUint64(elmt[8:16], uint64(off))
+				body = append(body, elmt...)
+				body = append(body, v...)
+			}
+			err = srv.reply_parts(conn, sid, body)
+
+		case kXR_close:
+			err = srv.reply(conn, sid, kXR_ok, nil)
+
+		default:
+			body := make([]byte, 4)
+			binary.BigEndian.PutUint32(body, 3006)
+			body = append(body, "unsupported request\x00"...)
+			err = srv.reply(conn, sid, kXR_error, body)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (srv *xrd_server) read(off, n int64) []byte {
+	if off >= int64(len(srv.data)) {
+		return nil
+	}
+	if end := int64(len(srv.data)); off+n > end {
+		n = end - off
+	}
+	return srv.data[off : off+n]
+}
+
+func TestXRootDReader(t *testing.T) {
+	data := new_http_data(1024 * 1024)
+	srv := new_xrd_server(t, data)
+	defer srv.close()
+
+	r, err := NewXRootDReader("root://" + srv.addr() + "//data/file.root")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := r.Size(), int64(len(data)); got != want {
+		t.Fatalf("got size=%d, want %d", got, want)
+	}
+	if got, want := srv.paths, []string{"/data/file.root"}; len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("got paths=%q, want %q", got, want)
+	}
+
+	// the first kXR_read is answered with kXR_wait, then with partial
+	// responses.
+	p := make([]byte, 100)
+	n, err := r.ReadAt(p, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(p) || !bytes.Equal(p, data[10:110]) {
+		t.Fatalf("invalid data")
+	}
+	n, err = r.ReadAt(p, 1000) // served by the read-ahead cache
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(p) || !bytes.Equal(p, data[1000:1100]) {
+		t.Fatalf("invalid data")
+	}
+
+	// past EOF
+	n, err = r.ReadAt(p, int64(len(data))-10)
+	if err != io.EOF || n != 10 || !bytes.Equal(p[:n], data[len(data)-10:]) {
+		t.Fatalf("got n=%d err=%v, want n=10 err=io.EOF", n, err)
+	}
+
+	spans := []span{
+		{off: 500000, len: 1000},
+		{off: 700000, len: 2000},
+		{off: 900000, len: 30},
+	}
+	err = r.prefetch(spans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range spans {
+		p := make([]byte, s.len)
+		_, err = r.ReadAt(p, s.off)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p, data[s.off:s.off+s.len]) {
+			t.Fatalf("span %v: invalid data", s)
+		}
+	}
+
+	err = r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []uint16{
+		kXR_login, kXR_open, kXR_stat,
+		kXR_read, kXR_read, // kXR_wait
+		kXR_read,  // past EOF
+		kXR_readv, // prefetch
+		kXR_close,
+	}
+	if got := srv.requests(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got requests=%v, want %v", got, want)
+	}
+}
+
+func TestXRootDReaderRedirect(t *testing.T) {
+	data := new_http_data(1000)
+	dst := new_xrd_server(t, data)
+	defer dst.close()
+
+	srv := new_xrd_server(t, nil)
+	defer srv.close()
+	srv.redirect = dst.addr()
+
+	r, err := NewXRootDReader("root://" + srv.addr() + "//file.root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.Size() != int64(len(data)) {
+		t.Fatalf("got size=%d, want %d", r.Size(), len(data))
+	}
+	if got, want := dst.paths, []string{"/file.root?tried=here"}; len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("got paths=%q, want %q", got, want)
+	}
+
+	p := make([]byte, 10)
+	_, err = r.ReadAt(p, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, data[20:30]) {
+		t.Fatalf("invalid data")
+	}
+}
+
+func TestXRootDReaderErrors(t *testing.T) {
+	srv := new_xrd_server(t, nil)
+	defer srv.close()
+
+	_, err := NewXRootDReader("root://" + srv.addr() + "//missing.root")
+	if err == nil || !strings.Contains(err.Error(), "xrootd error 3011: no such file") {
+		t.Fatalf("got err=%v", err)
+	}
+
+	auth := new_xrd_server(t, nil)
+	defer auth.close()
+	auth.sec = "&P=krb5,xrootd/host"
+
+	_, err = NewXRootDReader("root://" + auth.addr() + "//file.root")
+	if err == nil || !strings.Contains(err.Error(), "authentication not supported") {
+		t.Fatalf("got err=%v", err)
+	}
+
+	loop := new_xrd_server(t, nil)
+	defer loop.close()
+	loop.redirect = loop.addr()
+
+	_, err = NewXRootDReader("root://" + loop.addr() + "//file.root")
+	if err == nil || !strings.Contains(err.Error(), "too many xrootd redirections") {
+		t.Fatalf("got err=%v", err)
+	}
+
+	_, err = NewXRootDReader("http://" + srv.addr() + "//file.root")
+	if err == nil {
+		t.Fatalf("expected an error for a non-xrootd URL")
+	}
+}
+
+// EOF